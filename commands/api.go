@@ -0,0 +1,427 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vend/vend-cli/pkg/messenger"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vend/govend/vend"
+)
+
+// Pagination styles used by the 2.0 API
+const (
+	paginateVersion = "version"
+	paginateBefore  = "before"
+)
+
+// Command config
+var (
+	apiData     string
+	apiPaginate string
+	apiFields   []string
+	apiFormat   string
+	apiOutput   string
+
+	apiCmd = &cobra.Command{
+		Use:   "api METHOD PATH",
+		Short: "Make a request to any Vend API endpoint",
+		Long: fmt.Sprintf(`
+Makes a single request to the Vend API using the same authentication, retries and rate limit handling as every other command.
+PATH is relative to the store e.g. /api/2.0/products, query parameters can be included.
+
+Pagination (GET only):
+  --paginate version   follows ?after= using version.max, like products, customers and sales
+  --paginate before    follows ?before= using the last id, like the audit log endpoints
+
+Output:
+  --fields id,name,variant_options.0.value   picks fields from each record using dotted paths
+  --format json | ndjson | csv                 json by default, csv needs records to be objects
+
+Records are the items in "data" when the response has one, otherwise the response itself.
+Requests other than GET change data and ask for confirmation first (pass --yes to skip).
+
+Examples:
+%s
+%s
+%s`,
+			color.GreenString("vendcli api GET /api/2.0/outlets -d DOMAINPREFIX -t TOKEN"),
+			color.GreenString("vendcli api GET /api/2.0/products -d DOMAINPREFIX -t TOKEN --paginate version --fields id,sku,name --format csv -o products.csv"),
+			color.GreenString("vendcli api PUT /api/2.0/products/PRODUCTID -d DOMAINPREFIX -t TOKEN --data @product.json")),
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			callAPI(cmd, strings.ToUpper(args[0]), args[1])
+		},
+	}
+)
+
+func init() {
+	// Flags
+	apiCmd.Flags().StringVarP(&apiData, "data", "b", "", "JSON request body, or @filename.json to read it from a file")
+	apiCmd.Flags().StringVarP(&apiPaginate, "paginate", "p", "", "Follow pagination: version or before")
+	apiCmd.Flags().StringSliceVar(&apiFields, "fields", nil, "Dotted paths of the fields to output from each record (comma separated)")
+	apiCmd.Flags().StringVar(&apiFormat, "format", "json", "Output format: json, ndjson or csv")
+	apiCmd.Flags().StringVarP(&apiOutput, "output", "o", "", "Write the output to this file instead of the terminal")
+
+	rootCmd.AddCommand(apiCmd)
+}
+
+func callAPI(cmd *cobra.Command, method, path string) {
+
+	apiFormat = strings.ToLower(apiFormat)
+	switch apiFormat {
+	case "json", "ndjson", "csv":
+	default:
+		messenger.ExitWithError(fmt.Errorf("unknown format: %s, expecting json, ndjson or csv", apiFormat))
+	}
+	if apiPaginate != "" && apiPaginate != paginateVersion && apiPaginate != paginateBefore {
+		messenger.ExitWithError(fmt.Errorf("unknown pagination: %s, expecting version or before", apiPaginate))
+	}
+	if apiPaginate != "" && method != "GET" {
+		messenger.ExitWithError(fmt.Errorf("--paginate can only be used with GET requests"))
+	}
+
+	requestURL, err := apiURL(DomainPrefix, path)
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	body, err := readAPIData(apiData)
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	// Create new Vend Client.
+	vc := vend.NewClient(Token, DomainPrefix, "")
+	vendClient = &vc
+
+	// Anything but a GET can change data, so treat it like any other mutating command
+	if method != "GET" {
+		confirmDestructiveAction(destructiveAction{
+			Command: fmt.Sprintf("api %s", method),
+			Entity:  "requests",
+			IDs:     []string{path},
+		})
+		startRun(cmd)
+		currentRun.Flags["request"] = fmt.Sprintf("%s %s", method, path)
+	}
+
+	var responses [][]byte
+	if apiPaginate == "" {
+		res, err := vendClient.MakeRequest(method, requestURL, body)
+		if err != nil {
+			if method != "GET" {
+				finishRun(1, 0, "")
+			}
+			fmt.Fprintln(os.Stderr, string(res))
+			messenger.ExitWithError(fmt.Errorf("request failed: %w", err))
+		}
+		if method != "GET" {
+			finishRun(1, 1, "")
+		}
+		responses = append(responses, res)
+	} else {
+		responses, err = fetchAPIPages(requestURL, apiPaginate)
+		if err != nil {
+			messenger.ExitWithError(err)
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if apiOutput != "" {
+		file, err := os.Create(apiOutput)
+		if err != nil {
+			messenger.ExitWithError(fmt.Errorf("failed to create output file: %w", err))
+		}
+		defer file.Close()
+		out = file
+	}
+
+	// A single response with nothing to pick out is printed as Vend returned it
+	if apiFormat == "json" && apiPaginate == "" && len(apiFields) == 0 {
+		err = writeIndentedJSON(out, responses[0])
+	} else {
+		var records []interface{}
+		for _, res := range responses {
+			decoded, err := decodeAPIResponse(res)
+			if err != nil {
+				messenger.ExitWithError(err)
+			}
+			records = append(records, apiRecords(decoded)...)
+		}
+		err = writeAPIRecords(out, records, apiFields, apiFormat)
+	}
+	if err != nil {
+		messenger.ExitWithError(fmt.Errorf("failed to write output: %w", err))
+	}
+
+	if apiOutput != "" {
+		fmt.Println(color.GreenString("\nFinished! 🎉\nResponse written to %s", apiOutput))
+	}
+}
+
+// apiURL turns a path into a URL on the store. Full URLs are accepted as long as they point at the store,
+// so the token is never sent anywhere else.
+func apiURL(domainPrefix, path string) (string, error) {
+	host := fmt.Sprintf("%s.vendhq.com", domainPrefix)
+
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		parsed, err := url.Parse(path)
+		if err != nil {
+			return "", fmt.Errorf("invalid url: %s, %w", path, err)
+		}
+		if !strings.EqualFold(parsed.Host, host) {
+			return "", fmt.Errorf("url must be on %s, got %s", host, parsed.Host)
+		}
+		parsed.Scheme = "https"
+		return parsed.String(), nil
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return fmt.Sprintf("https://%s%s", host, path), nil
+}
+
+// readAPIData returns the request body, read from a file when the value starts with @
+func readAPIData(data string) (interface{}, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	raw := []byte(data)
+	if strings.HasPrefix(data, "@") {
+		var err error
+		raw, err = ioutil.ReadFile(strings.TrimPrefix(data, "@"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+	if !json.Valid(raw) {
+		return nil, fmt.Errorf("request body is not valid JSON")
+	}
+	return json.RawMessage(raw), nil
+}
+
+// fetchAPIPages follows version or before pagination until an empty page is returned
+func fetchAPIPages(requestURL, style string) ([][]byte, error) {
+	var responses [][]byte
+	cursor := ""
+	if style == paginateVersion {
+		cursor = "0"
+	}
+
+	for {
+		pageURL, err := url.Parse(requestURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url: %s, %w", requestURL, err)
+		}
+		query := pageURL.Query()
+		if style == paginateVersion {
+			query.Set("after", cursor)
+		} else if cursor != "" {
+			query.Set("before", cursor)
+		}
+		pageURL.RawQuery = query.Encode()
+
+		res, err := vendClient.MakeRequest("GET", pageURL.String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("request failed: %w response: %s", err, string(res))
+		}
+
+		page := struct {
+			Data    []map[string]interface{} `json:"data"`
+			Version map[string]json.Number   `json:"version"`
+		}{}
+		decoder := json.NewDecoder(bytes.NewReader(res))
+		decoder.UseNumber()
+		if err = decoder.Decode(&page); err != nil {
+			return nil, fmt.Errorf("response can not be paginated, expecting a list in data: %w", err)
+		}
+		if len(page.Data) == 0 {
+			break
+		}
+		responses = append(responses, res)
+		fmt.Fprintf(os.Stderr, "Fetched page %d (%d records)\n", len(responses), len(page.Data))
+
+		next := ""
+		if style == paginateVersion {
+			next = page.Version["max"].String()
+		} else if id, ok := page.Data[len(page.Data)-1]["id"]; ok {
+			next = fmt.Sprint(id)
+		}
+		// stop rather than loop forever if the cursor does not move
+		if next == "" || next == cursor {
+			break
+		}
+		cursor = next
+	}
+	return responses, nil
+}
+
+func decodeAPIResponse(res []byte) (interface{}, error) {
+	var decoded interface{}
+	decoder := json.NewDecoder(bytes.NewReader(res))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("response is not JSON: %w", err)
+	}
+	return decoded, nil
+}
+
+// apiRecords returns the items in "data" when the response has it, otherwise the response itself
+func apiRecords(response interface{}) []interface{} {
+	if object, ok := response.(map[string]interface{}); ok {
+		if data, ok := object["data"]; ok {
+			response = data
+		}
+	}
+	if list, ok := response.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{response}
+}
+
+// selectField walks a dotted path e.g. "variant_options.0.value" through objects and lists
+func selectField(record interface{}, path string) (interface{}, bool) {
+	value := record
+	for _, key := range strings.Split(path, ".") {
+		switch current := value.(type) {
+		case map[string]interface{}:
+			next, ok := current[key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil, false
+			}
+			value = current[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// pickFields reduces a record to the selected fields, keyed by their path
+func pickFields(record interface{}, fields []string) map[string]interface{} {
+	picked := map[string]interface{}{}
+	for _, field := range fields {
+		value, _ := selectField(record, field)
+		picked[field] = value
+	}
+	return picked
+}
+
+func writeAPIRecords(out io.Writer, records []interface{}, fields []string, format string) error {
+	if len(fields) > 0 {
+		picked := make([]interface{}, len(records))
+		for i, record := range records {
+			picked[i] = pickFields(record, fields)
+		}
+		records = picked
+	}
+
+	switch format {
+	case "ndjson":
+		encoder := json.NewEncoder(out)
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		return writeAPIRecordsCSV(out, records, fields)
+	default:
+		if records == nil {
+			records = []interface{}{}
+		}
+		encoded, err := json.Marshal(records)
+		if err != nil {
+			return err
+		}
+		return writeIndentedJSON(out, encoded)
+	}
+}
+
+// writeAPIRecordsCSV writes one row per record. Without fields the columns are every top level key.
+func writeAPIRecordsCSV(out io.Writer, records []interface{}, fields []string) error {
+	header := fields
+	if len(header) == 0 {
+		keys := map[string]bool{}
+		for _, record := range records {
+			object, ok := record.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("csv output needs records to be objects, use --fields or --format json")
+			}
+			for key := range object {
+				keys[key] = true
+			}
+		}
+		for key := range keys {
+			header = append(header, key)
+		}
+		sort.Strings(header)
+	}
+
+	writer := csv.NewWriter(out)
+	writer.Write(header)
+	for _, record := range records {
+		// fields were already picked into keys named after their path, so the columns are looked up as keys
+		object, _ := record.(map[string]interface{})
+		row := make([]string, len(header))
+		for i, field := range header {
+			row[i] = csvValue(object[field])
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvValue flattens a JSON value into a cell, nested objects and lists are kept as JSON
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
+	}
+}
+
+func writeIndentedJSON(out io.Writer, raw []byte) error {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, raw, "", "  "); err != nil {
+		// not JSON, print it as it came back
+		_, err = out.Write(raw)
+		return err
+	}
+	indented.WriteByte('\n')
+	_, err := indented.WriteTo(out)
+	return err
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIURL(t *testing.T) {
+	u, err := apiURL("store", "/api/2.0/products?page_size=10")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://store.vendhq.com/api/2.0/products?page_size=10", u)
+
+	u, err = apiURL("store", "api/2.0/outlets")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://store.vendhq.com/api/2.0/outlets", u)
+
+	u, err = apiURL("store", "https://store.vendhq.com/api/2.0/outlets")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://store.vendhq.com/api/2.0/outlets", u)
+
+	_, err = apiURL("store", "https://example.com/api/2.0/outlets")
+	assert.NotNil(t, err)
+}
+
+func TestAPIRecordsAndFields(t *testing.T) {
+	decoded, err := decodeAPIResponse([]byte(`{"data":[{"id":"a","price":1.50,"variant_options":[{"value":"Red"}]},{"id":"b"}],"version":{"max":12}}`))
+	assert.Nil(t, err)
+
+	records := apiRecords(decoded)
+	assert.Len(t, records, 2)
+
+	value, ok := selectField(records[0], "variant_options.0.value")
+	assert.True(t, ok)
+	assert.Equal(t, "Red", value)
+
+	_, ok = selectField(records[1], "variant_options.0.value")
+	assert.False(t, ok)
+
+	var out bytes.Buffer
+	err = writeAPIRecords(&out, records, []string{"id", "price"}, "csv")
+	assert.Nil(t, err)
+	assert.Equal(t, "id,price\na,1.50\nb,\n", out.String())
+
+	out.Reset()
+	err = writeAPIRecords(&out, apiRecords(decoded), []string{"id", "variant_options.0.value"}, "csv")
+	assert.Nil(t, err)
+	assert.Equal(t, "id,variant_options.0.value\na,Red\nb,\n", out.String())
+
+	single := apiRecords(map[string]interface{}{"data": map[string]interface{}{"id": "c"}})
+	assert.Len(t, single, 1)
+	assert.Equal(t, "c", single[0].(map[string]interface{})["id"])
+
+	assert.Equal(t, "12", csvValue(json.Number("12")))
+}
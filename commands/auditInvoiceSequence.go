@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vend/vend-cli/pkg/messenger"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vend/govend/vend"
+)
+
+// Command config
+var (
+	sequenceFilters    salesFilterFlags
+	sequencePrint      string
+	sequenceNoAuditLog bool
+
+	auditInvoiceSequenceCmd = &cobra.Command{
+		Use:   "audit-invoice-sequence",
+		Short: "Audit Invoice Numbering",
+		Long: fmt.Sprintf(`
+Checks the invoice numbering of every register between the dates, for fiscal audits. Reports:
+
+  Gap                 invoice sequence numbers with no sale
+  Duplicate Sequence  a sequence number used by more than one sale on a register
+  Duplicate Invoice   an invoice number used by more than one sale in the store
+  Out of Order        a sale dated before the sale with the previous sequence number
+  Deleted Sale        a sequence number whose sale was deleted
+  No Sequence         a sale without an invoice sequence number
+
+Findings about a sale list its audit log events. Duplicate invoice numbers can be fixed with update-sale-invoice-number.
+
+Example:
+%s`, color.GreenString("vendcli audit-invoice-sequence -d DOMAINPREFIX -t TOKEN -z TIMEZONE -F DATEFROM -T DATETO")),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			auditInvoiceSequence()
+		},
+	}
+)
+
+func init() {
+	// Flags
+	auditInvoiceSequenceCmd.Flags().StringVarP(&timeZone, "Timezone", "z", "", "Timezone of the store in zoneinfo format.")
+	auditInvoiceSequenceCmd.Flags().StringVarP(&dateFrom, "DateFrom", "F", "", "Date from (YYYY-MM-DD)")
+	auditInvoiceSequenceCmd.Flags().StringVarP(&dateTo, "DateTo", "T", "", "Date to (YYYY-MM-DD)")
+	auditInvoiceSequenceCmd.Flags().StringSliceVarP(&sequenceFilters.Outlets, "Outlet", "o", []string{"all"}, "Outlets to audit the registers of, or all")
+	auditInvoiceSequenceCmd.Flags().StringSliceVar(&sequenceFilters.Registers, "Register", nil, "Only audit these registers")
+	auditInvoiceSequenceCmd.Flags().BoolVar(&sequenceNoAuditLog, "no-auditlog", false, "Do not look up the audit log events of the sales found")
+	auditInvoiceSequenceCmd.Flags().StringVar(&sequencePrint, "print", "markdown", "Also write the findings as: markdown, html, none")
+	auditInvoiceSequenceCmd.MarkFlagRequired("Timezone")
+	auditInvoiceSequenceCmd.MarkFlagRequired("DateFrom")
+	auditInvoiceSequenceCmd.MarkFlagRequired("DateTo")
+
+	rootCmd.AddCommand(auditInvoiceSequenceCmd)
+}
+
+// sequenceFinding is a problem with the invoice numbering of a register
+type sequenceFinding struct {
+	Kind          string
+	Register      string
+	Sequence      string
+	InvoiceNumber string
+	SaleID        string
+	SaleDate      string
+	Detail        string
+	AuditLog      string
+}
+
+// registerSequence is the numbering of a register over the period
+type registerSequence struct {
+	RegisterID string
+	Register   string
+	Sales      int
+	First      int64
+	Last       int64
+	Findings   int
+}
+
+func auditInvoiceSequence() {
+	if err := checkReportFormat(sequencePrint); err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	vc := vend.NewClient(Token, DomainPrefix, timeZone)
+	fmt.Println("Auditing Invoice Sequence...")
+	sales, data := fetchSalesInRange(vc, sequenceFilters)
+
+	var events []vend.AuditLog
+	if !sequenceNoAuditLog {
+		fmt.Println("\nRetrieving Audit Log from Vend...")
+		utcDateFrom, _ := getUtcTime(dateFrom+"T00:00:00Z", timeZone)
+		var err error
+		// deletions can happen after the period, so read up to now
+		events, err = fetchAuditLog(strings.TrimSuffix(utcDateFrom, "Z"), time.Now().UTC().Format("2006-01-02T15:04:05"))
+		if err != nil {
+			fmt.Println(color.YellowString("\nCould not read the audit log, findings will not list audit events: %s", err))
+			events = nil
+		}
+	}
+
+	findings, registers := checkInvoiceSequence(sales, data, timeZone)
+	addAuditEvents(findings, events, data)
+
+	for _, register := range registers {
+		fmt.Printf("  %s: %d sales, sequence %d to %d, %d findings\n", register.Register, register.Sales, register.First, register.Last, register.Findings)
+	}
+
+	fileName := fmt.Sprintf("%s_invoice_sequence_audit_f%s_t%s.csv", DomainPrefix, dateFrom, dateTo)
+	files, err := sequenceTable(findings, registers).write(fileName, sequencePrint)
+	if err != nil {
+		err = fmt.Errorf("failed to write invoice sequence audit: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	if len(findings) == 0 {
+		fmt.Println(color.GreenString("\n\nFinished!🎉\nThe invoice numbering of %d registers is continuous: %s", len(registers), strings.Join(files, ", ")))
+		return
+	}
+	fmt.Println(color.YellowString("\n\nFinished!\nFound %d problems with the invoice numbering: %s", len(findings), strings.Join(files, ", ")))
+}
+
+// checkInvoiceSequence finds the gaps, duplicates, out of order dates and deleted sales in the numbering of each
+// register, and returns the findings in register and sequence order with a summary of every register
+func checkInvoiceSequence(sales []vend.Sale, data salesReportData, timeZone string) ([]*sequenceFinding, []registerSequence) {
+	// registers are told apart by id, stores often have a register of the same name in every outlet
+	byRegister := map[string][]vend.Sale{}
+	labels := map[string]string{}
+	var registerIDs []string
+	for _, sale := range sales {
+		registerID := stringOf(sale.RegisterID)
+		if _, ok := byRegister[registerID]; !ok {
+			registerIDs = append(registerIDs, registerID)
+			labels[registerID] = sequenceRegisterLabel(sale, data)
+		}
+		byRegister[registerID] = append(byRegister[registerID], sale)
+	}
+	sort.Slice(registerIDs, func(i, j int) bool {
+		if labels[registerIDs[i]] != labels[registerIDs[j]] {
+			return labels[registerIDs[i]] < labels[registerIDs[j]]
+		}
+		return registerIDs[i] < registerIDs[j]
+	})
+
+	var findings []*sequenceFinding
+	var summaries []registerSequence
+	for _, registerID := range registerIDs {
+		register := labels[registerID]
+		registerSales := byRegister[registerID]
+		sort.SliceStable(registerSales, func(i, j int) bool {
+			return sequenceOf(registerSales[i]) < sequenceOf(registerSales[j])
+		})
+
+		summary := registerSequence{RegisterID: registerID, Register: register, Sales: len(registerSales)}
+		var registerFindings []*sequenceFinding
+		var previous *vend.Sale
+		for i := range registerSales {
+			sale := registerSales[i]
+			finding := func(kind, detail string) {
+				registerFindings = append(registerFindings, newSequenceFinding(kind, register, sale, detail, timeZone))
+			}
+
+			if sale.InvoiceSequence == nil {
+				finding("No Sequence", "sale has no invoice sequence number")
+				continue
+			}
+			sequence := *sale.InvoiceSequence
+			if summary.First == 0 || sequence < summary.First {
+				summary.First = sequence
+			}
+			summary.Last = sequence
+
+			if previous != nil && sequence > *previous.InvoiceSequence+1 {
+				previousSequence := *previous.InvoiceSequence
+				gap := &sequenceFinding{Kind: "Gap", Register: register, Sequence: strconv.FormatInt(previousSequence+1, 10)}
+				if sequence > previousSequence+2 {
+					gap.Sequence += "-" + strconv.FormatInt(sequence-1, 10)
+				}
+				gap.Detail = fmt.Sprintf("%d sequence numbers missing after invoice %s", sequence-previousSequence-1, stringOf(previous.InvoiceNumber))
+				registerFindings = append(registerFindings, gap)
+			}
+			if sale.DeletedAt != nil {
+				finding("Deleted Sale", fmt.Sprintf("deleted at %s", *sale.DeletedAt))
+			}
+			if previous != nil {
+				if sequence == *previous.InvoiceSequence {
+					finding("Duplicate Sequence", fmt.Sprintf("sequence also used by sale %s", stringOf(previous.ID)))
+				}
+				if getTime((*sale.SaleDate)[:19] + "Z").Before(getTime((*previous.SaleDate)[:19] + "Z")) {
+					finding("Out of Order", fmt.Sprintf("dated before invoice %s with the previous sequence number", stringOf(previous.InvoiceNumber)))
+				}
+			}
+			previous = &registerSales[i]
+		}
+
+		summary.Findings = len(registerFindings)
+		findings = append(findings, registerFindings...)
+		summaries = append(summaries, summary)
+	}
+
+	// invoice numbers have to be unique across the store, not just the register. The first sale to use a number
+	// keeps it.
+	var live []vend.Sale
+	for _, sale := range sales {
+		if sale.InvoiceNumber != nil && *sale.InvoiceNumber != "" && sale.DeletedAt == nil {
+			live = append(live, sale)
+		}
+	}
+	sortBySaleDate(live)
+	firstSale := map[string]vend.Sale{}
+	for _, sale := range live {
+		first, seen := firstSale[*sale.InvoiceNumber]
+		if !seen {
+			firstSale[*sale.InvoiceNumber] = sale
+			continue
+		}
+		registerID := stringOf(sale.RegisterID)
+		detail := fmt.Sprintf("invoice number also used by sale %s on %s", stringOf(first.ID), labels[stringOf(first.RegisterID)])
+		findings = append(findings, newSequenceFinding("Duplicate Invoice", labels[registerID], sale, detail, timeZone))
+		for i := range summaries {
+			if summaries[i].RegisterID == registerID {
+				summaries[i].Findings++
+			}
+		}
+	}
+
+	return findings, summaries
+}
+
+// sequenceRegisterLabel is the name of a sale's register with its outlet, as shown in the audit
+func sequenceRegisterLabel(sale vend.Sale, data salesReportData) string {
+	label := data.registerName(sale.RegisterID)
+	if sale.OutletID != nil && data.Outlets[*sale.OutletID] != "" {
+		label += fmt.Sprintf(" (%s)", data.Outlets[*sale.OutletID])
+	}
+	return label
+}
+
+func newSequenceFinding(kind, register string, sale vend.Sale, detail, timeZone string) *sequenceFinding {
+	finding := &sequenceFinding{
+		Kind:          kind,
+		Register:      register,
+		InvoiceNumber: stringOf(sale.InvoiceNumber),
+		SaleID:        stringOf(sale.ID),
+		Detail:        detail,
+	}
+	if sale.InvoiceSequence != nil {
+		finding.Sequence = strconv.FormatInt(*sale.InvoiceSequence, 10)
+	}
+	if saleDate, err := vend.ParseVendDT(*sale.SaleDate, timeZone); err == nil {
+		finding.SaleDate = saleDate.Format("2006-01-02 15:04:05")
+	}
+	return finding
+}
+
+// addAuditEvents lists the audit log events of the sale of each finding, oldest first
+func addAuditEvents(findings []*sequenceFinding, events []vend.AuditLog, data salesReportData) {
+	bySale := map[string][]string{}
+	for _, event := range events {
+		if event.EntityID == nil {
+			continue
+		}
+		description := fmt.Sprintf("%s %s by %s (event %s)", stringOf(event.OccurredAt), stringOf(event.Action),
+			data.userName(event.UserID), stringOf(event.ID))
+		bySale[*event.EntityID] = append(bySale[*event.EntityID], description)
+	}
+	for _, finding := range findings {
+		if finding.SaleID == "" {
+			continue
+		}
+		descriptions := bySale[finding.SaleID]
+		sort.Strings(descriptions)
+		finding.AuditLog = strings.Join(descriptions, "; ")
+	}
+}
+
+// sequenceOf is the invoice sequence of a sale, with sales without one first
+func sequenceOf(sale vend.Sale) int64 {
+	if sale.InvoiceSequence == nil {
+		return -1
+	}
+	return *sale.InvoiceSequence
+}
+
+func stringOf(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func sequenceTable(findings []*sequenceFinding, registers []registerSequence) reportTable {
+	var rows [][]string
+	for _, finding := range findings {
+		rows = append(rows, []string{finding.Kind, finding.Register, finding.Sequence, finding.InvoiceNumber,
+			finding.SaleID, finding.SaleDate, finding.Detail, finding.AuditLog})
+	}
+
+	notes := []string{fmt.Sprintf("%s to %s", dateFrom, dateTo)}
+	for _, register := range registers {
+		notes = append(notes, fmt.Sprintf("%s: %d sales, sequence %d to %d, %d findings",
+			register.Register, register.Sales, register.First, register.Last, register.Findings))
+	}
+
+	return reportTable{
+		Title:  fmt.Sprintf("Invoice Sequence Audit %s", DomainPrefix),
+		Notes:  notes,
+		Header: []string{"Finding", "Register", "Sequence", "Invoice Number", "Sale ID", "Sale Date", "Detail", "Audit Log"},
+		Rows:   rows,
+	}
+}
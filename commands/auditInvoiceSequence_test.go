@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/govend/vend"
+)
+
+func TestCheckInvoiceSequence(t *testing.T) {
+	mainID, backID := "r1", "r2"
+	mainName, backName := "Main", "Back"
+	sales := []vend.Sale{
+		testSale(saleFixture{ID: "s1", Register: mainID, Sequence: 1, Invoice: "M-1", Date: "2024-03-01T01:00:00Z"}),
+		testSale(saleFixture{ID: "s2", Register: mainID, Sequence: 2, Invoice: "M-2", Date: "2024-03-01T02:00:00Z"}),
+		testSale(saleFixture{ID: "s4", Register: mainID, Sequence: 4, Invoice: "M-4", Date: "2024-03-01T04:00:00Z", DeletedAt: "2024-03-03T00:00:00Z"}),
+		// out of order
+		testSale(saleFixture{ID: "s5", Register: mainID, Sequence: 5, Invoice: "M-5", Date: "2024-03-01T03:00:00Z"}),
+		testSale(saleFixture{ID: "s8", Register: mainID, Sequence: 8, Invoice: "M-8", Date: "2024-03-01T08:00:00Z"}),
+		testSale(saleFixture{ID: "s8b", Register: mainID, Sequence: 8, Invoice: "M-8b", Date: "2024-03-01T08:30:00Z"}),
+		testSale(saleFixture{ID: "b1", Register: backID, Sequence: 1, Invoice: "M-1", Date: "2024-03-01T05:00:00Z"}),
+	}
+	data := newSalesReportData(nil, []vend.Register{{ID: &mainID, Name: &mainName}, {ID: &backID, Name: &backName}},
+		nil, nil, nil, nil, nil)
+
+	findings, registers := checkInvoiceSequence(sales, data, "UTC")
+
+	var kinds []string
+	for _, finding := range findings {
+		kinds = append(kinds, finding.Kind+" "+finding.Register+" "+finding.Sequence)
+	}
+	assert.Equal(t, []string{
+		"Gap Main 3",
+		"Deleted Sale Main 4",
+		"Out of Order Main 5",
+		"Gap Main 6-7",
+		"Duplicate Sequence Main 8",
+		"Duplicate Invoice Back 1",
+	}, kinds)
+	assert.Equal(t, []registerSequence{
+		{RegisterID: "r2", Register: "Back", Sales: 1, First: 1, Last: 1, Findings: 1},
+		{RegisterID: "r1", Register: "Main", Sales: 6, First: 1, Last: 8, Findings: 5},
+	}, registers)
+
+	userID, action, occurred, eventID, name := "u1", "sale.delete", "2024-03-03T00:00:00Z", "e1", "Anna"
+	data.Users["u1"] = vend.User{ID: &userID, DisplayName: &name}
+	saleID := "s4"
+	addAuditEvents(findings, []vend.AuditLog{{ID: &eventID, UserID: &userID, EntityID: &saleID, Action: &action, OccurredAt: &occurred}}, data)
+	assert.Equal(t, "2024-03-03T00:00:00Z sale.delete by Anna (event e1)", findings[1].AuditLog)
+}
+
+func TestCheckInvoiceSequenceSharedRegisterName(t *testing.T) {
+	cityID, mallID, cityOutlet, mallOutlet := "r1", "r2", "o1", "o2"
+	name := "Main Register"
+
+	sales := []vend.Sale{
+		testSale(saleFixture{ID: "c1", Invoice: "c1", Register: cityID, Outlet: cityOutlet, Sequence: 1, Date: "2024-03-01T01:00:00Z"}),
+		testSale(saleFixture{ID: "m1", Invoice: "m1", Register: mallID, Outlet: mallOutlet, Sequence: 1, Date: "2024-03-01T02:00:00Z"}),
+		testSale(saleFixture{ID: "c2", Invoice: "c2", Register: cityID, Outlet: cityOutlet, Sequence: 2, Date: "2024-03-01T03:00:00Z"}),
+		testSale(saleFixture{ID: "m3", Invoice: "m3", Register: mallID, Outlet: mallOutlet, Sequence: 3, Date: "2024-03-01T04:00:00Z"}),
+	}
+	data := newSalesReportData(map[string]string{cityOutlet: "City", mallOutlet: "Mall"},
+		[]vend.Register{{ID: &cityID, Name: &name}, {ID: &mallID, Name: &name}}, nil, nil, nil, nil, nil)
+
+	findings, registers := checkInvoiceSequence(sales, data, "UTC")
+
+	// each register keeps its own numbering, so the same sequence on both is not a duplicate
+	assert.Len(t, findings, 1)
+	assert.Equal(t, "Gap", findings[0].Kind)
+	assert.Equal(t, "Main Register (Mall)", findings[0].Register)
+	assert.Equal(t, []registerSequence{
+		{RegisterID: "r1", Register: "Main Register (City)", Sales: 2, First: 1, Last: 2},
+		{RegisterID: "r2", Register: "Main Register (Mall)", Sales: 2, First: 1, Last: 3, Findings: 1},
+	}, registers)
+}
@@ -0,0 +1,416 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vend/vend-cli/pkg/messenger"
+
+	"github.com/fatih/color"
+	"github.com/vend/govend/vend"
+)
+
+// chainGenesisHash is the previous hash of the first record of every register
+var chainGenesisHash = strings.Repeat("0", 64)
+
+// chainedRecord is a sale in a chained journal. Its hash is the SHA-256 of the record as JSON without the hash,
+// which includes the hash of the record before it on the register.
+type chainedRecord struct {
+	RegisterID    string           `json:"register_id"`
+	Register      string           `json:"register"`
+	Sequence      *int64           `json:"invoice_sequence"`
+	SaleID        string           `json:"sale_id"`
+	InvoiceNumber string           `json:"invoice_number"`
+	OutletID      string           `json:"outlet_id"`
+	UserID        string           `json:"user_id"`
+	CustomerID    string           `json:"customer_id"`
+	SaleDate      string           `json:"sale_date"`
+	Status        string           `json:"status"`
+	DeletedAt     string           `json:"deleted_at"`
+	TotalPrice    string           `json:"total_price"`
+	TotalTax      string           `json:"total_tax"`
+	Lines         []chainedLine    `json:"lines"`
+	Payments      []chainedPayment `json:"payments"`
+	PreviousHash  string           `json:"previous_hash"`
+	Hash          string           `json:"hash,omitempty"`
+}
+
+type chainedLine struct {
+	ProductID string `json:"product_id"`
+	Quantity  string `json:"quantity"`
+	Price     string `json:"price"`
+	Discount  string `json:"discount"`
+	Tax       string `json:"tax"`
+	TaxID     string `json:"tax_id"`
+	IsReturn  bool   `json:"is_return"`
+}
+
+type chainedPayment struct {
+	PaymentTypeID string `json:"retailer_payment_type_id"`
+	Name          string `json:"name"`
+	Amount        string `json:"amount"`
+}
+
+// journalManifest describes a chained journal and is signed, so the last record of each register and the journal file
+// as a whole can be checked
+type journalManifest struct {
+	Journal       string             `json:"journal"`
+	JournalSHA256 string             `json:"journal_sha256"`
+	DomainPrefix  string             `json:"domain_prefix"`
+	DateFrom      string             `json:"date_from"`
+	DateTo        string             `json:"date_to"`
+	TimeZone      string             `json:"timezone"`
+	CreatedAt     string             `json:"created_at"`
+	Registers     []manifestRegister `json:"registers"`
+	PublicKey     string             `json:"public_key"`
+	Signature     string             `json:"signature,omitempty"`
+}
+
+// manifestRegister is the chain of a register in a journal
+type manifestRegister struct {
+	RegisterID string `json:"register_id"`
+	Register   string `json:"register"`
+	Records    int    `json:"records"`
+	HeadHash   string `json:"head_hash"`
+}
+
+// exportChainedJournal writes every sale in the range as a hash chain per register, with a signed manifest
+func exportChainedJournal(signingKeyPath string) {
+	// the key is what makes the journal trustworthy, so it is never made up next to the output
+	if signingKeyPath == "" {
+		err := fmt.Errorf("--chained needs --signing-key, the store's ed25519 key kept outside the export folder")
+		messenger.ExitWithError(err)
+	}
+	key, created, err := loadSigningKey(signingKeyPath)
+	if err != nil {
+		err = fmt.Errorf("failed to load signing key %s: %w", signingKeyPath, err)
+		messenger.ExitWithError(err)
+	}
+	if created {
+		fmt.Println(color.YellowString("\nCreated the signing key %s, keep it safe and use it for every journal of this store. Its public key is in %s.",
+			signingKeyPath, publicKeyPath(signingKeyPath)))
+	}
+
+	vc := vend.NewClient(Token, DomainPrefix, timeZone)
+	fmt.Println("Creating Chained Sales Journal...")
+	sales, data := fetchSalesInRange(vc, journalFilters)
+	records, err := chainSales(sales, data)
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	fileName := fmt.Sprintf("%s_sales_journal_chained_f%s_t%s.ndjson", DomainPrefix, dateFrom, dateTo)
+	journalHash, err := writeChainedJournal(fileName, records)
+	if err != nil {
+		err = fmt.Errorf("failed to write journal: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	manifest := journalManifest{
+		Journal:       fileName,
+		JournalSHA256: journalHash,
+		DomainPrefix:  DomainPrefix,
+		DateFrom:      dateFrom,
+		DateTo:        dateTo,
+		TimeZone:      timeZone,
+		CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+		Registers:     chainHeads(records),
+	}
+	if err = manifest.sign(key); err != nil {
+		messenger.ExitWithError(err)
+	}
+	manifestFile := manifestPath(fileName)
+	if err = writeJSONFile(manifestFile, manifest); err != nil {
+		err = fmt.Errorf("failed to write journal manifest: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	fmt.Println(color.GreenString("\n\nFinished!🎉\nChained %d sales on %d registers to %s, signed in %s", len(records),
+		len(manifest.Registers), fileName, manifestFile))
+}
+
+// chainSales orders the sales by register and invoice sequence and links each to the one before it on its register
+func chainSales(sales []vend.Sale, data salesReportData) ([]chainedRecord, error) {
+	byRegister := map[string][]vend.Sale{}
+	var registerIDs []string
+	for _, sale := range sales {
+		registerID := stringOf(sale.RegisterID)
+		if _, ok := byRegister[registerID]; !ok {
+			registerIDs = append(registerIDs, registerID)
+		}
+		byRegister[registerID] = append(byRegister[registerID], sale)
+	}
+	sort.Slice(registerIDs, func(i, j int) bool {
+		nameI, nameJ := data.registerName(&registerIDs[i]), data.registerName(&registerIDs[j])
+		if nameI != nameJ {
+			return nameI < nameJ
+		}
+		return registerIDs[i] < registerIDs[j]
+	})
+
+	var records []chainedRecord
+	for _, registerID := range registerIDs {
+		registerSales := byRegister[registerID]
+		sortBySaleDate(registerSales)
+		sort.SliceStable(registerSales, func(i, j int) bool {
+			return sequenceOf(registerSales[i]) < sequenceOf(registerSales[j])
+		})
+
+		previous := chainGenesisHash
+		for _, sale := range registerSales {
+			record := newChainedRecord(sale, data)
+			record.PreviousHash = previous
+			hash, err := record.hash()
+			if err != nil {
+				return nil, err
+			}
+			record.Hash = hash
+			records = append(records, record)
+			previous = hash
+		}
+	}
+	return records, nil
+}
+
+func newChainedRecord(sale vend.Sale, data salesReportData) chainedRecord {
+	record := chainedRecord{
+		RegisterID:    stringOf(sale.RegisterID),
+		Register:      data.registerName(sale.RegisterID),
+		Sequence:      sale.InvoiceSequence,
+		SaleID:        stringOf(sale.ID),
+		InvoiceNumber: stringOf(sale.InvoiceNumber),
+		OutletID:      stringOf(sale.OutletID),
+		UserID:        stringOf(sale.UserID),
+		CustomerID:    stringOf(sale.CustomerID),
+		SaleDate:      stringOf(sale.SaleDate),
+		Status:        stringOf(sale.Status),
+		DeletedAt:     stringOf(sale.DeletedAt),
+		TotalPrice:    chainAmount(sale.TotalPrice),
+		TotalTax:      chainAmount(sale.TotalTax),
+		Lines:         []chainedLine{},
+		Payments:      []chainedPayment{},
+	}
+	for _, lineitem := range saleLines(sale) {
+		record.Lines = append(record.Lines, chainedLine{
+			ProductID: stringOf(lineitem.ProductID),
+			Quantity:  chainAmount(lineitem.Quantity),
+			Price:     chainAmount(lineitem.Price),
+			Discount:  chainAmount(lineitem.Discount),
+			Tax:       chainAmount(lineitem.Tax),
+			TaxID:     stringOf(lineitem.TaxID),
+			IsReturn:  lineitem.IsReturn != nil && *lineitem.IsReturn,
+		})
+	}
+	if sale.Payments != nil {
+		for _, payment := range *sale.Payments {
+			record.Payments = append(record.Payments, chainedPayment{
+				PaymentTypeID: stringOf(payment.RetailerPaymentTypeID),
+				Name:          stringOf(payment.Name),
+				Amount:        chainAmount(payment.Amount),
+			})
+		}
+	}
+	return record
+}
+
+// chainAmount writes amounts as strings, so the hashed form does not depend on how a reader parses numbers
+func chainAmount(amount *float64) string {
+	if amount == nil {
+		return "0"
+	}
+	return strconv.FormatFloat(*amount, 'f', -1, 64)
+}
+
+// hash is the hex SHA-256 of the record without its own hash
+func (r chainedRecord) hash() (string, error) {
+	r.Hash = ""
+	canonical, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// chainHeads is the last record of each register's chain, in journal order
+func chainHeads(records []chainedRecord) []manifestRegister {
+	var heads []manifestRegister
+	for _, record := range records {
+		if len(heads) == 0 || heads[len(heads)-1].RegisterID != record.RegisterID {
+			heads = append(heads, manifestRegister{RegisterID: record.RegisterID, Register: record.Register})
+		}
+		head := &heads[len(heads)-1]
+		head.Records++
+		head.HeadHash = record.Hash
+	}
+	return heads
+}
+
+// writeChainedJournal writes a record per line and returns the SHA-256 of the file
+func writeChainedJournal(fileName string, records []chainedRecord) (string, error) {
+	file, err := os.Create(fmt.Sprintf("./%s", fileName))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	writer := bufio.NewWriter(io.MultiWriter(file, hash))
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return "", err
+		}
+		writer.Write(append(line, '\n'))
+	}
+	if err = writer.Flush(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// manifestPath is where the manifest of a journal is written
+func manifestPath(journalPath string) string {
+	return strings.TrimSuffix(journalPath, ".ndjson") + "_manifest.json"
+}
+
+// signedBytes is the manifest as JSON without its signature
+func (m journalManifest) signedBytes() ([]byte, error) {
+	m.Signature = ""
+	return json.Marshal(m)
+}
+
+// sign adds the public key and signature of the key to the manifest
+func (m *journalManifest) sign(key ed25519.PrivateKey) error {
+	m.PublicKey = hex.EncodeToString(key.Public().(ed25519.PublicKey))
+	message, err := m.signedBytes()
+	if err != nil {
+		return err
+	}
+	m.Signature = hex.EncodeToString(ed25519.Sign(key, message))
+	return nil
+}
+
+// verify checks the signature of the manifest. Without a trusted key it can only check the manifest was signed by
+// the key it names.
+func (m journalManifest) verify(trusted ed25519.PublicKey) error {
+	publicKey, err := hex.DecodeString(m.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return errors.New("the manifest has no valid public key")
+	}
+	if trusted == nil {
+		return errors.New("no trusted public key to check the manifest against")
+	}
+	if !bytes.Equal(trusted, publicKey) {
+		return errors.New("the manifest was not signed with the trusted public key")
+	}
+	signature, err := hex.DecodeString(m.Signature)
+	if err != nil {
+		return errors.New("the manifest has no valid signature")
+	}
+	message, err := m.signedBytes()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(publicKey, message, signature) {
+		return errors.New("the manifest signature does not match, the manifest has been changed")
+	}
+	return nil
+}
+
+// loadSigningKey reads a PEM private key, creating it and its public key when the file does not exist
+func loadSigningKey(path string) (ed25519.PrivateKey, bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key, err := createSigningKey(path)
+		return key, err == nil, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, false, errors.New("not a PEM file")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, false, err
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, false, errors.New("not an ed25519 private key")
+	}
+	return key, false, nil
+}
+
+func createSigningKey(path string) (ed25519.PrivateKey, error) {
+	publicKey, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	private, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	public, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private}), 0600); err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(publicKeyPath(path), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}), 0644); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// publicKeyPath is where the public key of a signing key is written
+func publicKeyPath(keyPath string) string {
+	return strings.TrimSuffix(keyPath, ".pem") + "_public.pem"
+}
+
+// readPublicKey reads a PEM public key
+func readPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("not a PEM file")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("not an ed25519 public key")
+	}
+	return key, nil
+}
+
+func writeJSONFile(fileName string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fmt.Sprintf("./%s", fileName), append(data, '\n'), 0644)
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/govend/vend"
+)
+
+func TestChainedJournal(t *testing.T) {
+	mainID, backID := "r1", "r2"
+	mainName, backName := "Main", "Back"
+	date := "2024-03-01T01:00:00Z"
+
+	sales := []vend.Sale{
+		testSale(saleFixture{ID: "s2", Register: mainID, Sequence: 2, Status: "CLOSED", Date: date, Total: 20}),
+		testSale(saleFixture{ID: "b1", Register: backID, Sequence: 1, Status: "CLOSED", Date: date, Total: 5}),
+		testSale(saleFixture{ID: "s1", Register: mainID, Sequence: 1, Status: "CLOSED", Date: date, Total: 10}),
+		testSale(saleFixture{ID: "s3", Register: mainID, Sequence: 3, Status: "CLOSED", Date: date, Total: 30}),
+	}
+	data := newSalesReportData(nil, []vend.Register{{ID: &mainID, Name: &mainName}, {ID: &backID, Name: &backName}},
+		nil, nil, nil, nil, nil)
+
+	records, err := chainSales(sales, data)
+	assert.Nil(t, err)
+	var order []string
+	for _, record := range records {
+		order = append(order, record.SaleID)
+	}
+	assert.Equal(t, []string{"b1", "s1", "s2", "s3"}, order)
+	assert.Equal(t, chainGenesisHash, records[0].PreviousHash)
+	assert.Equal(t, chainGenesisHash, records[1].PreviousHash)
+	assert.Equal(t, records[1].Hash, records[2].PreviousHash)
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(t.TempDir())
+	journalHash, err := writeChainedJournal("journal.ndjson", records)
+	assert.Nil(t, err)
+	journal, err := os.ReadFile("journal.ndjson")
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(journal), "\n"), "\n")
+
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	manifest := journalManifest{JournalSHA256: journalHash, Registers: chainHeads(records)}
+	assert.Nil(t, manifest.sign(key))
+	assert.Nil(t, manifest.verify(key.Public().(ed25519.PublicKey)))
+	assert.Error(t, manifest.verify(nil))
+
+	verify := func(lines []string) []string {
+		count, problems, err := verifyChainedJournal(strings.NewReader(strings.Join(lines, "\n")+"\n"), manifest)
+		assert.Nil(t, err)
+		assert.NotZero(t, count)
+		return problems
+	}
+	assert.Empty(t, verify(lines))
+
+	altered := append([]string{}, lines...)
+	altered[2] = strings.Replace(altered[2], `"total_price":"20"`, `"total_price":"2"`, 1)
+	assert.Contains(t, verify(altered), "line 3: sale s2 has been altered")
+
+	removed := append(append([]string{}, lines[:2]...), lines[3:]...)
+	assert.Contains(t, verify(removed), "line 3: sale s3 does not follow the previous record of register Main, a record was inserted or removed before it")
+
+	problems := verify(lines[:3])
+	assert.Len(t, problems, 2)
+	assert.True(t, strings.HasPrefix(problems[0], "register Main has 2 records"))
+	assert.Equal(t, "the journal file does not match the SHA-256 in the manifest", problems[1])
+
+	// a whole new chain needs a new manifest, which the trusted key did not sign
+	forgedRecords, _ := chainSales(sales[:3], data)
+	forgedManifest := manifest
+	forgedManifest.Registers = chainHeads(forgedRecords)
+	assert.Error(t, forgedManifest.verify(key.Public().(ed25519.PublicKey)))
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, forgedManifest.sign(other))
+	assert.Error(t, forgedManifest.verify(key.Public().(ed25519.PublicKey)))
+}
@@ -27,6 +27,7 @@ This tool requires a CSV of Congsignment IDs, no headers.
 
 Example:
 %s`, color.GreenString("vendcli delete-consignments -d DOMAINPREFIX -t TOKEN -f FILENAME.csv")),
+	Annotations: map[string]string{mutatingAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		deleteConsignments()
 	},
@@ -34,8 +35,9 @@ Example:
 
 func init() {
 	// Flag
-	deleteConsignmentsCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv")
+	deleteConsignmentsCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv, or - to read standard input")
 	deleteConsignmentsCmd.MarkFlagRequired("Filename")
+	addIDColumnFlag(deleteConsignmentsCmd)
 
 	rootCmd.AddCommand(deleteConsignmentsCmd)
 }
@@ -48,12 +50,19 @@ func deleteConsignments() {
 
 	// Get passed entities from CSV
 	fmt.Println("\nReading CSV...")
-	ids, err := csvparser.ReadIdCSV(FilePath)
+	ids, err := readIDFile(FilePath)
 	if err != nil {
 		err = fmt.Errorf("failed to get IDs from the file: %s\nError:%s", FilePath, err)
 		messenger.ExitWithError(err)
 	}
 
+	confirmDestructiveAction(destructiveAction{
+		Command:  "delete-consignments",
+		Entity:   "consignments",
+		IDs:      ids,
+		Describe: describeConsignment,
+	})
+
 	failedRequests := []FailedDeleteRequest{}
 
 	// Make the requests
@@ -78,16 +87,18 @@ func deleteConsignments() {
 	}
 	p.Wait()
 
+	var failureFile string
 	if len(failedRequests) > 0 {
 		fmt.Println(color.RedString("\n\nThere were some errors. Writing failures to csv.."))
-		fileName := fmt.Sprintf("%s_failed_delete_consignment_requests__%v.csv", DomainPrefix, time.Now().Unix())
-		err := csvparser.WriteErrorCSV(fileName, failedRequests)
+		failureFile = fmt.Sprintf("%s_failed_delete_consignment_requests__%v.csv", DomainPrefix, time.Now().Unix())
+		err := csvparser.WriteErrorCSV(failureFile, failedRequests)
 		if err != nil {
 			messenger.ExitWithError(err)
 			return
 		}
 
 	}
+	finishRun(len(ids), count, failureFile)
 
 	fmt.Printf(color.GreenString("\n\nFinished! 🎉\nDeleted %d out of %d consignments"), count, len(ids))
 }
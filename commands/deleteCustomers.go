@@ -27,6 +27,7 @@ This tool requires a CSV of Customer IDs, no headers.
 
 Example:
 %s`, color.GreenString("vendcli delete-customers -d DOMAINPREFIX -t TOKEN -f FILENAME.csv")),
+	Annotations: map[string]string{mutatingAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		deleteCustomers()
 	},
@@ -34,8 +35,9 @@ Example:
 
 func init() {
 	// Flag
-	deleteCustomersCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv")
+	deleteCustomersCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv, or - to read standard input")
 	deleteCustomersCmd.MarkFlagRequired("Filename")
+	addIDColumnFlag(deleteCustomersCmd)
 
 	rootCmd.AddCommand(deleteCustomersCmd)
 }
@@ -48,12 +50,19 @@ func deleteCustomers() {
 
 	// Get passed entities from CSV
 	fmt.Println("\nReading CSV...")
-	ids, err := csvparser.ReadIdCSV(FilePath)
+	ids, err := readIDFile(FilePath)
 	if err != nil {
 		err = fmt.Errorf("failed to get IDs from the file: %s Error:%s", FilePath, err)
 		messenger.ExitWithError(err)
 	}
 
+	confirmDestructiveAction(destructiveAction{
+		Command:  "delete-customers",
+		Entity:   "customers",
+		IDs:      ids,
+		Describe: describeCustomer,
+	})
+
 	failedRequests := []FailedCustomerDeleteRequest{}
 
 	// Make the requests
@@ -73,21 +82,23 @@ func deleteCustomers() {
 	}
 	p.Wait()
 
+	var failureFile string
 	if len(failedRequests) > 0 {
 		fmt.Println(color.RedString("\nThere were some errors. Writing failures to csv.."))
-		saveFailedCustomerDeleteRequestsToCSV(failedRequests)
+		failureFile = saveFailedCustomerDeleteRequestsToCSV(failedRequests)
 	}
+	finishRun(len(ids), len(ids)-len(failedRequests), failureFile)
 
 	fmt.Println(color.GreenString("\n\nFinished! 🎉\n"))
 
 }
 
-func saveFailedCustomerDeleteRequestsToCSV(failedRequests []FailedCustomerDeleteRequest) {
+func saveFailedCustomerDeleteRequestsToCSV(failedRequests []FailedCustomerDeleteRequest) string {
 
 	fileName := fmt.Sprintf("%s_failed_delete_customer_requests__%v.csv", DomainPrefix, time.Now().Unix())
 	err := csvparser.WriteErrorCSV(fileName, failedRequests)
 	if err != nil {
 		messenger.ExitWithError(err)
-		return
 	}
+	return fileName
 }
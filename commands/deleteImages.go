@@ -27,6 +27,7 @@ This tool requires a CSV of Image IDs, no headers.
 
 Example:
 %s`, color.GreenString("vendcli delete-images -d DOMAINPREFIX -t TOKEN -f FILENAME.csv")),
+	Annotations: map[string]string{mutatingAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		deleteImages()
 	},
@@ -34,8 +35,9 @@ Example:
 
 func init() {
 	// Flag
-	deleteImagesCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv")
+	deleteImagesCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv, or - to read standard input")
 	deleteImagesCmd.MarkFlagRequired("Filename")
+	addIDColumnFlag(deleteImagesCmd)
 
 	rootCmd.AddCommand(deleteImagesCmd)
 }
@@ -48,12 +50,19 @@ func deleteImages() {
 
 	// Get passed entities from CSV
 	fmt.Println("\nReading CSV...")
-	ids, err := csvparser.ReadIdCSV(FilePath)
+	ids, err := readIDFile(FilePath)
 	if err != nil {
 		err = fmt.Errorf("failed to get IDs from the file: %s Error:%s", FilePath, err)
 		messenger.ExitWithError(err)
 	}
 
+	confirmDestructiveAction(destructiveAction{
+		Command:  "delete-images",
+		Entity:   "images",
+		IDs:      ids,
+		Describe: describeImage,
+	})
+
 	failedRequests := []FailedImageDeleteRequest{}
 
 	// Make the requests
@@ -79,15 +88,17 @@ func deleteImages() {
 	}
 	p.Wait()
 
+	var failureFile string
 	if len(failedRequests) > 0 {
 		fmt.Println(color.RedString("\n\nThere were some errors. Writing failures to csv.."))
-		fileName := fmt.Sprintf("%s_failed_delete_image_requests__%v.csv", DomainPrefix, time.Now().Unix())
-		err := csvparser.WriteErrorCSV(fileName, failedRequests)
+		failureFile = fmt.Sprintf("%s_failed_delete_image_requests__%v.csv", DomainPrefix, time.Now().Unix())
+		err := csvparser.WriteErrorCSV(failureFile, failedRequests)
 		if err != nil {
 			messenger.ExitWithError(err)
 			return
 		}
 	}
+	finishRun(len(ids), count, failureFile)
 
 	fmt.Printf(color.GreenString("\n\nFinished! 🎉\nDeleted %d out of %d images"), count, len(ids))
 }
@@ -27,6 +27,7 @@ This tool requires a CSV of Product IDs, no headers.
 
 Example:
 %s`, color.GreenString("vendcli delete-products -d DOMAINPREFIX -t TOKEN -f FILENAME.csv")),
+	Annotations: map[string]string{mutatingAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		deleteProducts()
 	},
@@ -34,8 +35,9 @@ Example:
 
 func init() {
 	// Flag
-	deleteProductsCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv")
+	deleteProductsCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv, or - to read standard input")
 	deleteProductsCmd.MarkFlagRequired("Filename")
+	addIDColumnFlag(deleteProductsCmd)
 
 	rootCmd.AddCommand(deleteProductsCmd)
 }
@@ -48,12 +50,19 @@ func deleteProducts() {
 
 	// Get passed entities from CSV
 	fmt.Println("\nReading CSV...")
-	ids, err := csvparser.ReadIdCSV(FilePath)
+	ids, err := readIDFile(FilePath)
 	if err != nil {
 		err = fmt.Errorf("failed to get ids from the file: %s Error:%s", FilePath, err)
 		messenger.ExitWithError(err)
 	}
 
+	confirmDestructiveAction(destructiveAction{
+		Command:  "delete-products",
+		Entity:   "products",
+		IDs:      ids,
+		Describe: describeProduct,
+	})
+
 	failedRequests := []FailedDeleteProductRequest{}
 
 	// Make the requests
@@ -77,15 +86,17 @@ func deleteProducts() {
 	}
 	p.Wait()
 
+	var failureFile string
 	if len(failedRequests) > 0 {
 		fmt.Println(color.RedString("\n\nThere were some errors. Writing failures to csv.."))
-		fileName := fmt.Sprintf("%s_failed_delete_product_requests_%v.csv", DomainPrefix, time.Now().Unix())
-		err := csvparser.WriteErrorCSV(fileName, failedRequests)
+		failureFile = fmt.Sprintf("%s_failed_delete_product_requests_%v.csv", DomainPrefix, time.Now().Unix())
+		err := csvparser.WriteErrorCSV(failureFile, failedRequests)
 		if err != nil {
 			messenger.ExitWithError(err)
 			return
 		}
 	}
+	finishRun(len(ids), count, failureFile)
 
 	fmt.Printf(color.GreenString("\n\nFinished! 🎉\nDeleted %d out of %d consignments\n"), count, len(ids))
 }
@@ -0,0 +1,501 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vend/vend-cli/pkg/csvparser"
+	"github.com/vend/vend-cli/pkg/messenger"
+	pbar "github.com/vend/vend-cli/pkg/progressbar"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vend/govend/vend"
+)
+
+// Command config
+var (
+	customerValueFilters salesFilterFlags
+	customerValueField   int
+	customerValueGroups  bool
+
+	exportCustomerValueCmd = &cobra.Command{
+		Use:   "export-customer-value",
+		Short: "Export Customer Lifetime Value and RFM Segments",
+		Long: fmt.Sprintf(`
+Exports every customer who bought between the dates with their first and last purchase, visits, spend including tax,
+average order value and preferred outlet, scored 1 to 5 on recency, frequency and monetary value (RFM) against the
+other customers and labelled with a segment:
+
+  Champions, Loyal, New, Promising, Can't Lose, At Risk, Hibernating, Lost
+
+The segment can be written back to Vend, into a customer custom field with --write-field 1-4, or by moving
+customers into the customer group named after their segment with --write-group. Writing back asks for
+confirmation, and the original values are saved to a snapshot file first, which vendcli restore puts back.
+
+Example:
+%s`, color.GreenString("vendcli export-customer-value -d DOMAINPREFIX -t TOKEN -z TIMEZONE -F 2022-01-01 -T 2024-03-31 --write-field 2")),
+
+		Annotations: map[string]string{mutatingAnnotation: "true"},
+		Run: func(cmd *cobra.Command, args []string) {
+			exportCustomerValue()
+		},
+	}
+)
+
+func init() {
+	// Flags
+	addSalesRangeFlags(exportCustomerValueCmd, &customerValueFilters)
+	exportCustomerValueCmd.Flags().IntVar(&customerValueField, "write-field", 0, "Write the segment into this customer custom field (1-4)")
+	exportCustomerValueCmd.Flags().BoolVar(&customerValueGroups, "write-group", false, "Move customers into the customer group named after their segment")
+
+	rootCmd.AddCommand(exportCustomerValueCmd)
+}
+
+// customerValue is the purchase history and RFM score of a customer
+type customerValue struct {
+	Customer      vend.Customer
+	FirstPurchase time.Time
+	LastPurchase  time.Time
+	Visits        int
+	Spend         float64
+	// visits and spend by outlet name, for the preferred outlet
+	OutletVisits map[string]int
+	OutletSpend  map[string]float64
+
+	Recency   int
+	Frequency int
+	Monetary  int
+	Segment   string
+}
+
+// customerWrite is a customer whose segment is written back to Vend
+type customerWrite struct {
+	ID       string
+	Field    string
+	Value    string
+	Original string
+}
+
+// FailedCustomerWrite is a customer whose segment could not be written back
+type FailedCustomerWrite struct {
+	CustomerID string
+	Field      string
+	Value      string
+	Reason     string
+}
+
+func exportCustomerValue() {
+	if customerValueField != 0 && (customerValueField < 1 || customerValueField > 4) {
+		messenger.ExitWithError(fmt.Errorf("--write-field must be a custom field from 1 to 4"))
+	}
+	writeBack := customerValueField != 0 || customerValueGroups
+	if !writeBack {
+		// exporting only does not change the store
+		skipRun()
+	}
+
+	vc := vend.NewClient(Token, DomainPrefix, timeZone)
+	fmt.Println("Creating Customer Value Export...")
+	sales, data := fetchReportSales(vc, customerValueFilters, false)
+
+	// recency is measured from the end of the last day
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+	lastDay, err := time.ParseInLocation("2006-01-02", dateTo, location)
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+	end := lastDay.AddDate(0, 0, 1)
+
+	values := buildCustomerValues(sales, data, timeZone)
+	scoreCustomerValues(values, end)
+
+	fileName := fmt.Sprintf("%s_customer_value_f%s_t%s.csv", DomainPrefix, dateFrom, dateTo)
+	if err := customerValueTable(values, data, end).writeCSV(fileName); err != nil {
+		err = fmt.Errorf("failed to write customer value export: %w", err)
+		messenger.ExitWithError(err)
+	}
+	fmt.Println(color.GreenString("\nExported %d customers: %s", len(values), fileName))
+
+	if !writeBack {
+		fmt.Println(color.GreenString("\n\nFinished!🎉"))
+		return
+	}
+
+	writes, err := customerWrites(values, data, customerValueField, customerValueGroups)
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+	if len(writes) == 0 {
+		skipRun()
+		fmt.Println(color.GreenString("\n\nFinished!🎉\nEvery customer already has their segment"))
+		return
+	}
+	writeCustomerSegments(writes, data)
+}
+
+// buildCustomerValues adds up the purchases of every customer with a sale, by customer id. A sale that returns more
+// than it sells is not a visit, but does take away from the spend.
+func buildCustomerValues(sales []vend.Sale, data salesReportData, timeZone string) map[string]*customerValue {
+	values := map[string]*customerValue{}
+	for _, sale := range sales {
+		if sale.CustomerID == nil || *sale.CustomerID == "" {
+			continue
+		}
+		saleDate, err := vend.ParseVendDT(*sale.SaleDate, timeZone)
+		if err != nil {
+			fmt.Printf("Error parsing date: %s\n", err)
+			continue
+		}
+
+		value, ok := values[*sale.CustomerID]
+		if !ok {
+			customer, ok := data.Customers[*sale.CustomerID]
+			if !ok || customer.DeletedAt != nil {
+				continue
+			}
+			value = &customerValue{Customer: customer, OutletVisits: map[string]int{}, OutletSpend: map[string]float64{}}
+			values[*sale.CustomerID] = value
+		}
+
+		var outlet string
+		if sale.OutletID != nil {
+			outlet = data.Outlets[*sale.OutletID]
+		}
+		var sold, returned float64
+		if sale.LineItems != nil {
+			for _, lineitem := range *sale.LineItems {
+				if lineitem.Quantity == nil || lineitem.Price == nil {
+					continue
+				}
+				amount := *lineitem.Price * *lineitem.Quantity
+				if lineitem.Tax != nil {
+					amount += *lineitem.Tax * *lineitem.Quantity
+				}
+				if amount < 0 {
+					returned -= amount
+				} else {
+					sold += amount
+				}
+			}
+		}
+		value.Spend += sold - returned
+		value.OutletSpend[outlet] += sold - returned
+		if returned > sold {
+			continue
+		}
+
+		value.Visits++
+		value.OutletVisits[outlet]++
+		if value.FirstPurchase.IsZero() || saleDate.Before(value.FirstPurchase) {
+			value.FirstPurchase = saleDate
+		}
+		if saleDate.After(value.LastPurchase) {
+			value.LastPurchase = saleDate
+		}
+	}
+
+	// customers who only returned goods have no purchases to score
+	for id, value := range values {
+		if value.Visits == 0 {
+			delete(values, id)
+		}
+	}
+	return values
+}
+
+// preferredOutlet is the outlet a customer visits most, then spends most at
+func (v customerValue) preferredOutlet() string {
+	var preferred string
+	for outlet, visits := range v.OutletVisits {
+		best := v.OutletVisits[preferred]
+		if preferred == "" || visits > best ||
+			(visits == best && v.OutletSpend[outlet] > v.OutletSpend[preferred]) ||
+			(visits == best && v.OutletSpend[outlet] == v.OutletSpend[preferred] && outlet < preferred) {
+			preferred = outlet
+		}
+	}
+	return preferred
+}
+
+// scoreCustomerValues scores every customer 1 to 5 on recency, frequency and spend by quintile, and labels their
+// segment. Recency is the days from the last purchase to end.
+func scoreCustomerValues(values map[string]*customerValue, end time.Time) {
+	var customers []*customerValue
+	for _, value := range values {
+		customers = append(customers, value)
+	}
+
+	rank := func(less func(a, b *customerValue) bool, score func(v *customerValue, s int)) {
+		sort.SliceStable(customers, func(i, j int) bool { return less(customers[i], customers[j]) })
+		for i := 0; i < len(customers); {
+			// equal values get the same score
+			j := i
+			for j < len(customers) && !less(customers[i], customers[j]) && !less(customers[j], customers[i]) {
+				j++
+			}
+			s := i*5/len(customers) + 1
+			for ; i < j; i++ {
+				score(customers[i], s)
+			}
+		}
+	}
+
+	// sorted worst first, so the best get a 5
+	longerAgo := func(a, b *customerValue) bool {
+		return daysBetween(a.LastPurchase, end) > daysBetween(b.LastPurchase, end)
+	}
+	fewerVisits := func(a, b *customerValue) bool { return a.Visits < b.Visits }
+	lessSpend := func(a, b *customerValue) bool { return math.Round(a.Spend*100) < math.Round(b.Spend*100) }
+	rank(longerAgo, func(v *customerValue, s int) { v.Recency = s })
+	rank(fewerVisits, func(v *customerValue, s int) { v.Frequency = s })
+	rank(lessSpend, func(v *customerValue, s int) { v.Monetary = s })
+
+	for _, value := range customers {
+		value.Segment = rfmSegment(value.Recency, value.Frequency, value.Monetary)
+	}
+}
+
+// rfmSegment labels a customer from their recency, frequency and monetary scores
+func rfmSegment(r, f, m int) string {
+	switch {
+	case r >= 4 && f >= 4 && m >= 4:
+		return "Champions"
+	case r >= 3 && f >= 3:
+		return "Loyal"
+	case r >= 4:
+		return "New"
+	case r == 3:
+		return "Promising"
+	case f >= 4:
+		return "Can't Lose"
+	case f >= 2 || m >= 3:
+		return "At Risk"
+	case r == 2:
+		return "Hibernating"
+	default:
+		return "Lost"
+	}
+}
+
+// daysBetween is the number of whole days from one time to a later one
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+func customerValueTable(values map[string]*customerValue, data salesReportData, end time.Time) reportTable {
+	var customers []*customerValue
+	for _, value := range values {
+		customers = append(customers, value)
+	}
+	// most valuable first
+	sort.SliceStable(customers, func(i, j int) bool {
+		if customers[i].Spend != customers[j].Spend {
+			return customers[i].Spend > customers[j].Spend
+		}
+		return *customers[i].Customer.ID < *customers[j].Customer.ID
+	})
+
+	var rows [][]string
+	for _, value := range customers {
+		customer := value.Customer
+		var code, email, group string
+		if customer.Code != nil {
+			code = *customer.Code
+		}
+		if customer.Email != nil {
+			email = *customer.Email
+		}
+		if customer.GroupId != nil {
+			group = data.CustomerGroups[*customer.GroupId]
+		}
+		rows = append(rows, []string{
+			*customer.ID,
+			code,
+			customerFullName(customer),
+			email,
+			group,
+			value.FirstPurchase.Format("2006-01-02"),
+			value.LastPurchase.Format("2006-01-02"),
+			strconv.Itoa(daysBetween(value.LastPurchase, end)),
+			strconv.Itoa(value.Visits),
+			formatCents(value.Spend),
+			formatCents(value.Spend / float64(value.Visits)),
+			value.preferredOutlet(),
+			strconv.Itoa(value.Recency),
+			strconv.Itoa(value.Frequency),
+			strconv.Itoa(value.Monetary),
+			fmt.Sprintf("%d%d%d", value.Recency, value.Frequency, value.Monetary),
+			value.Segment,
+		})
+	}
+
+	return reportTable{
+		Header: []string{"Customer ID", "Customer Code", "Customer Name", "Customer Email", "Customer Group",
+			"First Purchase", "Last Purchase", "Days Since Last Purchase", "Visits", "Lifetime Spend",
+			"Average Order Value", "Preferred Outlet", "Recency", "Frequency", "Monetary", "RFM", "Segment"},
+		Rows: rows,
+	}
+}
+
+func customerFullName(customer vend.Customer) string {
+	var name []string
+	if customer.FirstName != nil && *customer.FirstName != "" {
+		name = append(name, *customer.FirstName)
+	}
+	if customer.LastName != nil && *customer.LastName != "" {
+		name = append(name, *customer.LastName)
+	}
+	return strings.Join(name, " ")
+}
+
+// customerWrites are the changes needed to write every customer's segment back, leaving out customers that
+// already have it. Every segment needs a customer group of the same name to write groups.
+func customerWrites(values map[string]*customerValue, data salesReportData, field int, groups bool) ([]customerWrite, error) {
+	groupIDs := map[string]string{}
+	if groups {
+		for id, name := range data.CustomerGroups {
+			groupIDs[normaliseName(name)] = id
+		}
+		var missing []string
+		for _, value := range values {
+			if _, ok := groupIDs[normaliseName(value.Segment)]; !ok && !containsString(missing, value.Segment) {
+				missing = append(missing, value.Segment)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			return nil, fmt.Errorf("create customer groups for these segments first: %s", strings.Join(missing, ", "))
+		}
+	}
+
+	var ids []string
+	for id := range values {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var writes []customerWrite
+	for _, id := range ids {
+		value := values[id]
+		if field != 0 {
+			fieldName := fmt.Sprintf("custom_field_%d", field)
+			original := customerCustomField(value.Customer, field)
+			if original != value.Segment {
+				writes = append(writes, customerWrite{ID: id, Field: fieldName, Value: value.Segment, Original: original})
+			}
+		}
+		if groups {
+			var original string
+			if value.Customer.GroupId != nil {
+				original = *value.Customer.GroupId
+			}
+			groupID := groupIDs[normaliseName(value.Segment)]
+			if original != groupID {
+				writes = append(writes, customerWrite{ID: id, Field: "customer_group_id", Value: groupID, Original: original})
+			}
+		}
+	}
+	return writes, nil
+}
+
+func customerCustomField(customer vend.Customer, field int) string {
+	values := []*string{customer.CustomField1, customer.CustomField2, customer.CustomField3, customer.CustomField4}
+	if values[field-1] == nil {
+		return ""
+	}
+	return *values[field-1]
+}
+
+// writeCustomerSegments confirms the changes, then snapshots each customer's original values before posting its changes
+// to Vend
+func writeCustomerSegments(writes []customerWrite, data salesReportData) {
+	var ids []string
+	for _, write := range writes {
+		if !containsString(ids, write.ID) {
+			ids = append(ids, write.ID)
+		}
+	}
+	confirmDestructiveAction(destructiveAction{
+		Command: "export-customer-value",
+		Entity:  "customers",
+		IDs:     ids,
+		Describe: func(id string) string {
+			customer := data.Customers[id]
+			name := customerFullName(customer)
+			if customer.Code != nil {
+				name += fmt.Sprintf(" (%s)", *customer.Code)
+			}
+			return name
+		},
+	})
+
+	// keep the values being replaced, so vendcli restore can put them back
+	originals := map[string]map[string]string{}
+	for _, write := range writes {
+		if originals[write.ID] == nil {
+			originals[write.ID] = map[string]string{}
+		}
+		originals[write.ID][write.Field] = write.Original
+	}
+	startSnapshots("export-customer-value", "customers")
+	defer stopSnapshots()
+
+	fmt.Println("\nWriting segments to Vend...")
+	p := pbar.CreateSingleBar()
+	bar, err := p.AddProgressBar(len(writes), "Writing Segments")
+	if err != nil {
+		fmt.Println("Error creating progress bar:", err)
+	}
+
+	var failed []FailedCustomerWrite
+	var count int
+	vc := vend.NewClient(Token, DomainPrefix, "")
+	url := fmt.Sprintf("https://%s.vendhq.com/api/customers", DomainPrefix)
+	snapshotted := map[string]error{}
+	for _, write := range writes {
+		bar.Increment()
+		snapshotErr, ok := snapshotted[write.ID]
+		if !ok {
+			snapshotErr = snapshotCustomer(write.ID, originals[write.ID])
+			snapshotted[write.ID] = snapshotErr
+		}
+		if snapshotErr != nil {
+			failed = append(failed, FailedCustomerWrite{CustomerID: write.ID, Field: write.Field, Value: write.Value, Reason: snapshotErr.Error()})
+			continue
+		}
+
+		_, err := vc.MakeRequest("POST", url, map[string]string{"id": write.ID, write.Field: write.Value})
+		if err != nil {
+			failed = append(failed, FailedCustomerWrite{
+				CustomerID: write.ID,
+				Field:      write.Field,
+				Value:      write.Value,
+				Reason:     err.Error(),
+			})
+			continue
+		}
+		count++
+	}
+	p.Wait()
+
+	var failureFile string
+	if len(failed) > 0 {
+		fmt.Println(color.RedString("\nThere were some errors. Writing failures to csv.."))
+		failureFile = fmt.Sprintf("%s_failed_customer_value_requests_%v.csv", DomainPrefix, time.Now().Unix())
+		if err := csvparser.WriteErrorCSV(failureFile, failed); err != nil {
+			err = fmt.Errorf("couldnt write failed customer updates to CSV file: %s", err)
+			messenger.ExitWithError(err)
+		}
+	}
+	finishRun(len(writes), count, failureFile)
+
+	fmt.Println(color.GreenString("\n\nFinished!🎉\nSuccessfully wrote %d of %d customer segments", count, len(writes)))
+}
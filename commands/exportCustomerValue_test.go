@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/govend/vend"
+)
+
+func TestCustomerValues(t *testing.T) {
+	newmarket, ponsonby := "o1", "o2"
+	annaID, benID, groupID := "c1", "c2", "g1"
+	anna, ben, oldField := "Anna", "Ben", "Lost"
+
+	// prices have 15% tax
+	sales := []vend.Sale{
+		testSale(saleFixture{Customer: annaID, Outlet: newmarket, Date: "2024-01-05T01:00:00Z", Lines: []lineFixture{{Quantity: 1, Price: 100, Tax: 15}}}),
+		testSale(saleFixture{Customer: annaID, Outlet: ponsonby, Date: "2024-03-20T01:00:00Z", Lines: []lineFixture{{Quantity: 1, Price: 40, Tax: 6}}}),
+		testSale(saleFixture{Customer: annaID, Outlet: ponsonby, Date: "2024-03-25T01:00:00Z", Lines: []lineFixture{{Quantity: 1, Price: 60, Tax: 9}}}),
+		// a return is not a visit
+		testSale(saleFixture{Customer: annaID, Outlet: ponsonby, Date: "2024-03-26T01:00:00Z", Lines: []lineFixture{{Quantity: -1, Price: 40, Tax: 6}}}),
+		testSale(saleFixture{Customer: benID, Outlet: newmarket, Date: "2023-06-01T01:00:00Z", Lines: []lineFixture{{Quantity: 1, Price: 20, Tax: 3}}}),
+		// walk-in
+		testSale(saleFixture{Outlet: newmarket, Date: "2024-03-25T01:00:00Z", Lines: []lineFixture{{Quantity: 1, Price: 20, Tax: 3}}}),
+	}
+	customers := []vend.Customer{
+		{ID: &annaID, FirstName: &anna, CustomField2: &oldField},
+		{ID: &benID, FirstName: &ben, CustomField2: &oldField},
+	}
+	data := newSalesReportData(map[string]string{newmarket: "Newmarket", ponsonby: "Ponsonby"}, nil, nil, customers,
+		map[string]string{groupID: "Champions"}, nil, nil)
+
+	values := buildCustomerValues(sales, data, "UTC")
+	assert.Len(t, values, 2)
+
+	annaValue := values[annaID]
+	assert.Equal(t, 3, annaValue.Visits)
+	assert.InDelta(t, 184, annaValue.Spend, 0.0001)
+	assert.Equal(t, "2024-01-05", annaValue.FirstPurchase.Format("2006-01-02"))
+	assert.Equal(t, "2024-03-25", annaValue.LastPurchase.Format("2006-01-02"))
+	assert.Equal(t, "Ponsonby", annaValue.preferredOutlet())
+
+	// scores are quintiles, so with two customers the best is in the third
+	scoreCustomerValues(values, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, []int{3, 3, 3}, []int{annaValue.Recency, annaValue.Frequency, annaValue.Monetary})
+	assert.Equal(t, "Loyal", annaValue.Segment)
+	assert.Equal(t, []int{1, 1, 1}, []int{values[benID].Recency, values[benID].Frequency, values[benID].Monetary})
+	assert.Equal(t, "Lost", values[benID].Segment)
+
+	// Ben already has his segment in the custom field
+	writes, err := customerWrites(values, data, 2, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []customerWrite{{ID: annaID, Field: "custom_field_2", Value: "Loyal", Original: "Lost"}}, writes)
+
+	_, err = customerWrites(values, data, 0, true)
+	assert.EqualError(t, err, "create customer groups for these segments first: Lost, Loyal")
+}
+
+func TestRFMSegment(t *testing.T) {
+	assert.Equal(t, "Champions", rfmSegment(5, 4, 4))
+	assert.Equal(t, "Loyal", rfmSegment(3, 3, 1))
+	assert.Equal(t, "New", rfmSegment(5, 1, 1))
+	assert.Equal(t, "Promising", rfmSegment(3, 2, 2))
+	assert.Equal(t, "Can't Lose", rfmSegment(1, 5, 5))
+	assert.Equal(t, "At Risk", rfmSegment(2, 2, 1))
+	assert.Equal(t, "Hibernating", rfmSegment(2, 1, 1))
+	assert.Equal(t, "Lost", rfmSegment(1, 1, 2))
+}
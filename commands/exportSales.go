@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"sort"
 	"strconv"
@@ -22,19 +24,40 @@ var (
 	timeZone string
 	dateFrom string
 	dateTo   string
-	outlet   string
+
+	salesFilters     salesFilterFlags
+	salesCombine     bool
+	salesColumnsFlag []string
+	salesProfile     string
 
 	exportSalesCmd = &cobra.Command{
 		Use:   "export-sales",
 		Short: "Export Sales",
 		Long: fmt.Sprintf(`
-Exports all the Sales from an account, you can pass a single outlet to the command or export all outlets:
+Exports all the Sales from an account, you can pass outlets to the command or export all outlets:
 Single Outlet: -o Newmarket
 Single Outlet, two words: -o 'Newmarket Outlet'
+Several Outlets: -o Newmarket,Ponsonby
 All Outlets: -o all
 
+Sales can also be filtered by register, user, customer group, product and status. Each filter takes a
+comma separated list or can be repeated. Names are matched ignoring case and small typos.
+Deleted sales are never exported, and OPEN sales are left out unless --Status asks for them.
+
+A file is written per outlet, or a single file with an Outlet column with --combine.
+Columns come from a profile (%s) and can be changed with --columns:
+Drop and add columns: --columns="-Customer Address1,+Brand,+Supplier"
+Only these columns:   --columns "Sale Date,Outlet,Product Sku,Quantity,Total"
+
+Columns: %s
+
 Example:
-%s`, color.GreenString("vendcli export-sales -d DOMAINPREFIX -t TOKEN -z TIMEZONE -F DATEFROM -T DATETO -o all")),
+%s
+%s
+%s`, strings.Join(salesProfileNames(), ", "), strings.Join(salesColumns, ", "),
+			color.GreenString("vendcli export-sales -d DOMAINPREFIX -t TOKEN -z TIMEZONE -F DATEFROM -T DATETO -o all"),
+			color.GreenString("vendcli export-sales -d DOMAINPREFIX -t TOKEN -z TIMEZONE -F DATEFROM -T DATETO -o Newmarket,Ponsonby --Status LAYBY,LAYBY_CLOSED"),
+			color.GreenString("vendcli export-sales -d DOMAINPREFIX -t TOKEN -z TIMEZONE -F DATEFROM -T DATETO --combine --profile lines")),
 
 		Run: func(cmd *cobra.Command, args []string) {
 			getAllSales()
@@ -44,13 +67,10 @@ Example:
 
 func init() {
 	// Flags
-	exportSalesCmd.Flags().StringVarP(&timeZone, "Timezone", "z", "", "Timezone of the store in zoneinfo format.")
-	exportSalesCmd.Flags().StringVarP(&dateFrom, "DateFrom", "F", "", "Date from (YYYY-MM-DD)")
-	exportSalesCmd.Flags().StringVarP(&dateTo, "DateTo", "T", "", "Date to (YYYY-MM-DD)")
-	exportSalesCmd.Flags().StringVarP(&outlet, "Outlet", "o", "", "Outlet to export the sales from")
-	exportSalesCmd.MarkFlagRequired("Timezone")
-	exportSalesCmd.MarkFlagRequired("DateFrom")
-	exportSalesCmd.MarkFlagRequired("DateTo")
+	addSalesRangeFlags(exportSalesCmd, &salesFilters)
+	exportSalesCmd.Flags().BoolVar(&salesCombine, "combine", false, "Write one file for all outlets, with an Outlet column")
+	exportSalesCmd.Flags().StringVar(&salesProfile, "profile", "default", fmt.Sprintf("Column profile: %s", strings.Join(salesProfileNames(), ", ")))
+	exportSalesCmd.Flags().StringSliceVar(&salesColumnsFlag, "columns", nil, "Columns to write, or +Column and -Column to change the profile")
 
 	rootCmd.AddCommand(exportSalesCmd)
 }
@@ -67,51 +87,64 @@ func getAllSales() {
 	// Validate provided timezone
 	validateTimeZone(dateTo+"T00:00:00Z", timeZone)
 
+	// Validate the columns before fetching anything
+	layout, err := newSalesLayout(salesProfile, salesColumnsFlag, salesCombine)
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+
 	// Filter the sales by date range and outlet
 	utcDateFrom, utcDateTo, versionAfter := prepareDateAndVersion(vc)
 
 	// Get all sales data
-	sales, registers, users, customers, customerGroupMap, products := getAllSalesData(versionAfter)
+	sales, lineTaxes, registers, users, customers, customerGroupMap, products, taxes := getAllSalesData(versionAfter)
 
 	// Get outlets and lookup outlet name by id
 	oidToOutletName := getOutletsAndOutletNameMap(vc)
 
-	// Check if the provided outlet exists
-	if outlet != "all" && !validOutlet(outlet, oidToOutletName) {
-		err := fmt.Errorf("'%s' outlet does not exist in the '%s' account", outlet, DomainPrefix)
+	// Check the outlets and other filters exist
+	filter, err := newSalesFilter(salesFilters, oidToOutletName, registers, users, customers, customerGroupMap, products)
+	if err != nil {
 		messenger.ExitWithError(err)
 	}
 
+	data := newSalesReportData(oidToOutletName, registers, users, customers, customerGroupMap, products, taxes)
+	data.LineTaxes = lineTaxes
+
 	// Process outlets
-	processOutlets(vc, oidToOutletName, sales, utcDateFrom, utcDateTo, registers, users, customers, customerGroupMap, products)
+	processOutlets(vc, oidToOutletName, filter, layout, data, sales, utcDateFrom, utcDateTo)
 
 	fmt.Println(color.GreenString("\n\nFinished!🎉\nSales Reports Created!"))
 }
 
-func getAllSalesData(versionAfter int64) ([]vend.Sale, []vend.Register, []vend.User, []vend.Customer, map[string]string, []vend.Product) {
+func getAllSalesData(versionAfter int64) ([]vend.Sale, map[string][]lineTaxComponent, []vend.Register, []vend.User, []vend.Customer, map[string]string, []vend.Product, map[string]vend.Taxes) {
 	// Pull data from Vend
 	fmt.Println("\nRetrieving data from Vend...")
-	routines := 6
+	routines := 7
 	p, err := pbar.CreateMultiBarGroup(routines, Token, DomainPrefix)
 	if err != nil {
 		fmt.Println("error creating progress bar group: ", err)
 	}
 
-	p.FetchSalesDataWithProgressBar(versionAfter)
+	p.PerformTaskWithProgressBar("sales", func(args ...interface{}) interface{} {
+		return fetchSales(*p.VendClient, versionAfter)
+	})
 	p.FetchDataWithProgressBar("registers")
 	p.FetchDataWithProgressBar("users")
 	p.FetchDataWithProgressBar("customers")
 	p.FetchDataWithProgressBar("customer-groups")
 	p.FetchDataWithProgressBar("products")
+	p.FetchDataWithProgressBar("taxes")
 
 	p.MultiBarGroupWait()
 
-	var sales []vend.Sale
+	var fetched fetchedSales
 	var registers []vend.Register
 	var users []vend.User
 	var customers []vend.Customer
 	customerGroupMap := make(map[string]string)
 	var products []vend.Product
+	taxes := make(map[string]vend.Taxes)
 
 	for err = range p.ErrorChannel {
 		err = fmt.Errorf("error fetching data: %v", err)
@@ -120,8 +153,8 @@ func getAllSalesData(versionAfter int64) ([]vend.Sale, []vend.Register, []vend.U
 
 	for data := range p.DataChannel {
 		switch d := data.(type) {
-		case []vend.Sale:
-			sales = d
+		case fetchedSales:
+			fetched = d
 		case []vend.Register:
 			registers = d
 		case []vend.User:
@@ -132,16 +165,85 @@ func getAllSalesData(versionAfter int64) ([]vend.Sale, []vend.Register, []vend.U
 			customerGroupMap = d
 		case []vend.Product:
 			products = d
+		case map[string]vend.Taxes:
+			taxes = d
 		}
 	}
 
-	return sales, registers, users, customers, customerGroupMap, products
+	if fetched.err != nil {
+		err = fmt.Errorf("error fetching data: %v", fetched.err)
+		messenger.ExitWithError(err)
+	}
+
+	return fetched.sales, fetched.lineTaxes, registers, users, customers, customerGroupMap, products, taxes
+}
+
+// lineTaxComponent is the tax of one rate on a sale line, for the whole line. govend's TaxComponent has no
+// JSON tags and a whole number amount, so the tax_components of sale lines are decoded into this instead.
+type lineTaxComponent struct {
+	RateID   string  `json:"rate_id"`
+	TotalTax float64 `json:"total_tax"`
+}
+
+// fetchedSales are the sales after a version with the tax components of their lines by line id
+type fetchedSales struct {
+	sales     []vend.Sale
+	lineTaxes map[string][]lineTaxComponent
+	err       error
 }
 
-func processOutlets(vc vend.Client, oidToOutletName map[string]string, sales []vend.Sale, utcDateFrom, utcDateTo string, registers []vend.Register, users []vend.User, customers []vend.Customer, customerGroupMap map[string]string, products []vend.Product) {
+// fetchSales pages through the sales after a version like govend's SalesAfter, also keeping the tax components
+// of every line
+func fetchSales(vc vend.Client, versionAfter int64) fetchedSales {
+	fetched := fetchedSales{lineTaxes: map[string][]lineTaxComponent{}}
+	version := versionAfter
+	for {
+		data, next, err := vc.ResourcePage(version, "GET", "sales")
+		if err != nil {
+			fetched.err = err
+			return fetched
+		}
+		page := []vend.Sale{}
+		if err = json.Unmarshal(data, &page); err != nil {
+			fetched.err = fmt.Errorf("error while unmarshalling: %s", err)
+			return fetched
+		}
+		if len(page) == 0 {
+			return fetched
+		}
+
+		var taxPage []struct {
+			LineItems []struct {
+				ID            string             `json:"id"`
+				TaxComponents []lineTaxComponent `json:"tax_components"`
+			} `json:"line_items"`
+		}
+		if err = json.Unmarshal(data, &taxPage); err != nil {
+			fetched.err = fmt.Errorf("error while unmarshalling tax components: %s", err)
+			return fetched
+		}
+		for _, sale := range taxPage {
+			for _, lineitem := range sale.LineItems {
+				if lineitem.ID != "" && len(lineitem.TaxComponents) > 0 {
+					fetched.lineTaxes[lineitem.ID] = lineitem.TaxComponents
+				}
+			}
+		}
+
+		fetched.sales = append(fetched.sales, page...)
+		version = next
+	}
+}
+
+func processOutlets(vc vend.Client, oidToOutletName map[string]string, filter salesFilter, layout salesLayout, data salesReportData, sales []vend.Sale, utcDateFrom, utcDateTo string) {
 
-	allOutletsName := getAllOutletsToProcess(oidToOutletName)
-	filteredSalesMap := getFilteredSales(sales, utcDateFrom, utcDateTo, oidToOutletName)
+	allOutletsName := getAllOutletsToProcess(oidToOutletName, filter)
+	filteredSalesMap := getFilteredSales(sales, utcDateFrom, utcDateTo, oidToOutletName, filter)
+
+	if salesCombine {
+		processCombined(vc, allOutletsName, filteredSalesMap, layout, data)
+		return
+	}
 
 	fmt.Println("\nWriting CSVs...")
 	var skippedOutlets []string
@@ -162,7 +264,7 @@ func processOutlets(vc vend.Client, oidToOutletName map[string]string, sales []v
 				if err != nil {
 					fmt.Println(err)
 				}
-				processOutlet(vc, bar, outlet, filteredSales, registers, users, customers, customerGroupMap, products)
+				processOutlet(vc, bar, outlet, filteredSales, layout, data)
 			} else {
 				skippedOutlets = append(skippedOutlets, outlet)
 			}
@@ -178,20 +280,42 @@ func processOutlets(vc vend.Client, oidToOutletName map[string]string, sales []v
 
 }
 
-func getAllOutletsToProcess(oidToOutletName map[string]string) []string {
+func getAllOutletsToProcess(oidToOutletName map[string]string, filter salesFilter) []string {
+	if len(filter.outlets) == 0 {
+		return getAllOutletNames(oidToOutletName)
+	}
+
 	var allOutletsName []string
+	for oid := range filter.outlets {
+		allOutletsName = append(allOutletsName, oidToOutletName[oid])
+	}
+	sort.Strings(allOutletsName)
 
-	// add the provided outlet by default
-	allOutletsName = append(allOutletsName, outlet)
+	return allOutletsName
+}
 
-	if outlet == "all" {
-		allOutletsName = getAllOutletNames(oidToOutletName)
+// processCombined writes the sales of every outlet to one report
+func processCombined(vc vend.Client, allOutletsName []string, filteredSalesMap map[string][]vend.Sale, layout salesLayout, data salesReportData) {
+	var combinedSales []vend.Sale
+	for _, outlet := range allOutletsName {
+		combinedSales = append(combinedSales, filteredSalesMap[outlet]...)
+	}
+	if len(combinedSales) == 0 {
+		fmt.Printf("\n%s\n", color.YellowString("There were no sales in the date range"))
+		return
 	}
 
-	return allOutletsName
+	fmt.Println("\nWriting CSV...")
+	p := pbar.CreateSingleBar()
+	bar, err := p.AddProgressBar(len(combinedSales), "All outlets")
+	if err != nil {
+		fmt.Println(err)
+	}
+	processOutlet(vc, bar, "combined", combinedSales, layout, data)
+	p.Wait()
 }
 
-func processOutlet(vc vend.Client, bar *pbar.CustomBar, outlet string, filteredSales []vend.Sale, registers []vend.Register, users []vend.User, customers []vend.Customer, customerGroupMap map[string]string, products []vend.Product) {
+func processOutlet(vc vend.Client, bar *pbar.CustomBar, outlet string, filteredSales []vend.Sale, layout salesLayout, data salesReportData) {
 
 	sortBySaleDate(filteredSales)
 
@@ -202,9 +326,9 @@ func processOutlet(vc vend.Client, bar *pbar.CustomBar, outlet string, filteredS
 	}
 	defer file.Close()
 
-	file = addSalesReportHeader(file)
+	file = addSalesReportHeader(file, layout)
 
-	writeSalesReport(file, bar, registers, users, customers, customerGroupMap, products, filteredSales, vc.TimeZone)
+	writeSalesReport(file, bar, layout, data, filteredSales, vc.TimeZone)
 
 }
 
@@ -279,21 +403,9 @@ func sortBySaleDate(sales []vend.Sale) {
 	})
 }
 
-// validOutlet checks if outlet name exists in store
-func validOutlet(outletName string, oidToName map[string]string) bool {
-	for oid := range oidToName {
-		currName := oidToName[oid]
-
-		if strings.EqualFold(currName, outletName) {
-			return true
-		}
-	}
-	return false
-}
-
-// getFilteredSales filters sales by date range and status and sorts them by outlet
+// getFilteredSales filters sales by date range and the sales filter and sorts them by outlet
 func getFilteredSales(sales []vend.Sale, utcdatefrom string, utcdateto string,
-	oidToOutletName map[string]string) map[string][]vend.Sale {
+	oidToOutletName map[string]string, filter salesFilter) map[string][]vend.Sale {
 
 	fmt.Println("\nFiltering sales by outlet and date range...")
 	filteredSalesMap := make(map[string][]vend.Sale)
@@ -310,12 +422,8 @@ func getFilteredSales(sales []vend.Sale, utcdatefrom string, utcdateto string,
 		//outletName := oidToOutlet[outletId][0] // seems like the .Oultets returns a map outletid : []Outlet?
 		outletName := oidToOutletName[outletId]
 
-		// Do not include deleted sales in reports.
-		if sale.DeletedAt != nil {
-			continue
-		}
-		// Do not include sales with status of "OPEN"
-		if sale.Status != nil && *sale.Status == "OPEN" {
+		// Do not include deleted sales, or sales the filters leave out
+		if !filter.matches(sale) {
 			continue
 		}
 
@@ -390,54 +498,21 @@ func createReport(domainPrefix string, outlet string) (*os.File, error) {
 	return file, err
 }
 
-func addSalesReportHeader(file *os.File) *os.File {
+func addSalesReportHeader(file *os.File, layout salesLayout) *os.File {
 	// Start CSV writer.
 	writer := csv.NewWriter(file)
 
-	// Set header values.
-	var headerLine []string
-	headerLine = append(headerLine, "Sale UUID")          // 0
-	headerLine = append(headerLine, "Sale Date")          // 1
-	headerLine = append(headerLine, "Sale Time")          // 2
-	headerLine = append(headerLine, "Invoice Number")     // 3
-	headerLine = append(headerLine, "Line Type")          // 4
-	headerLine = append(headerLine, "Customer Code")      // 5
-	headerLine = append(headerLine, "Customer Name")      // 6
-	headerLine = append(headerLine, "Customer Email")     // 7
-	headerLine = append(headerLine, "Customer Group")     // 8
-	headerLine = append(headerLine, "Customer Address1")  // 9
-	headerLine = append(headerLine, "Customer Address2")  // 10
-	headerLine = append(headerLine, "Customer City")      // 11
-	headerLine = append(headerLine, "Customer State")     // 12
-	headerLine = append(headerLine, "Customer Postcode")  // 13
-	headerLine = append(headerLine, "Customer CountryID") // 14
-	headerLine = append(headerLine, "Do not email")       // 15
-	headerLine = append(headerLine, "Sale Note")          // 16
-	headerLine = append(headerLine, "Quantity")           // 17
-	headerLine = append(headerLine, "Cost")               // 18
-	headerLine = append(headerLine, "Price")              // 19
-	headerLine = append(headerLine, "Tax")                // 20
-	headerLine = append(headerLine, "Discount")           // 21
-	headerLine = append(headerLine, "Loyalty")            // 22
-	headerLine = append(headerLine, "Total")              // 23
-	headerLine = append(headerLine, "Paid")               // 24
-	headerLine = append(headerLine, "Details")            // 25
-	headerLine = append(headerLine, "Register")           // 26
-	headerLine = append(headerLine, "User")               // 27
-	headerLine = append(headerLine, "Status")             // 28
-	headerLine = append(headerLine, "Product Sku")        // 29
-
 	// Write headerline to file.
-	writer.Write(headerLine)
+	writer.Write(layout.Columns)
 	writer.Flush()
 
 	return file
 }
 
-// writeReport aims to mimic the report generated by exporting Vend sales history
-func writeSalesReport(file *os.File, bar *pbar.CustomBar, registers []vend.Register, users []vend.User,
-	customers []vend.Customer, customerGroupMap map[string]string, products []vend.Product, sales []vend.Sale,
-	timeZone string) *os.File {
+// writeReport aims to mimic the report generated by exporting Vend sales history. Every row has every column,
+// the layout picks the ones written.
+func writeSalesReport(file *os.File, bar *pbar.CustomBar, layout salesLayout, data salesReportData,
+	sales []vend.Sale, timeZone string) *os.File {
 
 	// Create CSV writer.
 	writer := csv.NewWriter(file)
@@ -470,60 +545,59 @@ func writeSalesReport(file *os.File, bar *pbar.CustomBar, registers []vend.Regis
 			invoiceNumber = *sale.InvoiceNumber
 		}
 
+		var outletName string
+		if sale.OutletID != nil {
+			outletName = data.Outlets[*sale.OutletID]
+		}
+
 		// Customer
-		var customerName, customerFirstName, customerLastName,
-			customerCode, customerEmail, doNotEmail string
+		var customerName, customerCode, customerEmail, doNotEmail string
 		var customerFullName []string
 
 		// extra customer info field based on feature request
 		var customerPostalAddress1, customerPostalAddress2, customerPostalCity,
 			customerPostalState, customerPostalPostcode, customerPostalCountryID, customerGroup string
-		for _, customer := range customers {
+		if sale.CustomerID != nil {
 			// Make sure we only use info from customer on our sale.
-			if customer.ID != nil && sale.CustomerID != nil {
-				if *customer.ID == *sale.CustomerID {
-					if customer.FirstName != nil {
-						customerFirstName = *customer.FirstName
-						customerFullName = append(customerFullName, customerFirstName)
-					}
-					if customer.LastName != nil {
-						customerLastName = *customer.LastName
-						customerFullName = append(customerFullName, customerLastName)
-					}
-					if customer.Code != nil {
-						customerCode = *customer.Code
-					}
-					if customer.Email != nil {
-						customerEmail = *customer.Email
-					}
-					if customer.GroupId != nil {
-						customerGroup = customerGroupMap[*customer.GroupId]
-					}
-					if customer.DoNotEmail != nil {
-						doNotEmail = fmt.Sprint(*customer.DoNotEmail)
-					}
-					if customer.PostalAddress1 != nil {
-						customerPostalAddress1 = *customer.PostalAddress1
-					}
-					if customer.PostalAddress2 != nil {
-						customerPostalAddress2 = *customer.PostalAddress2
-					}
-					if customer.PostalCity != nil {
-						customerPostalCity = *customer.PostalCity
-					}
-					if customer.PostalState != nil {
-						customerPostalState = *customer.PostalState
-					}
-					if customer.PostalPostcode != nil {
-						customerPostalPostcode = *customer.PostalPostcode
-					}
-					if customer.PostalCountryID != nil {
-						customerPostalCountryID = *customer.PostalCountryID
-					}
-
-					customerName = strings.Join(customerFullName, " ")
-					break
+			if customer, ok := data.Customers[*sale.CustomerID]; ok {
+				if customer.FirstName != nil {
+					customerFullName = append(customerFullName, *customer.FirstName)
+				}
+				if customer.LastName != nil {
+					customerFullName = append(customerFullName, *customer.LastName)
+				}
+				if customer.Code != nil {
+					customerCode = *customer.Code
+				}
+				if customer.Email != nil {
+					customerEmail = *customer.Email
+				}
+				if customer.GroupId != nil {
+					customerGroup = data.CustomerGroups[*customer.GroupId]
+				}
+				if customer.DoNotEmail != nil {
+					doNotEmail = fmt.Sprint(*customer.DoNotEmail)
+				}
+				if customer.PostalAddress1 != nil {
+					customerPostalAddress1 = *customer.PostalAddress1
 				}
+				if customer.PostalAddress2 != nil {
+					customerPostalAddress2 = *customer.PostalAddress2
+				}
+				if customer.PostalCity != nil {
+					customerPostalCity = *customer.PostalCity
+				}
+				if customer.PostalState != nil {
+					customerPostalState = *customer.PostalState
+				}
+				if customer.PostalPostcode != nil {
+					customerPostalPostcode = *customer.PostalPostcode
+				}
+				if customer.PostalCountryID != nil {
+					customerPostalCountryID = *customer.PostalCountryID
+				}
+
+				customerName = strings.Join(customerFullName, " ")
 			}
 		}
 
@@ -536,30 +610,32 @@ func writeSalesReport(file *os.File, bar *pbar.CustomBar, registers []vend.Regis
 		// Add up the total quantities of each product line item.
 		var totalQuantity, totalDiscount, totalTransactionCost float64
 		var saleItems []string
+		var saleTaxComponents []taxComponent
 		for _, lineitem := range *sale.LineItems {
 			if lineitem.Quantity != nil && lineitem.DiscountTotal != nil {
 				totalQuantity += *lineitem.Quantity
 			}
 
+			totalDiscount += lineDiscountTotal(lineitem)
+			saleTaxComponents = addTaxComponents(saleTaxComponents, lineTaxComponents(lineitem, data))
+
 			if lineitem.TotalCost != nil {
 				totalTransactionCost += *lineitem.TotalCost
 			}
 
-			for _, product := range products {
-				if *product.ID == *lineitem.ProductID {
-					var productItems []string
-					productItems = append(productItems, fmt.Sprintf("%v", *lineitem.Quantity))
-					productItems = append(productItems, *product.Name)
+			if product, ok := data.Products[*lineitem.ProductID]; ok {
+				var productItems []string
+				productItems = append(productItems, fmt.Sprintf("%v", *lineitem.Quantity))
+				productItems = append(productItems, *product.Name)
 
-					prodItem := strings.Join(productItems, " X ")
-					saleItems = append(saleItems, fmt.Sprintf("%v", prodItem))
-					break
-				}
+				prodItem := strings.Join(productItems, " X ")
+				saleItems = append(saleItems, fmt.Sprintf("%v", prodItem))
 			}
 		}
 		totalQuantityStr := strconv.FormatFloat(totalQuantity, 'f', -1, 64)
 		totalDiscountStr := strconv.FormatFloat(totalDiscount, 'f', -1, 64)
 		totalTransactionCostStr := strconv.FormatFloat(totalTransactionCost, 'f', 2, 64)
+		saleTaxComponentsStr := formatTaxComponents(saleTaxComponents)
 		// Show items sold separated by + sign.
 		saleDetails := strings.Join(saleItems, " + ")
 
@@ -585,77 +661,61 @@ func writeSalesReport(file *os.File, bar *pbar.CustomBar, registers []vend.Regis
 			totalLoyaltyStr = strconv.FormatFloat(*sale.TotalLoyalty, 'f', -1, 64)
 		}
 
-		var registerName string
-		for _, register := range registers {
-			if *sale.RegisterID == *register.ID {
-				registerName = *register.Name
-				// Append (Deleted) to name if register is deleted.
-				if register.DeletedAt != nil {
-					registerName += " (Deleted)"
-				}
-				break
-			} else {
-				// Should no longer reach this point as registers endpoint now returns
-				// deleted registers. But if for whatever reason we do, write <deleted register>.
-				registerName = "<Deleted Register>"
-			}
-		}
-
-		var userName string
-		for _, user := range users {
-			if sale.UserID != nil && *sale.UserID == *user.ID {
-				if user.DisplayName != nil {
-					userName = *user.DisplayName
-				} else if user.Username != nil {
-					userName = *user.Username
-				} else {
-					userName = ""
-				}
-				break
-			} else {
-				userName = ""
-			}
-		}
+		registerName := data.registerName(sale.RegisterID)
+		userName := data.userName(sale.UserID)
 
 		var saleStatus string
 		if sale.Status != nil {
 			saleStatus = *sale.Status
 		}
 
+		var returnFor string
+		if sale.ReturnFor != nil {
+			returnFor = *sale.ReturnFor
+		}
+
+		// Every row of a sale says which sale it is part of.
+		saleIdentity := salesRow{
+			"Sale UUID":      saleID,
+			"Sale Date":      dateStr,
+			"Sale Time":      timeStr,
+			"Invoice Number": invoiceNumber,
+			"Outlet":         outletName,
+			"Return For":     returnFor,
+		}
+
 		// Write first sale line to file.
-		var record []string
-		record = append(record, saleID)                  // 0. Sale UUID
-		record = append(record, dateStr)                 // 1. Date
-		record = append(record, timeStr)                 // 2. Time
-		record = append(record, invoiceNumber)           // 3. Receipt Number
-		record = append(record, "Sale")                  // 4. Line Type
-		record = append(record, customerCode)            // 5. Customer Code
-		record = append(record, customerName)            // 6. Customer Name
-		record = append(record, customerEmail)           // 7. Customer Email
-		record = append(record, customerGroup)           // 8. Customer Group
-		record = append(record, customerPostalAddress1)  // 9. Customer PostalAddress 1
-		record = append(record, customerPostalAddress2)  // 10. Customer PostalAddress 2
-		record = append(record, customerPostalCity)      // 11. Customer PostalCity
-		record = append(record, customerPostalState)     // 12. Customer Postal State
-		record = append(record, customerPostalPostcode)  // 13. Customer Postal PostCode
-		record = append(record, customerPostalCountryID) // 14. Customer Postal Country ID
-		record = append(record, doNotEmail)              // 15. Marketing Opt in/out
-		record = append(record, saleNote)                // 16. Note
-		record = append(record, totalQuantityStr)        // 17. Quantity
-		record = append(record, totalTransactionCostStr) // 18. Transaction Cost
-		record = append(record, totalPrice)              // 19. Subtotal
-		record = append(record, totalTax)                // 20. Sales Tax
-		record = append(record, totalDiscountStr)        // 21. Discount
-		record = append(record, totalLoyaltyStr)         // 22. Loyalty
-		record = append(record, total)                   // 23. Sale total
-		record = append(record, "")                      // 24. Paid
-		record = append(record, saleDetails)             // 25. Details
-		record = append(record, registerName)            // 26. Register
-		record = append(record, userName)                // 27. User
-		record = append(record, saleStatus)              // 28. Status
-		record = append(record, "")                      // 29. Sku
-
-		writer.Write(record)
+		saleRecord := saleIdentity.with(salesRow{
+			"Line Type":          "Sale",
+			"Customer Code":      customerCode,
+			"Customer Name":      customerName,
+			"Customer Email":     customerEmail,
+			"Customer Group":     customerGroup,
+			"Customer Address1":  customerPostalAddress1,
+			"Customer Address2":  customerPostalAddress2,
+			"Customer City":      customerPostalCity,
+			"Customer State":     customerPostalState,
+			"Customer Postcode":  customerPostalPostcode,
+			"Customer CountryID": customerPostalCountryID,
+			"Do not email":       doNotEmail,
+			"Sale Note":          saleNote,
+			"Quantity":           totalQuantityStr,
+			"Cost":               totalTransactionCostStr,
+			"Price":              totalPrice,
+			"Tax":                totalTax,
+			"Discount":           totalDiscountStr,
+			"Loyalty":            totalLoyaltyStr,
+			"Total":              total,
+			"Details":            saleDetails,
+			"Register":           registerName,
+			"User":               userName,
+			"Status":             saleStatus,
+			"Tax Components":     saleTaxComponentsStr,
+		})
+
+		if !layout.LinesOnly {
+			writer.Write(layout.record(saleRecord))
+		}
 
 		for _, lineitem := range *sale.LineItems {
 			var quantity, unitCost, price, tax, discount, loyalty, total string
@@ -681,48 +741,78 @@ func writeSalesReport(file *os.File, bar *pbar.CustomBar, registers []vend.Regis
 				total = strconv.FormatFloat(((*lineitem.Price + *lineitem.Tax) * *lineitem.Quantity), 'f', -1, 64)
 			}
 
-			var productName, productSKU string
-			for _, product := range products {
-				if *product.ID == *lineitem.ProductID {
+			var taxName string
+			if lineitem.TaxID != nil {
+				if lineTax, ok := data.Taxes[*lineitem.TaxID]; ok && lineTax.Name != nil {
+					taxName = *lineTax.Name
+				}
+			}
+
+			lineTaxStr := formatTaxComponents(lineTaxComponents(lineitem, data))
+
+			var isReturn, lineStatus string
+			if lineitem.IsReturn != nil {
+				isReturn = fmt.Sprint(*lineitem.IsReturn)
+			}
+			if lineitem.Status != nil {
+				lineStatus = *lineitem.Status
+			}
+
+			var productID, productName, productSKU, productHandle, brand, supplier, productType string
+			if lineitem.ProductID != nil {
+				productID = *lineitem.ProductID
+			}
+			if product, ok := data.Products[productID]; ok {
+				if product.VariantName != nil {
 					productName = *product.VariantName
+				}
+				if product.SKU != nil {
 					productSKU = *product.SKU
 				}
+				if product.Handle != nil {
+					productHandle = *product.Handle
+				}
+				if product.Brand.Name != nil {
+					brand = *product.Brand.Name
+				}
+				if product.Type.Name != nil {
+					productType = *product.Type.Name
+				}
+				supplier = productSuppliers(product)
 			}
 
 			// Write product records for given sale to file.
-			productRecord := record
-			productRecord[0] = saleID        // 0. Sale UUID
-			productRecord[1] = dateStr       // 1. Sale Date
-			productRecord[2] = timeStr       // 2. Sale Time
-			productRecord[3] = invoiceNumber // 3. Invoice Number
-			productRecord[4] = "Sale Line"   // 4. Line Type
-			productRecord[5] = ""            // 5. Customer Code
-			productRecord[6] = ""            // 6. Customer Name
-			productRecord[7] = ""            // 7. Customer Email
-			productRecord[8] = ""            // 8. Customer Group
-			productRecord[9] = ""            // 9. Customer Postal Address 1
-			productRecord[10] = ""           // 10. Customer Postal Address 2
-			productRecord[11] = ""           // 11. Customer Postal City
-			productRecord[12] = ""           // 12. Customer Postal State
-			productRecord[13] = ""           // 13. Customer Postal PostCode
-			productRecord[14] = ""           // 14. Customer Postal Country ID
-			productRecord[15] = ""           // 15. TODO: line note from the product?
-			productRecord[16] = ""           // 16. Marketing Opt in/out
-			productRecord[17] = quantity     // 17. Quantity
-			productRecord[18] = unitCost     // 18. Unit Cost
-			productRecord[19] = price        // 19. Subtotal
-			productRecord[20] = tax          // 20. Sales Tax
-			productRecord[21] = discount     // 21. Discount
-			productRecord[22] = loyalty      // 22. Loyalty
-			productRecord[23] = total        // 23. Total
-			productRecord[24] = ""           // 24. Paid
-			productRecord[25] = productName  // 25. Details
-			productRecord[26] = ""           // 26. Register
-			productRecord[27] = ""           // 27. User
-			productRecord[28] = ""           // 28. Status
-			productRecord[29] = productSKU   // 29. Sku
-
-			writer.Write(productRecord)
+			productRecord := saleIdentity.with(salesRow{
+				"Line Type":      "Sale Line",
+				"Quantity":       quantity,
+				"Cost":           unitCost,
+				"Price":          price,
+				"Tax":            tax,
+				"Discount":       discount,
+				"Loyalty":        loyalty,
+				"Total":          total,
+				"Details":        productName,
+				"Product Sku":    productSKU,
+				"Tax Name":       taxName,
+				"Tax Components": lineTaxStr,
+				"Is Return":      isReturn,
+				"Line Status":    lineStatus,
+				"Product ID":     productID,
+				"Product Handle": productHandle,
+				"Brand":          brand,
+				"Supplier":       supplier,
+				"Product Type":   productType,
+			})
+			if layout.LinesOnly {
+				// a flat table repeats the sale's customer, register, user and status on every line
+				productRecord = saleRecord.with(productRecord)
+			}
+
+			writer.Write(layout.record(productRecord))
+		}
+
+		if layout.LinesOnly {
+			continue
 		}
 
 		payments := *sale.Payments
@@ -731,41 +821,131 @@ func writeSalesReport(file *os.File, bar *pbar.CustomBar, registers []vend.Regis
 			paid := strconv.FormatFloat(*payment.Amount, 'f', -1, 64)
 			name := *payment.Name
 
-			paymentRecord := record
-			paymentRecord[0] = saleID        // 0. Sale UUID
-			paymentRecord[1] = dateStr       // 1. Sale Date
-			paymentRecord[2] = timeStr       // 2. Sale Time
-			paymentRecord[3] = invoiceNumber // 3. Invoice Number
-			paymentRecord[4] = "Payment"     // 4. Line Type
-			paymentRecord[5] = ""            // 5. Customer Code
-			paymentRecord[6] = ""            // 6. Customer Name
-			paymentRecord[7] = ""            // 7. Customer Email
-			paymentRecord[8] = ""            // 8. Customer Group
-			paymentRecord[9] = ""            // 9. Customer Postal Address 1
-			paymentRecord[10] = ""           // 10. Customer Postal Address 2
-			paymentRecord[11] = ""           // 11. Customer Postal City
-			paymentRecord[12] = ""           // 12. Customer Postal State
-			paymentRecord[13] = ""           // 13. Customer Postal PostalCode
-			paymentRecord[14] = ""           // 14. Customer Postal Country ID
-			paymentRecord[15] = ""           // 15. Marketing Opt in/out
-			paymentRecord[16] = ""           // 16. TODO: line note
-			paymentRecord[17] = ""           // 17. Quantity
-			paymentRecord[18] = ""           // 18. Cost
-			paymentRecord[19] = ""           // 19. Subtotal
-			paymentRecord[20] = ""           // 20. Sales Tax
-			paymentRecord[21] = ""           // 21. Discount
-			paymentRecord[22] = ""           // 22. Loyalty
-			paymentRecord[23] = ""           // 23. Total
-			paymentRecord[24] = paid         // 24. Paid
-			paymentRecord[25] = name         // 25. Details
-			paymentRecord[26] = ""           // 26. Register
-			paymentRecord[27] = ""           // 27. User
-			paymentRecord[28] = ""           // 28. Status
-			paymentRecord[29] = ""           // 29. Sku
-
-			writer.Write(paymentRecord)
+			paymentRecord := saleIdentity.with(salesRow{
+				"Line Type": "Payment",
+				"Paid":      paid,
+				"Details":   name,
+			})
+
+			writer.Write(layout.record(paymentRecord))
 		}
 	}
 	writer.Flush()
 	return file
 }
+
+// taxComponent is the part of a line's tax collected for one rate of its tax
+type taxComponent struct {
+	Name   string
+	Amount float64
+}
+
+// lineDiscountTotal is the discount given on the whole line. The 2.0 API gives the discount per unit.
+func lineDiscountTotal(lineitem vend.LineItem) float64 {
+	if lineitem.TotalDiscount != nil {
+		return *lineitem.TotalDiscount
+	}
+	if lineitem.Discount != nil && lineitem.Quantity != nil {
+		return *lineitem.Discount * *lineitem.Quantity
+	}
+	return 0
+}
+
+// lineCostTotal is the cost of the whole line, worked out from the unit cost when the total is missing
+func lineCostTotal(lineitem vend.LineItem) float64 {
+	if lineitem.TotalCost != nil {
+		return *lineitem.TotalCost
+	}
+	if lineitem.UnitCost != nil && lineitem.Quantity != nil {
+		return *lineitem.UnitCost * *lineitem.Quantity
+	}
+	return 0
+}
+
+// lineTaxComponents is the tax of a line by rate, from the tax components Vend recorded on the line. Lines without
+// components have their tax split across the rates of the line's tax in proportion to each rate.
+func lineTaxComponents(lineitem vend.LineItem, data salesReportData) []taxComponent {
+	if lineitem.ID != nil {
+		if recorded, ok := data.LineTaxes[*lineitem.ID]; ok {
+			var components []taxComponent
+			for _, component := range recorded {
+				components = append(components, taxComponent{Name: data.taxRateName(component.RateID), Amount: roundAmount(component.TotalTax)})
+			}
+			return components
+		}
+	}
+	return estimateTaxComponents(lineitem, data.Taxes)
+}
+
+// estimateTaxComponents splits the tax of the whole line across the rates of the line's tax, in proportion to each
+// rate. The last rate takes what is left after rounding, so the components add up to the line's tax.
+func estimateTaxComponents(lineitem vend.LineItem, taxes map[string]vend.Taxes) []taxComponent {
+	if lineitem.TaxID == nil || lineitem.Tax == nil || lineitem.Quantity == nil {
+		return nil
+	}
+	tax, ok := taxes[*lineitem.TaxID]
+	if !ok || len(tax.TaxRates) == 0 {
+		return nil
+	}
+
+	lineTax := roundAmount(*lineitem.Tax * *lineitem.Quantity)
+	var rateTotal float64
+	for _, rate := range tax.TaxRates {
+		if rate.Rate != nil {
+			rateTotal += *rate.Rate
+		}
+	}
+
+	var components []taxComponent
+	remaining := lineTax
+	for i, rate := range tax.TaxRates {
+		var name string
+		if rate.Name != nil {
+			name = *rate.Name
+		}
+		var amount float64
+		switch {
+		case i == len(tax.TaxRates)-1:
+			amount = remaining
+		case rateTotal != 0 && rate.Rate != nil:
+			amount = roundAmount(lineTax * *rate.Rate / rateTotal)
+		case rateTotal == 0 && i == 0:
+			amount = lineTax
+		}
+		remaining = roundAmount(remaining - amount)
+		components = append(components, taxComponent{Name: name, Amount: amount})
+	}
+	return components
+}
+
+// addTaxComponents adds the amounts of components to totals by rate name, keeping the order rates were first seen in
+func addTaxComponents(totals []taxComponent, components []taxComponent) []taxComponent {
+	for _, component := range components {
+		found := false
+		for i := range totals {
+			if totals[i].Name == component.Name {
+				totals[i].Amount = roundAmount(totals[i].Amount + component.Amount)
+				found = true
+				break
+			}
+		}
+		if !found {
+			totals = append(totals, component)
+		}
+	}
+	return totals
+}
+
+// formatTaxComponents writes components as "GST: 1.5; PST: 0.75"
+func formatTaxComponents(components []taxComponent) string {
+	var parts []string
+	for _, component := range components {
+		parts = append(parts, fmt.Sprintf("%s: %s", component.Name, strconv.FormatFloat(component.Amount, 'f', -1, 64)))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// roundAmount rounds to the 5 decimal places Vend keeps amounts to
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100000) / 100000
+}
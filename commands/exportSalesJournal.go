@@ -0,0 +1,510 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vend/vend-cli/pkg/csvparser"
+	"github.com/vend/vend-cli/pkg/messenger"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vend/govend/vend"
+)
+
+// Command config
+var (
+	journalFilters    salesFilterFlags
+	journalPeriod     string
+	journalFormat     string
+	journalDateFormat string
+	journalPaymentMap string
+	journalTaxMap     string
+	journalNoCost     bool
+	journalXeroTax    string
+	journalAccounts   journalAccountFlags
+	journalChained    bool
+	journalSigningKey string
+
+	exportSalesJournalCmd = &cobra.Command{
+		Use:   "export-sales-journal",
+		Short: "Export Sales as Accounting Journals",
+		Long: fmt.Sprintf(`
+Exports sales as double-entry journals, one per day and outlet or one per sale, ready to import into
+an accounting system. Each journal credits revenue by the product's sales account code and tax by rate,
+debits payments by payment type, and moves the cost of the goods sold out of inventory. What is left unpaid
+on layby and account sales goes to the receivable account, and rounding of a few cents to the rounding
+account. Any other difference is posted to the suspense account and listed when the export finishes.
+
+Payment types are posted to accounts named after them, and tax to --tax-account, unless a mapping file
+gives their account:
+  --payment-map payments.csv   with columns payment_type,account_code
+  --tax-map taxes.csv          with columns tax_rate,account_code
+
+Formats: generic, xero (manual journal import) and quickbooks (journal entry import).
+Payments are posted on the day they were paid. Layby and account payments made after the day of the sale
+are taken off the receivable account.
+
+With --chained it writes a tamper-evident journal instead: every sale, in invoice sequence order per register, as
+a line of JSON holding the SHA-256 of the sale and of the line before it, with a manifest signed by an ed25519 key
+(--signing-key, required, and created with its public key when the file does not exist). Check it later with
+verify-journal and the public key.
+
+Example:
+%s`, color.GreenString("vendcli export-sales-journal -d DOMAINPREFIX -t TOKEN -z TIMEZONE -F DATEFROM -T DATETO --format xero --payment-map payments.csv")),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			exportSalesJournal()
+		},
+	}
+)
+
+// journalAccountFlags are the accounts lines are posted to when the store or a mapping file has no account for them
+type journalAccountFlags struct {
+	Revenue    string
+	Tax        string
+	Cost       string
+	Inventory  string
+	Receivable string
+	Rounding   string
+	Suspense   string
+}
+
+// journalMappingSchema is the layout of the payment type and tax rate mapping files
+func journalMappingSchema(key string, aliases ...string) csvparser.Schema {
+	return csvparser.Schema{Columns: []csvparser.Column{
+		{Name: key, Aliases: aliases, Required: true},
+		{Name: "account_code", Aliases: []string{"account", "code", "account_name"}, Required: true},
+	}}
+}
+
+func init() {
+	// Flags
+	addSalesRangeFlags(exportSalesJournalCmd, &journalFilters)
+	exportSalesJournalCmd.Flags().StringVar(&journalPeriod, "period", "day", "One journal per: day (and outlet), sale")
+	exportSalesJournalCmd.Flags().StringVar(&journalFormat, "format", "generic", "Output layout: generic, xero, quickbooks")
+	exportSalesJournalCmd.Flags().StringVar(&journalDateFormat, "date-format", "YYYY-MM-DD", "Date format: YYYY-MM-DD, DD/MM/YYYY, MM/DD/YYYY")
+	exportSalesJournalCmd.Flags().StringVar(&journalPaymentMap, "payment-map", "", "CSV mapping payment types to account codes")
+	exportSalesJournalCmd.Flags().StringVar(&journalTaxMap, "tax-map", "", "CSV mapping tax rates to account codes")
+	exportSalesJournalCmd.Flags().BoolVar(&journalNoCost, "no-cost", false, "Do not post the cost of goods sold")
+	exportSalesJournalCmd.Flags().StringVar(&journalXeroTax, "xero-tax-rate", "Tax Exempt", "Tax rate for xero lines, tax is posted on its own lines")
+	exportSalesJournalCmd.Flags().StringVar(&journalAccounts.Revenue, "revenue-account", "Sales", "Account for products without a sales account code")
+	exportSalesJournalCmd.Flags().StringVar(&journalAccounts.Tax, "tax-account", "Sales Tax", "Account for tax rates not in the tax map")
+	exportSalesJournalCmd.Flags().StringVar(&journalAccounts.Cost, "cost-account", "Cost of Goods Sold", "Account for the cost of products without a purchase account code")
+	exportSalesJournalCmd.Flags().StringVar(&journalAccounts.Inventory, "inventory-account", "Inventory", "Account the cost of goods sold is taken from")
+	exportSalesJournalCmd.Flags().StringVar(&journalAccounts.Receivable, "receivable-account", "Accounts Receivable", "Account for what is left unpaid on a sale")
+	exportSalesJournalCmd.Flags().StringVar(&journalAccounts.Rounding, "rounding-account", "Rounding", "Account for rounding differences")
+	exportSalesJournalCmd.Flags().StringVar(&journalAccounts.Suspense, "suspense-account", "Suspense", "Account for differences that are not rounding or unpaid balances")
+	exportSalesJournalCmd.Flags().BoolVar(&journalChained, "chained", false, "Write a hash-chained journal of every sale with a signed manifest")
+	exportSalesJournalCmd.Flags().StringVar(&journalSigningKey, "signing-key", "", "PEM ed25519 key to sign a chained journal with, required with --chained")
+
+	rootCmd.AddCommand(exportSalesJournalCmd)
+}
+
+// journalLine is a line of a journal, debits are positive and credits negative
+type journalLine struct {
+	Account     string
+	Description string
+	Amount      float64
+}
+
+// journal is the journal of a day and outlet, or of a sale
+type journal struct {
+	Date      time.Time
+	Outlet    string
+	Reference string
+	Lines     []journalLine
+}
+
+// journalMapping is the account of each payment type and tax rate, keyed by normalised name
+type journalMapping struct {
+	Payments map[string]string
+	Taxes    map[string]string
+}
+
+// journalRoundingLimit is the largest difference posted as rounding, more is posted to suspense and reported
+const journalRoundingLimit = 0.1
+
+// journalUnpaidStatuses are the sales whose unpaid balance is owed to the store and posted as receivable
+var journalUnpaidStatuses = map[string]bool{"LAYBY": true, "LAYBY_CLOSED": true, "ONACCOUNT": true, "ONACCOUNT_CLOSED": true}
+
+func exportSalesJournal() {
+	if journalChained {
+		exportChainedJournal(journalSigningKey)
+		return
+	}
+
+	// Check the options before fetching anything
+	journalPeriod = strings.ToLower(journalPeriod)
+	if journalPeriod != "day" && journalPeriod != "sale" {
+		err := fmt.Errorf("'%s' is not a valid period, use day or sale", journalPeriod)
+		messenger.ExitWithError(err)
+	}
+	writer, ok := journalWriters[strings.ToLower(journalFormat)]
+	if !ok {
+		err := fmt.Errorf("'%s' is not a valid format, use generic, xero or quickbooks", journalFormat)
+		messenger.ExitWithError(err)
+	}
+	dateLayout, err := journalDateLayout(journalDateFormat)
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	mapping := journalMapping{Payments: map[string]string{}, Taxes: map[string]string{}}
+	if journalPaymentMap != "" {
+		if mapping.Payments, err = readJournalMapping(journalPaymentMap, journalMappingSchema("payment_type", "payment", "payment_name", "name")); err != nil {
+			messenger.ExitWithError(err)
+		}
+	}
+	if journalTaxMap != "" {
+		if mapping.Taxes, err = readJournalMapping(journalTaxMap, journalMappingSchema("tax_rate", "tax", "rate", "tax_name")); err != nil {
+			messenger.ExitWithError(err)
+		}
+	}
+
+	vc := vend.NewClient(Token, DomainPrefix, timeZone)
+	fmt.Println("Creating Sales Journal...")
+	sales, data := fetchReportSales(vc, journalFilters, false)
+
+	journals, unmapped, unbalanced := buildJournals(sales, data, mapping, journalAccounts, journalPeriod, !journalNoCost, timeZone)
+	if len(unmapped) > 0 {
+		fmt.Println(color.YellowString("\nThese payment types were posted to accounts named after them, add them to --payment-map to choose the account:"))
+		for _, name := range unmapped {
+			fmt.Println(" -", name)
+		}
+	}
+	if len(unbalanced) > 0 {
+		fmt.Println(color.YellowString("\nThese journals did not balance, the difference was posted to %s:", journalAccounts.Suspense))
+		for _, difference := range unbalanced {
+			fmt.Println(" -", difference)
+		}
+	}
+
+	fileName := fmt.Sprintf("%s_sales_journal_%s_f%s_t%s.csv", DomainPrefix, strings.ToLower(journalFormat), dateFrom, dateTo)
+	if err = writeJournals(fileName, journals, writer, dateLayout); err != nil {
+		err = fmt.Errorf("failed to write journal: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	fmt.Println(color.GreenString("\n\nFinished!🎉\nWrote %d journals from %d sales to %s", len(journals), len(sales), fileName))
+}
+
+// readJournalMapping reads a mapping file into accounts by normalised name
+func readJournalMapping(path string, schema csvparser.Schema) (map[string]string, error) {
+	table, err := readSchemaFile(path, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	accounts := map[string]string{}
+	for _, record := range table.Records {
+		accounts[normaliseName(record.String(schema.Columns[0].Name))] = record.String("account_code")
+	}
+	return accounts, nil
+}
+
+// buildJournals posts every sale to the journal of its period, and every payment to the journal of the day it was
+// paid. It returns the journals in date order, the payment types that had no mapping and the journals that did not
+// balance.
+func buildJournals(sales []vend.Sale, data salesReportData, mapping journalMapping, accounts journalAccountFlags,
+	period string, includeCost bool, timeZone string) ([]journal, []string, []string) {
+
+	var journals []*journal
+	byKey := map[string]*journal{}
+	unmapped := map[string]bool{}
+
+	// journalFor returns the journal of a day and outlet, or of a sale and day, creating it the first time
+	journalFor := func(date time.Time, outletName, saleKey, invoiceNumber string) *journal {
+		day := date.Format("2006-01-02")
+		key := day + "|" + outletName
+		reference := fmt.Sprintf("Vend sales %s %s", outletName, day)
+		if period == "sale" {
+			key = saleKey + "|" + day
+			reference = fmt.Sprintf("Vend sale %s %s", invoiceNumber, outletName)
+		}
+		entry, ok := byKey[key]
+		if !ok {
+			entry = &journal{Date: date, Outlet: outletName, Reference: strings.TrimSpace(reference)}
+			byKey[key] = entry
+			journals = append(journals, entry)
+		}
+		return entry
+	}
+
+	for i, sale := range sales {
+		saleDate, err := vend.ParseVendDT(*sale.SaleDate, timeZone)
+		if err != nil {
+			fmt.Printf("Error parsing date: %s\n", err)
+			continue
+		}
+		var outletName, invoiceNumber string
+		if sale.OutletID != nil {
+			outletName = data.Outlets[*sale.OutletID]
+		}
+		if sale.InvoiceNumber != nil {
+			invoiceNumber = *sale.InvoiceNumber
+		}
+		saleKey := fmt.Sprint(i)
+		unpaid := sale.Status != nil && journalUnpaidStatuses[strings.ToUpper(*sale.Status)]
+
+		entry := journalFor(saleDate, outletName, saleKey, invoiceNumber)
+		owing := postJournalSale(entry, sale, data, mapping, accounts, includeCost)
+
+		if sale.Payments != nil {
+			for _, payment := range *sale.Payments {
+				if payment.Amount == nil {
+					continue
+				}
+				paidEntry := entry
+				if payment.PaymentDate != nil {
+					paid := payment.PaymentDate.In(saleDate.Location())
+					if paid.Format("2006-01-02") != saleDate.Format("2006-01-02") {
+						paidEntry = journalFor(paid, outletName, saleKey, invoiceNumber)
+					}
+				}
+				postJournalPayment(paidEntry, payment, mapping, unmapped)
+
+				switch {
+				case paidEntry == entry:
+					owing -= *payment.Amount
+				case unpaid:
+					// a later layby or account payment pays off what the sale left owing
+					paidEntry.add(accounts.Receivable, "Unpaid balance paid", -*payment.Amount)
+				}
+			}
+		}
+		if unpaid {
+			entry.add(accounts.Receivable, "Unpaid balance", owing)
+		}
+	}
+
+	sort.SliceStable(journals, func(i, j int) bool { return journals[i].Date.Before(journals[j].Date) })
+
+	var balanced []journal
+	var unbalanced []string
+	for _, entry := range journals {
+		result, difference := balanceJournal(*entry, accounts)
+		balanced = append(balanced, result)
+		if difference == 0 {
+			continue
+		}
+		label := result.Reference
+		if period == "sale" {
+			label += " " + result.Date.Format("2006-01-02")
+		}
+		unbalanced = append(unbalanced, fmt.Sprintf("%s: %s", label, formatCents(difference)))
+	}
+
+	var unmappedNames []string
+	for name := range unmapped {
+		unmappedNames = append(unmappedNames, name)
+	}
+	sort.Strings(unmappedNames)
+	return balanced, unmappedNames, unbalanced
+}
+
+// postJournalSale adds the revenue, tax and cost of a sale to a journal, and returns the sale's total including tax.
+// Returns have negative quantities, so they reverse what a sale posts.
+func postJournalSale(entry *journal, sale vend.Sale, data salesReportData, mapping journalMapping, accounts journalAccountFlags,
+	includeCost bool) float64 {
+
+	var total float64
+	if sale.LineItems == nil {
+		return total
+	}
+	for _, lineitem := range *sale.LineItems {
+		if lineitem.Quantity == nil {
+			continue
+		}
+		var product vend.Product
+		if lineitem.ProductID != nil {
+			product = data.Products[*lineitem.ProductID]
+		}
+
+		if lineitem.Price != nil {
+			account := accounts.Revenue
+			if product.AccountCodeSales != nil && *product.AccountCodeSales != "" {
+				account = *product.AccountCodeSales
+			}
+			entry.add(account, "Sales", -*lineitem.Price**lineitem.Quantity)
+			total += *lineitem.Price * *lineitem.Quantity
+		}
+
+		components := lineTaxComponents(lineitem, data)
+		if len(components) == 0 && lineitem.Tax != nil && *lineitem.Tax != 0 {
+			components = []taxComponent{{Name: "Tax", Amount: *lineitem.Tax * *lineitem.Quantity}}
+		}
+		for _, component := range components {
+			account, ok := mapping.Taxes[normaliseName(component.Name)]
+			if !ok {
+				account = accounts.Tax
+			}
+			entry.add(account, "Tax: "+component.Name, -component.Amount)
+			total += component.Amount
+		}
+
+		if includeCost {
+			cost := lineCostTotal(lineitem)
+			account := accounts.Cost
+			if product.AccountCodePurchase != nil && *product.AccountCodePurchase != "" {
+				account = *product.AccountCodePurchase
+			}
+			entry.add(account, "Cost of goods sold", cost)
+			entry.add(accounts.Inventory, "Inventory", -cost)
+		}
+	}
+	return total
+}
+
+// postJournalPayment debits a payment to the account of its payment type
+func postJournalPayment(entry *journal, payment vend.Payment, mapping journalMapping, unmapped map[string]bool) {
+	var name string
+	if payment.Name != nil {
+		name = *payment.Name
+	}
+	account, ok := mapping.Payments[normaliseName(name)]
+	if !ok && payment.RetailerPaymentTypeID != nil {
+		account, ok = mapping.Payments[normaliseName(*payment.RetailerPaymentTypeID)]
+	}
+	if !ok {
+		account = name
+		unmapped[name] = true
+	}
+	entry.add(account, "Payments: "+name, *payment.Amount)
+}
+
+// add adds an amount to the line with the same account and description
+func (j *journal) add(account, description string, amount float64) {
+	for i := range j.Lines {
+		if j.Lines[i].Account == account && j.Lines[i].Description == description {
+			j.Lines[i].Amount += amount
+			return
+		}
+	}
+	j.Lines = append(j.Lines, journalLine{Account: account, Description: description, Amount: amount})
+}
+
+// balanceJournal rounds every line to cents, drops empty lines and posts what is left over, so debits equal credits.
+// It returns the difference it posted to suspense, which is not rounding and has to be looked into.
+func balanceJournal(entry journal, accounts journalAccountFlags) (journal, float64) {
+	var lines []journalLine
+	var total float64
+	for _, line := range entry.Lines {
+		line.Amount = roundCents(line.Amount)
+		if line.Amount == 0 {
+			continue
+		}
+		total += line.Amount
+		lines = append(lines, line)
+	}
+
+	total = roundCents(total)
+	var unexplained float64
+	switch {
+	case total == 0:
+	case math.Abs(total) <= journalRoundingLimit:
+		lines = append(lines, journalLine{Account: accounts.Rounding, Description: "Rounding", Amount: -total})
+	default:
+		unexplained = -total
+		lines = append(lines, journalLine{Account: accounts.Suspense, Description: "Unexplained difference", Amount: unexplained})
+	}
+
+	entry.Lines = lines
+	return entry, unexplained
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// journalWriter writes the header and rows of an accounting import layout
+type journalWriter struct {
+	Header []string
+	Rows   func(number int, entry journal, dateLayout string) [][]string
+}
+
+// journalWriters are the import layouts by format name
+var journalWriters = map[string]journalWriter{
+	"generic": {
+		Header: []string{"Date", "Reference", "Outlet", "Account", "Description", "Debit", "Credit"},
+		Rows: func(number int, entry journal, dateLayout string) [][]string {
+			var rows [][]string
+			for _, line := range entry.Lines {
+				debit, credit := debitCredit(line.Amount)
+				rows = append(rows, []string{entry.Date.Format(dateLayout), entry.Reference, entry.Outlet, line.Account, line.Description, debit, credit})
+			}
+			return rows
+		},
+	},
+	"xero": {
+		Header: []string{"Narration", "Date", "Description", "AccountCode", "TaxRate", "Amount", "TrackingName1", "TrackingOption1"},
+		Rows: func(number int, entry journal, dateLayout string) [][]string {
+			var rows [][]string
+			for _, line := range entry.Lines {
+				rows = append(rows, []string{entry.Reference, entry.Date.Format(dateLayout), line.Description, line.Account,
+					journalXeroTax, formatCents(line.Amount), "Outlet", entry.Outlet})
+			}
+			return rows
+		},
+	},
+	"quickbooks": {
+		Header: []string{"Journal No", "Journal Date", "Account Name", "Debits", "Credits", "Description", "Location"},
+		Rows: func(number int, entry journal, dateLayout string) [][]string {
+			var rows [][]string
+			journalNumber := fmt.Sprintf("VEND-%s-%d", entry.Date.Format("20060102"), number)
+			for _, line := range entry.Lines {
+				debit, credit := debitCredit(line.Amount)
+				rows = append(rows, []string{journalNumber, entry.Date.Format(dateLayout), line.Account, debit, credit, line.Description, entry.Outlet})
+			}
+			return rows
+		},
+	},
+}
+
+func debitCredit(amount float64) (string, string) {
+	if amount >= 0 {
+		return formatCents(amount), ""
+	}
+	return "", formatCents(-amount)
+}
+
+func formatCents(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// journalDateLayout converts a date format flag to a Go time layout
+func journalDateLayout(format string) (string, error) {
+	layouts := map[string]string{
+		"YYYY-MM-DD": "2006-01-02",
+		"DD/MM/YYYY": "02/01/2006",
+		"MM/DD/YYYY": "01/02/2006",
+	}
+	layout, ok := layouts[strings.ToUpper(format)]
+	if !ok {
+		return "", fmt.Errorf("'%s' is not a valid date format, use YYYY-MM-DD, DD/MM/YYYY or MM/DD/YYYY", format)
+	}
+	return layout, nil
+}
+
+func writeJournals(fileName string, journals []journal, writer journalWriter, dateLayout string) error {
+	file, err := os.Create(fmt.Sprintf("./%s", fileName))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	csvWriter := csv.NewWriter(file)
+	csvWriter.Write(writer.Header)
+	for i, entry := range journals {
+		for _, row := range writer.Rows(i+1, entry, dateLayout) {
+			csvWriter.Write(row)
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/govend/vend"
+)
+
+func TestBuildJournals(t *testing.T) {
+	outletID, productID, taxID, salesCode := "o1", "p1", "t1", "4000"
+	gst, rate, taxName := "GST", 0.15, "GST 15%"
+	date1, date2 := "2024-03-01T01:00:00Z", "2024-03-01T03:00:00Z"
+	later := time.Date(2024, 3, 4, 2, 0, 0, 0, time.UTC)
+
+	paidLater := testPayment("Card", 18)
+	paidLater.PaymentDate = &later
+
+	sales := []vend.Sale{
+		testSale(saleFixture{Outlet: outletID, Date: date1, Status: "CLOSED", Payments: []vend.Payment{testPayment("Cash", 23)},
+			Lines: []lineFixture{{Product: productID, TaxID: taxID, Quantity: 2, Price: 10, Tax: 1.5, Cost: 8}}}),
+		testSale(saleFixture{Outlet: outletID, Date: date2, Status: "CLOSED", Payments: []vend.Payment{testPayment("Card", -11.5)},
+			Lines: []lineFixture{{Product: productID, TaxID: taxID, Quantity: -1, Price: 10, Tax: 1.5, Cost: -4}}}),
+		// a layby with a deposit, paid off three days later
+		testSale(saleFixture{Outlet: outletID, Date: date2, Status: "LAYBY_CLOSED", Payments: []vend.Payment{testPayment("Cash", 5), paidLater},
+			Lines: []lineFixture{{Product: productID, TaxID: taxID, Quantity: 1, Price: 20, Tax: 3, Cost: 9}}}),
+	}
+	data := newSalesReportData(map[string]string{outletID: "Newmarket"}, nil, nil, nil, nil,
+		[]vend.Product{{ID: &productID, AccountCodeSales: &salesCode}},
+		map[string]vend.Taxes{taxID: {Name: &taxName, TaxRates: []vend.TaxRates{{Name: &gst, Rate: &rate}}}})
+	mapping := journalMapping{Payments: map[string]string{"cash": "1010"}, Taxes: map[string]string{"gst": "2200"}}
+	accounts := journalAccountFlags{Revenue: "Sales", Tax: "Sales Tax", Cost: "COGS", Inventory: "Inventory", Receivable: "AR",
+		Rounding: "Rounding", Suspense: "Suspense"}
+
+	journals, unmapped, unbalanced := buildJournals(sales, data, mapping, accounts, "day", true, "UTC")
+	assert.Equal(t, []string{"Card"}, unmapped)
+	assert.Empty(t, unbalanced)
+	assert.Len(t, journals, 2)
+	assert.Equal(t, "Vend sales Newmarket 2024-03-01", journals[0].Reference)
+	assert.Equal(t, []journalLine{
+		{Account: "4000", Description: "Sales", Amount: -30},
+		{Account: "2200", Description: "Tax: GST", Amount: -4.5},
+		{Account: "COGS", Description: "Cost of goods sold", Amount: 13},
+		{Account: "Inventory", Description: "Inventory", Amount: -13},
+		{Account: "1010", Description: "Payments: Cash", Amount: 28},
+		{Account: "Card", Description: "Payments: Card", Amount: -11.5},
+		{Account: "AR", Description: "Unpaid balance", Amount: 18},
+	}, journals[0].Lines)
+	assert.Equal(t, "Vend sales Newmarket 2024-03-04", journals[1].Reference)
+	assert.Equal(t, []journalLine{
+		{Account: "Card", Description: "Payments: Card", Amount: 18},
+		{Account: "AR", Description: "Unpaid balance paid", Amount: -18},
+	}, journals[1].Lines)
+
+	journals, _, _ = buildJournals(sales, data, mapping, accounts, "sale", false, "UTC")
+	assert.Len(t, journals, 4)
+	for _, entry := range journals {
+		var total float64
+		for _, line := range entry.Lines {
+			total += line.Amount
+		}
+		assert.InDelta(t, 0, total, 0.0001)
+	}
+
+	// a sale that is neither paid nor owed is reported, not hidden in receivable
+	sales = []vend.Sale{testSale(saleFixture{Outlet: outletID, Date: date1, Status: "CLOSED", Payments: []vend.Payment{testPayment("Cash", 5)},
+		Lines: []lineFixture{{Product: productID, TaxID: taxID, Quantity: 1, Price: 10, Tax: 1.5, Cost: 5}}})}
+	journals, _, unbalanced = buildJournals(sales, data, mapping, accounts, "day", false, "UTC")
+	assert.Equal(t, []string{"Vend sales Newmarket 2024-03-01: 6.50"}, unbalanced)
+	assert.Equal(t, journalLine{Account: "Suspense", Description: "Unexplained difference", Amount: 6.5}, journals[0].Lines[len(journals[0].Lines)-1])
+}
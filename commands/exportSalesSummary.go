@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vend/vend-cli/pkg/messenger"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vend/govend/vend"
+)
+
+// Command config
+var (
+	summaryFilters salesFilterFlags
+	summaryBy      string
+	summaryPrint   string
+
+	exportSalesSummaryCmd = &cobra.Command{
+		Use:   "export-sales-summary",
+		Short: "Export End of Day Sales Summary",
+		Long: fmt.Sprintf(`
+Summarises sales per day and outlet, or per day and register with --by register: sale and return counts,
+items sold, gross sales, discounts, returns, net sales, tax, loyalty and the total taken per payment type.
+Amounts are excluding tax unless they are totals or payments.
+
+The summary is written as CSV, and as Markdown (default) or a printable HTML page with --print html.
+
+Example:
+%s`, color.GreenString("vendcli export-sales-summary -d DOMAINPREFIX -t TOKEN -z TIMEZONE -F DATEFROM -T DATETO --by register --print html")),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			exportSalesSummary()
+		},
+	}
+)
+
+func init() {
+	// Flags
+	addSalesRangeFlags(exportSalesSummaryCmd, &summaryFilters)
+	exportSalesSummaryCmd.Flags().StringVar(&summaryBy, "by", "outlet", "Summarise each day by: outlet, register")
+	exportSalesSummaryCmd.Flags().StringVar(&summaryPrint, "print", "markdown", "Also write the summary as: markdown, html, none")
+
+	rootCmd.AddCommand(exportSalesSummaryCmd)
+}
+
+// salesSummary is the summary of a day at an outlet or register
+type salesSummary struct {
+	Date        string
+	Outlet      string
+	Register    string
+	SaleCount   int
+	ReturnCount int
+	Items       float64
+	Gross       float64
+	Discounts   float64
+	Returns     float64
+	Net         float64
+	Tax         float64
+	Loyalty     float64
+	Payments    map[string]float64
+}
+
+func exportSalesSummary() {
+	// Check the options before fetching anything
+	summaryBy = strings.ToLower(summaryBy)
+	if summaryBy != "outlet" && summaryBy != "register" {
+		err := fmt.Errorf("'%s' is not a valid option for --by, use outlet or register", summaryBy)
+		messenger.ExitWithError(err)
+	}
+	if err := checkReportFormat(summaryPrint); err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	vc := vend.NewClient(Token, DomainPrefix, timeZone)
+	fmt.Println("Creating Sales Summary...")
+	sales, data := fetchReportSales(vc, summaryFilters, false)
+
+	summaries, paymentNames := summariseSales(sales, data, summaryBy == "register", timeZone)
+	table := salesSummaryTable(summaries, paymentNames, summaryBy == "register")
+
+	fileName := fmt.Sprintf("%s_sales_summary_f%s_t%s.csv", DomainPrefix, dateFrom, dateTo)
+	files, err := table.write(fileName, summaryPrint)
+	if err != nil {
+		err = fmt.Errorf("failed to write sales summary: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	fmt.Println(color.GreenString("\n\nFinished!🎉\nSummarised %d sales: %s", len(sales), strings.Join(files, ", ")))
+}
+
+// summariseSales adds up sales by day and outlet or register, in date order. It also returns the names of every
+// payment type taken, sorted.
+func summariseSales(sales []vend.Sale, data salesReportData, byRegister bool, timeZone string) ([]*salesSummary, []string) {
+	var summaries []*salesSummary
+	byKey := map[string]*salesSummary{}
+	paymentNames := map[string]bool{}
+
+	for _, sale := range sales {
+		saleDate, err := vend.ParseVendDT(*sale.SaleDate, timeZone)
+		if err != nil {
+			fmt.Printf("Error parsing date: %s\n", err)
+			continue
+		}
+		summary := salesSummary{Date: saleDate.Format("2006-01-02")}
+		if sale.OutletID != nil {
+			summary.Outlet = data.Outlets[*sale.OutletID]
+		}
+		if byRegister {
+			summary.Register = data.registerName(sale.RegisterID)
+		}
+
+		key := summary.Date + "|" + summary.Outlet + "|" + summary.Register
+		total, ok := byKey[key]
+		if !ok {
+			summary.Payments = map[string]float64{}
+			total = &summary
+			byKey[key] = total
+			summaries = append(summaries, total)
+		}
+
+		total.add(sale)
+		for name := range total.Payments {
+			paymentNames[name] = true
+		}
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.Outlet != b.Outlet {
+			return a.Outlet < b.Outlet
+		}
+		return a.Register < b.Register
+	})
+
+	var names []string
+	for name := range paymentNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return summaries, names
+}
+
+// add adds a sale to the summary. A sale that returns more than it sells counts as a return.
+func (s *salesSummary) add(sale vend.Sale) {
+	var sold, returned float64
+	if sale.LineItems != nil {
+		for _, lineitem := range *sale.LineItems {
+			if lineitem.Quantity == nil {
+				continue
+			}
+			quantity := *lineitem.Quantity
+			var price, discount, tax float64
+			if lineitem.Price != nil {
+				price = *lineitem.Price
+			}
+			if lineitem.Discount != nil {
+				discount = *lineitem.Discount
+			}
+			if lineitem.Tax != nil {
+				tax = *lineitem.Tax
+			}
+
+			if quantity < 0 {
+				s.Returns -= price * quantity
+				returned -= price * quantity
+			} else {
+				s.Items += quantity
+				s.Gross += (price + discount) * quantity
+				s.Discounts += discount * quantity
+				sold += price * quantity
+			}
+			s.Net += price * quantity
+			s.Tax += tax * quantity
+		}
+	}
+
+	if returned > sold {
+		s.ReturnCount++
+	} else {
+		s.SaleCount++
+	}
+	if sale.TotalLoyalty != nil {
+		s.Loyalty += *sale.TotalLoyalty
+	}
+	if sale.Payments != nil {
+		for _, payment := range *sale.Payments {
+			if payment.Name != nil && payment.Amount != nil {
+				s.Payments[*payment.Name] += *payment.Amount
+			}
+		}
+	}
+}
+
+// salesSummaryTable lays the summaries out with a column per payment type and a total row
+func salesSummaryTable(summaries []*salesSummary, paymentNames []string, byRegister bool) reportTable {
+	header := []string{"Date", "Outlet"}
+	if byRegister {
+		header = append(header, "Register")
+	}
+	numericFrom := len(header)
+	header = append(header, "Sales", "Returns", "Items Sold", "Gross Sales", "Discounts", "Returns Value", "Net Sales", "Tax", "Total", "Loyalty")
+	for _, name := range paymentNames {
+		header = append(header, "Payment: "+name)
+	}
+	header = append(header, "Total Paid")
+
+	grandTotal := salesSummary{Date: "Total", Payments: map[string]float64{}}
+	var rows [][]string
+	for _, summary := range summaries {
+		rows = append(rows, summary.row(paymentNames, byRegister))
+
+		grandTotal.SaleCount += summary.SaleCount
+		grandTotal.ReturnCount += summary.ReturnCount
+		grandTotal.Items += summary.Items
+		grandTotal.Gross += summary.Gross
+		grandTotal.Discounts += summary.Discounts
+		grandTotal.Returns += summary.Returns
+		grandTotal.Net += summary.Net
+		grandTotal.Tax += summary.Tax
+		grandTotal.Loyalty += summary.Loyalty
+		for name, amount := range summary.Payments {
+			grandTotal.Payments[name] += amount
+		}
+	}
+	rows = append(rows, grandTotal.row(paymentNames, byRegister))
+
+	return reportTable{
+		Title:       fmt.Sprintf("Sales Summary %s", DomainPrefix),
+		Notes:       []string{fmt.Sprintf("%s to %s", dateFrom, dateTo), "Amounts exclude tax, except Total and payments"},
+		Header:      header,
+		Rows:        rows,
+		NumericFrom: numericFrom,
+	}
+}
+
+func (s salesSummary) row(paymentNames []string, byRegister bool) []string {
+	row := []string{s.Date, s.Outlet}
+	if byRegister {
+		row = append(row, s.Register)
+	}
+	row = append(row,
+		strconv.Itoa(s.SaleCount),
+		strconv.Itoa(s.ReturnCount),
+		strconv.FormatFloat(s.Items, 'f', -1, 64),
+		formatCents(s.Gross),
+		formatCents(s.Discounts),
+		formatCents(s.Returns),
+		formatCents(s.Net),
+		formatCents(s.Tax),
+		formatCents(s.Net+s.Tax),
+		formatCents(s.Loyalty),
+	)
+	var paid float64
+	for _, name := range paymentNames {
+		row = append(row, formatCents(s.Payments[name]))
+		paid += s.Payments[name]
+	}
+	return append(row, formatCents(paid))
+}
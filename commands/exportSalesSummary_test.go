@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/govend/vend"
+)
+
+func TestSummariseSales(t *testing.T) {
+	outletID, registerID := "o1", "r1"
+	registerName := "Main"
+
+	sales := []vend.Sale{
+		testSale(saleFixture{Outlet: outletID, Register: registerID, Date: "2024-03-02T01:00:00Z", Loyalty: 0.5,
+			Lines: []lineFixture{{Quantity: 1, Price: 20, Tax: 3}}, Payments: []vend.Payment{testPayment("Card", 23)}}),
+		testSale(saleFixture{Outlet: outletID, Register: registerID, Date: "2024-03-01T01:00:00Z", Loyalty: 0.5,
+			Lines: []lineFixture{{Quantity: 2, Price: 8, Discount: 2, Tax: 1.2}}, Payments: []vend.Payment{testPayment("Cash", 18.4)}}),
+		testSale(saleFixture{Outlet: outletID, Register: registerID, Date: "2024-03-01T03:00:00Z", Loyalty: 0.5,
+			Lines: []lineFixture{{Quantity: -1, Price: 8, Tax: 1.2}}, Payments: []vend.Payment{testPayment("Card", -9.2)}}),
+	}
+	data := newSalesReportData(map[string]string{outletID: "Newmarket"},
+		[]vend.Register{{ID: &registerID, Name: &registerName}}, nil, nil, nil, nil, nil)
+
+	summaries, paymentNames := summariseSales(sales, data, true, "UTC")
+	assert.Equal(t, []string{"Card", "Cash"}, paymentNames)
+	assert.Len(t, summaries, 2)
+
+	day := summaries[0]
+	assert.Equal(t, "2024-03-01", day.Date)
+	assert.Equal(t, "Main", day.Register)
+	assert.Equal(t, 1, day.SaleCount)
+	assert.Equal(t, 1, day.ReturnCount)
+	assert.Equal(t, 20.0, day.Gross)
+	assert.Equal(t, 4.0, day.Discounts)
+	assert.Equal(t, 8.0, day.Returns)
+	assert.Equal(t, 8.0, day.Net)
+	assert.Equal(t, 1.2, day.Tax)
+	assert.Equal(t, map[string]float64{"Cash": 18.4, "Card": -9.2}, day.Payments)
+
+	table := salesSummaryTable(summaries, paymentNames, true)
+	assert.Len(t, table.Rows, 3)
+	total := table.Rows[2]
+	assert.Equal(t, "Total", total[0])
+	assert.Equal(t, "2", total[3])
+	assert.Equal(t, "1.50", total[12])
+	assert.Equal(t, "32.20", total[len(total)-1])
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/govend/vend"
+)
+
+func TestLineTaxComponents(t *testing.T) {
+	taxID, gst, pst, gstID, pstID := "tax", "GST", "PST", "r1", "r2"
+	gstRate, pstRate := 0.05, 0.07
+	combined := "GST + PST"
+	data := newSalesReportData(nil, nil, nil, nil, nil, nil, map[string]vend.Taxes{
+		taxID: {Name: &combined, TaxRates: []vend.TaxRates{{ID: &gstID, Name: &gst, Rate: &gstRate}, {ID: &pstID, Name: &pst, Rate: &pstRate}}},
+	})
+
+	// without recorded components the tax is split by rate
+	quantity, tax := 2.0, 1.2
+	line := vend.LineItem{TaxID: &taxID, Tax: &tax, Quantity: &quantity}
+	components := lineTaxComponents(line, data)
+	assert.Equal(t, []taxComponent{{Name: "GST", Amount: 1}, {Name: "PST", Amount: 1.4}}, components)
+
+	totals := addTaxComponents(nil, components)
+	totals = addTaxComponents(totals, components)
+	assert.Equal(t, "GST: 2; PST: 2.8", formatTaxComponents(totals))
+
+	// the estimated shares add up to the line's tax
+	quantity, tax = 1, 0.01
+	components = lineTaxComponents(line, data)
+	assert.Equal(t, []taxComponent{{Name: "GST", Amount: 0.00417}, {Name: "PST", Amount: 0.00583}}, components)
+
+	// the components Vend recorded are used as they are, e.g. for a compound tax
+	lineID := "l1"
+	line.ID = &lineID
+	data.LineTaxes = map[string][]lineTaxComponent{lineID: {{RateID: gstID, TotalTax: 0.5}, {RateID: pstID, TotalTax: 0.735}, {RateID: "gone", TotalTax: 0.1}}}
+	assert.Equal(t, []taxComponent{{Name: "GST", Amount: 0.5}, {Name: "PST", Amount: 0.735}, {Name: "gone", Amount: 0.1}}, lineTaxComponents(line, data))
+
+	unknown := "unknown"
+	line.ID, line.TaxID = nil, &unknown
+	assert.Empty(t, lineTaxComponents(line, data))
+}
+
+func TestLineDiscountTotal(t *testing.T) {
+	quantity, discount, total := 3.0, 1.5, 4.0
+	assert.Equal(t, 4.5, lineDiscountTotal(vend.LineItem{Quantity: &quantity, Discount: &discount}))
+	assert.Equal(t, 4.0, lineDiscountTotal(vend.LineItem{Quantity: &quantity, Discount: &discount, TotalDiscount: &total}))
+	assert.Equal(t, 0.0, lineDiscountTotal(vend.LineItem{}))
+}
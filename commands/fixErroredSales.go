@@ -48,11 +48,13 @@ It offers two modes, "parse" and "post":
 When posting you can choose whether or not you'd like to "overwrite" existing sales: 
 * If overwrite is set to "false" (safer, and the default) it will check if the sale has already beend posted before posting. If it has, it will skip it.
 * If overwrite is set to "true" it will post the sale regardless of whether it already exists in vend or not.
+  Existing sales are saved to a snapshot file before they are overwritten, see vendcli restore.
 
 If posting it is highly recommended that you use parse mode FIRST to check the sales before posting.
 
 Example:
 	%s`, color.GreenString("vendcli fix-errored-sales -d DOMAINPREFIX -t TOKEN -f FILENAME.json -m MODE -o OVERWRITE -z TIMEZONE")),
+		Annotations: map[string]string{mutatingAnnotation: "true"},
 		Run: func(cmd *cobra.Command, args []string) {
 			importSales()
 		},
@@ -98,6 +100,8 @@ func importSales() {
 
 	if isPostMode {
 		if overwriteBool {
+			startSaleSnapshots("fix-errored-sales")
+			defer stopSnapshots()
 			postSales(erroredSales)
 		} else {
 			checkedBeforePosting(erroredSales)
@@ -113,15 +117,21 @@ func importSales() {
 		}
 	}
 
+	var failureFile string
 	if len(failedSalePostRequests) > 0 {
 		fmt.Println(color.RedString("\nThere were some errors. Writing failures to csv.."))
-		fileName := fmt.Sprintf("%s_failed_post_sale_requests_%v.csv", DomainPrefix, time.Now().Unix())
-		err := csvparser.WriteErrorCSV(fileName, failedSalePostRequests)
+		failureFile = fmt.Sprintf("%s_failed_post_sale_requests_%v.csv", DomainPrefix, time.Now().Unix())
+		err := csvparser.WriteErrorCSV(failureFile, failedSalePostRequests)
 		if err != nil {
 			messenger.ExitWithError(err)
 			return
 		}
 	}
+	if isPostMode {
+		finishRun(len(erroredSales), len(erroredSales)-len(failedSalePostRequests), failureFile)
+	} else {
+		skipRun()
+	}
 
 	fmt.Println(color.GreenString("\nFinished! 🎉\n"))
 }
@@ -247,7 +257,7 @@ func parseSales(sales9 []vend.Sale9) {
 	sortBySaleDate(sales)
 
 	// Get other data
-	registers, users, customers, customerGroupMap, products := GetVendDataForSalesReport(*vendClient)
+	registers, users, customers, customerGroupMap, products, taxes := GetVendDataForSalesReport(*vendClient)
 
 	// Create report
 	file, err := createErredSalesReport()
@@ -258,7 +268,8 @@ func parseSales(sales9 []vend.Sale9) {
 
 	defer file.Close()
 
-	file = addSalesReportHeader(file)
+	layout := salesProfiles["default"]
+	file = addSalesReportHeader(file, layout)
 
 	fmt.Println("\nWriting sales report...")
 	p := pbar.CreateSingleBar()
@@ -266,7 +277,8 @@ func parseSales(sales9 []vend.Sale9) {
 	if err != nil {
 		fmt.Println(err)
 	}
-	file = writeSalesReport(file, bar, registers, users, customers, customerGroupMap, products, sales, timeZoneImportSales)
+	data := newSalesReportData(nil, registers, users, customers, customerGroupMap, products, taxes)
+	file = writeSalesReport(file, bar, layout, data, sales, timeZoneImportSales)
 	p.Wait()
 
 	fmt.Printf("\nSales report created: %s\n", file.Name())
@@ -284,7 +296,16 @@ func postSales(sales []vend.Sale9) {
 	for idx, sale := range sales {
 		bar.Increment()
 		if sale.ID != nil {
-			err := postSale(sale)
+			// save the sale we are about to overwrite in case we need to restore it
+			err := snapshotExistingSale(*sale.ID)
+			if err != nil {
+				failedSalePostRequests = append(failedSalePostRequests, FailedSalePostRequest{
+					SaleID: *sale.ID,
+					Reason: err.Error(),
+				})
+				continue
+			}
+			err = postSale(sale)
 			if err != nil {
 				err = fmt.Errorf("error posting sale: %s", err)
 				failedSalePostRequests = append(failedSalePostRequests, FailedSalePostRequest{
@@ -431,10 +452,10 @@ func createErredSalesReport() (*os.File, error) {
 	return file, err
 }
 
-func GetVendDataForSalesReport(vc vend.Client) ([]vend.Register, []vend.User, []vend.Customer, map[string]string, []vend.Product) {
+func GetVendDataForSalesReport(vc vend.Client) ([]vend.Register, []vend.User, []vend.Customer, map[string]string, []vend.Product, map[string]vend.Taxes) {
 	// create a waitgroup to wait for all goroutines to finish
 	fmt.Println("\nFetching data from Vend...")
-	p, err := pbar.CreateMultiBarGroup(5, Token, DomainPrefix)
+	p, err := pbar.CreateMultiBarGroup(6, Token, DomainPrefix)
 	if err != nil {
 		fmt.Println("error creating progress bar group: ", err)
 	}
@@ -444,6 +465,7 @@ func GetVendDataForSalesReport(vc vend.Client) ([]vend.Register, []vend.User, []
 	p.FetchDataWithProgressBar("customers")
 	p.FetchDataWithProgressBar("customerGroups")
 	p.FetchDataWithProgressBar("products")
+	p.FetchDataWithProgressBar("taxes")
 
 	p.MultiBarGroupWait()
 
@@ -452,6 +474,7 @@ func GetVendDataForSalesReport(vc vend.Client) ([]vend.Register, []vend.User, []
 	var customers []vend.Customer
 	customerGroupMap := make(map[string]string)
 	var products []vend.Product
+	taxes := make(map[string]vend.Taxes)
 
 	for err = range p.ErrorChannel {
 		err = fmt.Errorf("error fetching data: %v", err)
@@ -470,10 +493,12 @@ func GetVendDataForSalesReport(vc vend.Client) ([]vend.Register, []vend.User, []
 			customerGroupMap = d
 		case []vend.Product:
 			products = d
+		case map[string]vend.Taxes:
+			taxes = d
 		}
 	}
 
-	return registers, users, customers, customerGroupMap, products
+	return registers, users, customers, customerGroupMap, products, taxes
 }
 
 func validateModeFlag(m string) bool {
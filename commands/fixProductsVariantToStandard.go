@@ -22,6 +22,7 @@ This tool requires a CSV of Product IDs, no headers.
 
 Example:
 %s`, color.GreenString("vendcli fix-products-variant-to-standard -d DOMAINPREFIX -t TOKEN -f FILENAME.csv -r ''")),
+	Annotations: map[string]string{mutatingAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		fixProductsVariantToStandard()
 	},
@@ -31,10 +32,11 @@ var Reason string
 
 func init() {
 	// Flag
-	fixProductsVariantToStandardCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv")
+	fixProductsVariantToStandardCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv, or - to read standard input")
 	fixProductsVariantToStandardCmd.Flags().StringVarP(&Reason, "Reason", "r", "", "The reason for performing this action")
 
 	fixProductsVariantToStandardCmd.MarkFlagRequired("Filename")
+	addIDColumnFlag(fixProductsVariantToStandardCmd)
 	fixProductsVariantToStandardCmd.MarkFlagRequired("Reason")
 
 	rootCmd.AddCommand(fixProductsVariantToStandardCmd)
@@ -66,7 +68,7 @@ func fixProductsVariantToStandard() {
 
 	// Get passed entities from CSV
 	fmt.Println("\nReading CSV...")
-	ids, err := csvparser.ReadIdCSV(FilePath)
+	ids, err := readIDFile(FilePath)
 	if err != nil {
 		err = fmt.Errorf("failed to get IDs from the file: %s Error:%s", FilePath, err)
 		messenger.ExitWithError(err)
@@ -111,14 +113,16 @@ func fixProductsVariantToStandard() {
 		}
 	}
 
+	var failureFile string
 	if len(failedRequests) > 0 {
 		fmt.Println(color.RedString("\n\nThere were some errors. Writing failures to csv.."))
-		fileName := fmt.Sprintf("%s_failed_convert_variant_to_standard_requests_%v.csv", DomainPrefix, time.Now().Unix())
-		err := csvparser.WriteErrorCSV(fileName, failedRequests)
+		failureFile = fmt.Sprintf("%s_failed_convert_variant_to_standard_requests_%v.csv", DomainPrefix, time.Now().Unix())
+		err := csvparser.WriteErrorCSV(failureFile, failedRequests)
 		if err != nil {
 			messenger.ExitWithError(err)
 		}
 	}
+	finishRun(len(ids), len(ids)-len(failedRequests), failureFile)
 
 	fmt.Println(color.GreenString("\n\nFinished! 🎉\n"))
 }
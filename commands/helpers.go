@@ -1,38 +1,57 @@
 package cmd
 
 import (
-	"bytes"
 	"fmt"
 	"os"
+	"strings"
 
 	"encoding/csv"
 	"io/ioutil"
 	"net/http"
+
+	"github.com/vend/vend-cli/pkg/csvparser"
+	pbar "github.com/vend/vend-cli/pkg/progressbar"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
 )
 
-// loadRecordsFromCSV reads the content of a csv file and returns headers and records.
-func loadRecordsFromCSV(path string) ([]string, [][]string, error) {
-	raw, err := ioutil.ReadFile(path)
+// number of row problems printed when reading a file, the rest are in the failures file
+const maxPrintedRowErrors = 20
+
+// readSchemaFile reads a file with a schema, showing progress and warning about ignored columns
+func readSchemaFile(path string, schema csvparser.Schema) (*csvparser.Table, error) {
+	p := pbar.CreateSingleBar()
+	bar, err := p.AddIndeterminateProgressBar("Reading CSV")
 	if err != nil {
-		err = fmt.Errorf(`%s - please check you've specified the right file path.%sTip: make sure you're in the same folder as your file. Use "cd ~/Downloads" to navigate to your Downloads folder`, err, "\n")
-		return nil, nil, err
+		return nil, fmt.Errorf("error creating progress bar:%s", err)
 	}
-	return readRecords(raw)
-}
+	done := make(chan struct{})
+	go bar.AnimateIndeterminateBar(done)
 
-// readRecords converts a byte array to string slices representing headers and records
-func readRecords(csvBytes []byte) ([]string, [][]string, error) {
-	reader := csv.NewReader(bytes.NewReader(csvBytes))
-	reader.FieldsPerRecord = -1
-	header, err := reader.Read()
+	table, err := schema.ReadFile(path)
 	if err != nil {
-		return nil, nil, err
+		bar.AbortBar()
+		p.Wait()
+		return nil, err
 	}
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, nil, err
+	bar.SetIndeterminateBarComplete()
+	p.Wait()
+
+	if len(table.Ignored) > 0 {
+		fmt.Println(color.YellowString("Ignoring unknown column(s): %s", strings.Join(table.Ignored, ", ")))
 	}
-	return header, records, nil
+	if len(table.Errors) > 0 {
+		fmt.Println(color.YellowString("%d row problem(s) found, these rows will be skipped:", len(table.Errors)))
+		for i, rowErr := range table.Errors {
+			if i == maxPrintedRowErrors {
+				fmt.Printf("  ... and %d more\n", len(table.Errors)-maxPrintedRowErrors)
+				break
+			}
+			fmt.Printf("  %s\n", rowErr.Error())
+		}
+	}
+	return table, nil
 }
 
 // writeCSV combines headers and rows to create a csv file.
@@ -73,3 +92,27 @@ func makeRequest(method, url string, body interface{}) (int, string, error) {
 	}
 	return resp.StatusCode, string(responseBody), nil
 }
+
+// idColumn is set by --column on commands that read a list of ids
+var idColumn string
+
+// addIDColumnFlag adds --column to a command that reads a list of ids
+func addIDColumnFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&idColumn, "column", "", "Read the ids from this column of a file with a header, e.g. id in an export")
+}
+
+// readIDFile reads the ids a delete or void command works on, from a named column when --column is set
+func readIDFile(path string) ([]string, error) {
+	if idColumn != "" {
+		return csvparser.ReadIdColumn(path, idColumn)
+	}
+	return csvparser.ReadIdCSV(path)
+}
+
+// readSchemaIDFile reads ids like readIDFile, for commands whose template has a header: the header skipped is the schema's
+func readSchemaIDFile(path string, schema csvparser.Schema) ([]string, error) {
+	if idColumn != "" {
+		return csvparser.ReadIdColumn(path, idColumn)
+	}
+	return csvparser.ReadIdList(path, schema)
+}
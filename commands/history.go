@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vend/vend-cli/pkg/csvparser"
+	"github.com/vend/vend-cli/pkg/messenger"
+	"github.com/vend/vend-cli/pkg/runlog"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// currentRun is the run log entry for the command being executed, nil for read-only commands
+var currentRun *runlog.Run
+
+// Command config
+var (
+	historyCommand string
+	historyUser    string
+	historySince   string
+	historyLimit   int
+	historyJSON    bool
+
+	historyCmd = &cobra.Command{
+		Use:   "history",
+		Short: "List previous runs",
+		Long: fmt.Sprintf(`
+Lists the runs of commands that change data in a store (deletes, voids, imports and updates).
+
+Every such run is appended to %s in your home directory with the time, your OS user,
+the domain, the command and flags (the token is never stored), the SHA-256 of the input file,
+how many rows were attempted, succeeded and failed, and where the failures were written.
+
+Filter by domain with -d, and by command or OS user with -c and -u.
+
+Example:
+%s`, color.YellowString(".vendcli_history.jsonl"),
+			color.GreenString("vendcli history -d DOMAINPREFIX -c void-sales --since 2024-01-01")),
+		Annotations: map[string]string{offlineAnnotation: "true"},
+		Run: func(cmd *cobra.Command, args []string) {
+			listHistory()
+		},
+	}
+)
+
+func init() {
+	// Flags
+	historyCmd.Flags().StringVarP(&historyCommand, "command", "c", "", "Only show runs of this command")
+	historyCmd.Flags().StringVarP(&historyUser, "user", "u", "", "Only show runs by this OS user")
+	historyCmd.Flags().StringVar(&historySince, "since", "", "Only show runs on or after this date (YYYY-MM-DD)")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 50, "Maximum number of runs to show, 0 for all")
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "Print the matching runs as JSON lines")
+
+	rootCmd.AddCommand(historyCmd)
+}
+
+func listHistory() {
+
+	path, err := runlog.DefaultPath()
+	if err != nil {
+		err = fmt.Errorf("failed to find run log: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	runs, err := runlog.Read(path)
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	var since time.Time
+	if historySince != "" {
+		since, err = time.Parse("2006-01-02", historySince)
+		if err != nil {
+			err = fmt.Errorf("incorrect since date: %v, %v", historySince, err)
+			messenger.ExitWithError(err)
+		}
+	}
+
+	runs = filterRuns(runs, DomainPrefix, historyCommand, historyUser, since)
+	if historyLimit > 0 && len(runs) > historyLimit {
+		runs = runs[len(runs)-historyLimit:]
+	}
+
+	if historyJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, run := range runs {
+			encoder.Encode(run)
+		}
+		return
+	}
+
+	if len(runs) == 0 {
+		fmt.Println(color.YellowString("\nNo runs found"))
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "TIME\tUSER\tDOMAIN\tCOMMAND\tINPUT\tSHA-256\tATTEMPTED\tSUCCEEDED\tFAILED\tSTATUS\tFAILURES")
+	for _, run := range runs {
+		sha := run.InputSHA256
+		if len(sha) > 12 {
+			sha = sha[:12]
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\t%s\n",
+			run.Timestamp.Local().Format("2006-01-02 15:04:05"), run.OSUser, run.Domain, run.Command,
+			run.InputFile, sha, run.Attempted, run.Succeeded, run.Failed, run.Status, run.FailureFile)
+	}
+	writer.Flush()
+}
+
+// filterRuns returns the runs matching all the provided filters, empty filters match everything
+func filterRuns(runs []runlog.Run, domain, command, osUser string, since time.Time) []runlog.Run {
+	var filtered []runlog.Run
+	for _, run := range runs {
+		if domain != "" && !strings.EqualFold(run.Domain, domain) {
+			continue
+		}
+		if command != "" && !strings.EqualFold(run.Command, command) {
+			continue
+		}
+		if osUser != "" && !strings.EqualFold(run.OSUser, osUser) {
+			continue
+		}
+		if !since.IsZero() && run.Timestamp.Before(since) {
+			continue
+		}
+		filtered = append(filtered, run)
+	}
+	return filtered
+}
+
+// startRun captures who is running which command against which store, before anything is posted
+func startRun(cmd *cobra.Command) {
+	run := runlog.Run{
+		Timestamp: time.Now().UTC(),
+		OSUser:    runlog.CurrentUser(),
+		Domain:    DomainPrefix,
+		Command:   cmd.Name(),
+		Flags:     map[string]string{},
+	}
+
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if flag.Name == "Token" {
+			run.Flags[flag.Name] = "[REDACTED]"
+			return
+		}
+		run.Flags[flag.Name] = flag.Value.String()
+	})
+
+	for _, name := range []string{"Filename", "filename"} {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Value.String() == "" {
+			continue
+		}
+		run.InputFile = flag.Value.String()
+		if run.InputFile == csvparser.Stdin {
+			// the command reads the same bytes from csvparser once it starts
+			if raw, err := csvparser.ReadStdin(); err == nil {
+				run.InputFile = "(standard input)"
+				run.InputSHA256 = runlog.SHA256(raw)
+			}
+			continue
+		}
+		if sha, err := runlog.FileSHA256(run.InputFile); err == nil {
+			run.InputSHA256 = sha
+		}
+	}
+
+	currentRun = &run
+}
+
+// finishRun records the outcome of the current run in the run log
+func finishRun(attempted, succeeded int, failureFile string) {
+	if currentRun == nil {
+		return
+	}
+	currentRun.Attempted = attempted
+	currentRun.Succeeded = succeeded
+	currentRun.Failed = attempted - succeeded
+	currentRun.FailureFile = failureFile
+	currentRun.Status = runlog.StatusCompleted
+	writeRun()
+}
+
+// abortRun records a run that exited before it finished
+func abortRun(reason interface{}) {
+	if currentRun == nil {
+		return
+	}
+	currentRun.Status = runlog.StatusAborted
+	if exit, ok := reason.(messenger.Exit); ok && exit.Message != nil {
+		currentRun.Error = exit.Message.Error()
+	} else {
+		currentRun.Error = fmt.Sprint(reason)
+	}
+	writeRun()
+}
+
+// skipRun drops the current run from the run log, for runs that turned out not to change anything
+func skipRun() {
+	currentRun = nil
+}
+
+func writeRun() {
+	path, err := runlog.DefaultPath()
+	if err == nil {
+		err = runlog.Append(path, *currentRun)
+	}
+	if err != nil {
+		fmt.Println(color.YellowString("\nWarning: failed to record this run in the run log: %s", err))
+	}
+	currentRun = nil
+}
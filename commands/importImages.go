@@ -2,11 +2,10 @@ package cmd
 
 import (
 	"crypto/tls"
-	"encoding/csv"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -18,7 +17,6 @@ import (
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/vend/govend/vend"
-	"github.com/wallclockbuilder/stringutil"
 )
 
 type FailedImageUpload struct {
@@ -33,11 +31,12 @@ var importImagesCmd = &cobra.Command{
 	Use:   "import-images",
 	Short: "Import Product Images",
 	Long: fmt.Sprintf(`
-This tool requires the Import Images CSV template, you can download it here: https://bit.ly/vendcli-csv-templates
+This tool requires the Import Images CSV template, get it with "vendcli template import-images"
 
 Example:
 %s`, color.GreenString("vendcli import-images -d DOMAINPREFIX -t TOKEN -f FILENAME.csv")),
 
+	Annotations: map[string]string{mutatingAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		importImages(FilePath)
 	},
@@ -47,6 +46,7 @@ func init() {
 	// Flags
 	importImagesCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv")
 	importImagesCmd.MarkFlagRequired("Filename")
+	addValidateFlag(importImagesCmd)
 
 	rootCmd.AddCommand(importImagesCmd)
 }
@@ -59,6 +59,11 @@ func importImages(FilePath string) {
 	vc := vend.NewClient(Token, DomainPrefix, "")
 	vendClient = &vc
 
+	if validateOnly {
+		validateImageFile(FilePath)
+		return
+	}
+
 	// Read provided CSV file and store product info.
 	fmt.Println("\nReading products from CSV file...")
 	productsFromCSV, err := ReadImageCSV(FilePath)
@@ -82,17 +87,20 @@ func importImages(FilePath string) {
 	fmt.Println("\nGrabbing images and posting to Vend...")
 	uploadedCount := grabAndUploadImage(matchedProducts)
 
+	var failureFile string
 	if len(failedImageUploads) > 0 {
 		fmt.Println(color.RedString("\n\nThere were some errors. Writing failures to csv.."))
-		fileName := fmt.Sprintf("%s_failed_image_upload_requests_%v.csv", DomainPrefix, time.Now().Unix())
-		err := csvparser.WriteErrorCSV(fileName, failedImageUploads)
+		failureFile = fmt.Sprintf("%s_failed_image_upload_requests_%v.csv", DomainPrefix, time.Now().Unix())
+		err := csvparser.WriteErrorCSV(failureFile, failedImageUploads)
 		if err != nil {
+			failureFile = ""
 			fmt.Println(color.RedString("\nFailed to write failures to CSV. Printing failures to console instead."))
 			for _, failure := range failedImageUploads {
 				fmt.Printf("Failed to upload image for SKU: %s, Handle: %s, ImageURL: %s\nReason: %s\n", failure.SKU, failure.Handle, failure.ImageURL, failure.Reason)
 			}
 		}
 	}
+	finishRun(len(productsFromCSV), uploadedCount, failureFile)
 
 	fmt.Printf(color.GreenString("\nFinished! Uploaded %v out of %v products\n"), uploadedCount, len(matchedProducts))
 
@@ -220,115 +228,93 @@ Match:
 	return products, nil
 }
 
-// ReadImageCSV reads the provided CSV file and stores the input as product objects.
-func ReadImageCSV(productFilePath string) ([]vend.ProductUpload, error) {
-	// SKU and Handle combo should be a unique identifier.
-	header := []string{"sku", "handle", "image_url"}
+// validateImageFile checks every handle and SKU pair matches a product and every image URL is a web address,
+// without uploading anything
+func validateImageFile(filePath string) {
+	v := newValidation("import-images")
 
-	p := pbar.CreateSingleBar()
-	bar, err := p.AddIndeterminateProgressBar("Reading CSV")
+	fmt.Println("\nReading products from CSV file...")
+	table, err := readSchemaFile(filePath, imageSchema)
 	if err != nil {
-		err = fmt.Errorf("error creating progress bar:%s", err)
-		return nil, err
+		err = fmt.Errorf("error reading CSV file: %s", err)
+		messenger.ExitWithError(err)
 	}
+	v.addRowErrors(table.Errors)
 
-	done := make(chan struct{})
-	go bar.AnimateIndeterminateBar(done)
-
-	// Open our provided CSV file.
-	file, err := os.Open(productFilePath)
+	products, err := fetchStoreRecords("products", paginateVersion, "id")
 	if err != nil {
-		err = fmt.Errorf(`%s - please check you've specified the right file path.%sTip: make sure you're in the same folder as your file. Use "cd ~/Downloads" to navigate to your Downloads folder`, err, "\n")
-		bar.AbortBar()
-		p.Wait()
-		return []vend.ProductUpload{}, err
+		messenger.ExitWithError(err)
 	}
-	// Make sure to close at end.
-	defer file.Close()
-
-	// Create CSV reader on our file.
-	reader := csv.NewReader(file)
-
-	// Read and store our header line.
-	headerRow, err := reader.Read()
-	if err != nil {
-		bar.AbortBar()
-		p.Wait()
-		return []vend.ProductUpload{}, err
+	// a deleted product never replaces a live one with the same handle and SKU
+	byHandleSKU := storeRecords{}
+	for _, product := range products[0] {
+		key := recordString(product, "handle") + " " + recordString(product, "sku")
+		if existing, ok := byHandleSKU[key]; ok && !isDeletedRecord(existing) {
+			continue
+		}
+		byHandleSKU[key] = product
 	}
 
-	if len(headerRow) > 3 {
-		err = fmt.Errorf("header row longer than expected")
-		bar.AbortBar()
-		p.Wait()
-		return []vend.ProductUpload{}, err
-	}
+	seen := map[string]int{}
+	for _, record := range table.Records {
+		v.rows++
+		handleSKU := record.String("handle") + " " + record.String("sku")
+		v.exists(record.Line, "product with handle and SKU", handleSKU, byHandleSKU)
 
-	// Check each string in the header row is same as our template.
-	for i, row := range headerRow {
-		if stringutil.Strip(strings.ToLower(row)) != header[i] {
-			bar.AbortBar()
-			p.Wait()
-			err = fmt.Errorf("mismatched CSV headers, expecting {sku, handle, image_url}")
-			return []vend.ProductUpload{}, err
+		imageURL := record.String("image_url")
+		if parsed, err := url.Parse(imageURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			v.add(record.Line, imageURL, "image_url is not a http or https URL")
 		}
+		v.unique(record.Line, "image", handleSKU+" "+imageURL, seen)
 	}
+	v.finish()
+}
 
-	// Read the rest of the data from the CSV.
-	rawData, err := reader.ReadAll()
+// imageSchema is the layout of the Product Images CSV template, SKU and handle together identify a product
+var imageSchema = csvparser.Schema{
+	Columns: []csvparser.Column{
+		{Name: "sku", Required: true, Example: "10042",
+			Description: "SKU of the product, together with the handle it identifies the product"},
+		{Name: "handle", Required: true, Example: "classic-tee",
+			Description: "Handle of the product"},
+		{Name: "image_url", Aliases: []string{"image", "url"}, Required: true, Example: "https://example.com/images/classic-tee.jpg",
+			Description: "Web address of the image, it is downloaded and uploaded to the product"},
+	},
+}
+
+// ReadImageCSV reads the provided CSV file and stores the input as product objects.
+func ReadImageCSV(productFilePath string) ([]vend.ProductUpload, error) {
+
+	table, err := readSchemaFile(productFilePath, imageSchema)
 	if err != nil {
-		bar.AbortBar()
-		p.Wait()
 		return []vend.ProductUpload{}, err
 	}
 
-	var product vend.ProductUpload
-	var productList []vend.ProductUpload
-	var rowNumber int
-
-	// Loop through rows and assign them to product.
-	for idx, row := range rawData {
-		rowNumber++
-		product, err = readRow(row)
-		if err != nil {
-			bar.AbortBar()
-			err = fmt.Errorf("error reading row %v from CSV: %s", idx+1, err) // plus one since csvs are 1 indexed
-			return productList, err
-		}
+	for _, rowErr := range table.Errors {
+		failedImageUploads = append(failedImageUploads, FailedImageUpload{
+			SKU:      rowErr.Values["sku"],
+			Handle:   rowErr.Values["handle"],
+			ImageURL: rowErr.Values["image_url"],
+			Reason:   rowErr.Error(),
+		})
+	}
 
-		// Append each product to our list.
-		productList = append(productList, product)
+	var productList []vend.ProductUpload
+	for _, record := range table.Records {
+		productList = append(productList, vend.ProductUpload{
+			SKU:      record.String("sku"),
+			Handle:   record.String("handle"),
+			ImageURL: record.String("image_url"),
+		})
 	}
 
 	// Check how many rows we successfully read and stored.
-	if len(productList) > 0 {
-	} else {
-		bar.AbortBar()
+	if len(productList) == 0 {
 		err = fmt.Errorf("no valid products found")
 		return productList, err
 	}
 
-	bar.SetIndeterminateBarComplete()
-	p.Wait()
-
-	return productList, err
-}
-
-// Read a single row of a CSV file and check for errors.
-func readRow(row []string) (vend.ProductUpload, error) {
-	var product vend.ProductUpload
-
-	product.SKU = row[0]
-	product.Handle = row[1]
-	product.ImageURL = row[2]
-
-	for i := range row {
-		if len(row[i]) < 1 {
-			err := errors.New("missing field")
-			return product, err
-		}
-	}
-	return product, nil
+	return productList, nil
 }
 
 // Grab downloads a product image and writes it to a file.
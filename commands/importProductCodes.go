@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/vend/vend-cli/pkg/csvparser"
 	"github.com/vend/vend-cli/pkg/messenger"
 	pbar "github.com/vend/vend-cli/pkg/progressbar"
 
@@ -43,10 +44,11 @@ var importProductCodesCmd = &cobra.Command{
 	Use:   "import-product-codes",
 	Short: "Import Product Codes",
 	Long: fmt.Sprintf(`
-This tool requires the Product Codes CSV template, you can download it here: https://bit.ly/vendcli-csv-templates
+This tool requires the Product Codes CSV template, get it with "vendcli template import-product-codes"
 Example:
 %s`, color.GreenString("vendcli import-product-codes -d DOMAINPREFIX -t TOKEN -f FILENAME.csv")),
 
+	Annotations: map[string]string{mutatingAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		importProductCodes()
 	},
@@ -56,6 +58,7 @@ func init() {
 	// Flags
 	importProductCodesCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv")
 	importProductCodesCmd.MarkFlagRequired("Filename")
+	addValidateFlag(importProductCodesCmd)
 
 	rootCmd.AddCommand(importProductCodesCmd)
 }
@@ -65,6 +68,11 @@ func importProductCodes() {
 	vc := vend.NewClient(Token, DomainPrefix, "")
 	vendClient = &vc
 
+	if validateOnly {
+		validateProductCodesFile(FilePath)
+		return
+	}
+
 	// Read Product Codes from CSV file
 	fmt.Println("\nReading product codes CSV...")
 	productCodes, err := readProductCodesCSV(FilePath)
@@ -82,55 +90,52 @@ func importProductCodes() {
 	}
 }
 
+// productCodeSchema is the layout of the Product Codes CSV template, every column after product_id is a code type
+var productCodeSchema = csvparser.Schema{
+	Columns: []csvparser.Column{
+		{Name: "product_id", Aliases: []string{"id"}, Required: true, Example: "0adfd74a-153e-11e9-fa42-67b5781ba1fb",
+			Description: "ID of the product the codes are added to"},
+	},
+	KeepExtra: true,
+}
+
 // Read passed CSV, returns a slice of product codes add instructions.
 func readProductCodesCSV(filePath string) ([]ProductCodeAdd, error) {
-	p := pbar.CreateSingleBar()
-	bar, err := p.AddIndeterminateProgressBar("Reading CSV")
-	if err != nil {
-		fmt.Printf("Error creating progress bar:%s\n", err)
-	}
 
-	header, records, err := loadRecordsFromCSV(filePath)
+	table, err := readSchemaFile(filePath, productCodeSchema)
 	if err != nil {
-		bar.AbortBar()
 		return nil, err
 	}
 
-	done := make(chan struct{})
-	go bar.AnimateIndeterminateBar(done)
-
 	// Ensure valid header fields have been provided
-	err = validateHeader(header)
+	err = validateHeader(table.Extra)
 	if err != nil {
-		bar.AbortBar()
-		p.Wait()
 		fmt.Println("Header validation failed")
 		return nil, err
 	}
+	if len(table.Errors) > 0 {
+		return nil, fmt.Errorf("%d row(s) have problems, fix them and try again", len(table.Errors))
+	}
 
 	// Ensure there are no duplicate product codes
-	err = validateProductCodeUniqueness(records)
+	err = validateProductCodeUniqueness(table)
 	if err != nil {
-		bar.AbortBar()
-		p.Wait()
 		err = fmt.Errorf("uniqueness validation failed! All product codes must be unique across the product catalogue. %w", err)
 		return nil, err
 	}
 
 	var prodCodes []ProductCodeAdd
 
-	for _, row := range records {
-		productId := row[0]
-		// Start at column 1, column 0 is always product_id
-		for c := 1; c < len(row); c++ {
-			pCode := row[c]
+	for _, record := range table.Records {
+		for _, codeType := range table.Extra {
+			pCode := record.Extra(codeType)
 			// Only add codes where a value was provided.
 			if pCode != "" {
 				prodCodes = append(prodCodes, ProductCodeAdd{
 					Action:    AddCodeAction,
-					ProductID: productId,
+					ProductID: record.String("product_id"),
 					Data: ProductCode{
-						Type: header[c],
+						Type: codeType,
 						Code: pCode,
 					},
 				})
@@ -138,41 +143,89 @@ func readProductCodesCSV(filePath string) ([]ProductCodeAdd, error) {
 		}
 	}
 
-	bar.SetIndeterminateBarComplete()
-	p.Wait()
-
-	return prodCodes, err
+	return prodCodes, nil
 }
 
-func validateHeader(header []string) error {
-	if len(header) < 2 {
+// validateHeader makes sure there is at least one code type column next to product_id
+func validateHeader(codeTypes []string) error {
+	if len(codeTypes) < 1 {
 		return errors.New("incomplete data, expecting at least one product code")
 	}
-	if header[0] != "product_id" {
-		return errors.New("missing product_id column")
-	}
 	return nil
 }
 
-func validateProductCodeUniqueness(records [][]string) error {
-	codes := make(map[string]interface{})
-	for idx, row := range records {
-		// Start at column 1, column 0 is always product_id
-		for c := 1; c < len(row); c++ {
-			pCode := row[c]
+func validateProductCodeUniqueness(table *csvparser.Table) error {
+	codes := make(map[string]int)
+	for _, record := range table.Records {
+		for _, codeType := range table.Extra {
+			pCode := record.Extra(codeType)
 			if pCode == "" {
 				continue
 			}
-			if _, ok := codes[pCode]; ok {
-				return fmt.Errorf("duplicate code: %s on row %v", pCode, idx+1) // csv is 1-based index
-			} else {
-				codes[pCode] = nil
+			if line, ok := codes[pCode]; ok {
+				return fmt.Errorf("duplicate code: %s on line %d, first seen on line %d", pCode, record.Line, line)
 			}
+			codes[pCode] = record.Line
 		}
 	}
 	return nil
 }
 
+// validateProductCodesFile checks every product exists and every code is unique in the file and the catalogue,
+// without adding any codes
+func validateProductCodesFile(filePath string) {
+	v := newValidation("import-product-codes")
+
+	fmt.Println("\nReading product codes CSV...")
+	table, err := readSchemaFile(filePath, productCodeSchema)
+	if err == nil {
+		err = validateHeader(table.Extra)
+	}
+	if err != nil {
+		err = fmt.Errorf("couldnt read Product Code CSV file, %s", err)
+		messenger.ExitWithError(err)
+	}
+	v.addRowErrors(table.Errors)
+
+	products, err := fetchStoreRecords("products", paginateVersion, "id")
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	// codes already used in the catalogue, by the product using them
+	catalogueCodes := map[string]string{}
+	for id, product := range products[0] {
+		if isDeletedRecord(product) {
+			continue
+		}
+		codes, _ := product["product_codes"].([]interface{})
+		for _, code := range codes {
+			if code, ok := code.(map[string]interface{}); ok {
+				catalogueCodes[recordString(code, "code")] = id
+			}
+		}
+	}
+
+	seen := map[string]int{}
+	for _, record := range table.Records {
+		v.rows++
+		productID := record.String("product_id")
+		v.exists(record.Line, "product", productID, products[0])
+
+		for _, codeType := range table.Extra {
+			code := record.Extra(codeType)
+			if code == "" {
+				continue
+			}
+			v.unique(record.Line, "code", code, seen)
+			if owner, ok := catalogueCodes[code]; ok && owner != productID {
+				v.add(record.Line, code, "code is already used by product %s", owner)
+			}
+		}
+	}
+	v.finish()
+}
+
 // Post product codes to Vend
 func postProductCodes(productCodes []ProductCodeAdd) error {
 	var err error
@@ -219,6 +272,11 @@ func postProductCodes(productCodes []ProductCodeAdd) error {
 	}
 	p.Wait()
 
+	failedCount := 0
+	for _, failures := range failedProductCodes {
+		failedCount += len(failures.ProductCodes)
+	}
+
 	// If any codes failed, export them
 	if len(failedProductCodes) > 0 {
 		filename, err := writeOutput(failedProductCodes)
@@ -226,9 +284,11 @@ func postProductCodes(productCodes []ProductCodeAdd) error {
 			fmt.Printf("\nUnsuccesssful! Failed to write ouput for %d Product Codes", len(failedProductCodes))
 			return err
 		}
+		finishRun(totalProducts, totalProducts-failedCount, filename)
 		fmt.Println(color.GreenString("\nFinished! 🎉"))
 		fmt.Println(color.RedString("Partially successful, %d batches failed. Please check %s file for the failed batches.", len(failedProductCodes), filename))
 	} else {
+		finishRun(totalProducts, totalProducts, "")
 		fmt.Println(color.GreenString("\nFinished! 🎉 Succesfully created %d Product Codes", len(productCodes)))
 	}
 
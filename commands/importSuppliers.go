@@ -1,9 +1,8 @@
 package cmd
 
 import (
-	"encoding/csv"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/vend/vend-cli/pkg/csvparser"
@@ -25,11 +24,12 @@ var importSuppliersCmd = &cobra.Command{
 	Use:   "import-suppliers",
 	Short: "Import Suppliers",
 	Long: fmt.Sprintf(`
-This tool requires the Supplier CSV template, you can download it here: https://bit.ly/vendcli-csv-templates
+This tool requires the Supplier CSV template, get it with "vendcli template import-suppliers"
 
 Example:
 %s`, color.GreenString("vendcli import-suppliers -d DOMAINPREFIX -t TOKEN -f FILENAME.csv")),
 
+	Annotations: map[string]string{mutatingAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		importSuppliers()
 	},
@@ -41,6 +41,7 @@ func init() {
 	// Flags
 	importSuppliersCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv")
 	importSuppliersCmd.MarkFlagRequired("Filename")
+	addValidateFlag(importSuppliersCmd)
 
 	rootCmd.AddCommand(importSuppliersCmd)
 }
@@ -51,6 +52,11 @@ func importSuppliers() {
 	vc := vend.NewClient(Token, DomainPrefix, "")
 	vendClient = &vc
 
+	if validateOnly {
+		validateSupplierFile(FilePath)
+		return
+	}
+
 	// Read Suppliers from CSV file
 	fmt.Println("\nReading Supplier CSV...")
 	suppliers, err := readSupplierCSV(FilePath)
@@ -67,120 +73,140 @@ func importSuppliers() {
 		messenger.ExitWithError(err)
 	}
 
+	var failureFile string
 	if len(failedSupplierImportRequests) > 0 {
 		fmt.Println(color.RedString("\nThere were some errors. Writing failures to csv.."))
-		fileName := fmt.Sprintf("%s_failed_import_suppliers_requests_%v.csv", DomainPrefix, time.Now().Unix())
-		err := csvparser.WriteErrorCSV(fileName, failedSupplierImportRequests)
+		failureFile = fmt.Sprintf("%s_failed_import_suppliers_requests_%v.csv", DomainPrefix, time.Now().Unix())
+		err := csvparser.WriteErrorCSV(failureFile, failedSupplierImportRequests)
 		if err != nil {
 			messenger.ExitWithError(err)
 			return
 		}
 	}
+	finishRun(count+len(failedSupplierImportRequests), count, failureFile)
 	fmt.Println(color.GreenString("\nFinished!🎉\nImported %d out of %d suppliers\n", count, len(suppliers)))
 }
 
-// Read passed CSV, returns a slice of suppliers
-func readSupplierCSV(filePath string) ([]vend.SupplierBase, error) {
+// supplierSchema is the layout of the Supplier CSV template
+var supplierSchema = csvparser.Schema{
+	Columns: []csvparser.Column{
+		{Name: "name", Aliases: []string{"supplier", "supplier_name"}, Required: true, Example: "Acme Apparel", Description: "Supplier name, must be unique"},
+		{Name: "description", Example: "Tees and hoodies", Description: "Notes about the supplier"},
+		{Name: "first_name", Example: "Jo", Description: "First name of the contact"},
+		{Name: "last_name", Example: "Bloggs", Description: "Last name of the contact"},
+		{Name: "company_name", Aliases: []string{"company"}, Example: "Acme Apparel Ltd", Description: "Registered company name"},
+		{Name: "phone", Example: "+64 9 555 0100", Description: "Phone number"},
+		{Name: "mobile", Example: "+64 21 555 0100", Description: "Mobile number"},
+		{Name: "fax", Description: "Fax number"},
+		{Name: "email", Aliases: []string{"email_address"}, Example: "orders@example.com", Description: "Email address"},
+		{Name: "twitter", Description: "Twitter handle"},
+		{Name: "website", Example: "https://example.com", Description: "Website"},
+		{Name: "physical_address1", Aliases: []string{"physical_address_1"}, Example: "1 Queen Street", Description: "Street address, first line"},
+		{Name: "physical_address2", Aliases: []string{"physical_address_2"}, Description: "Street address, second line"},
+		{Name: "physical_suburb", Example: "CBD", Description: "Street address suburb"},
+		{Name: "physical_city", Example: "Auckland", Description: "Street address city"},
+		{Name: "physical_postcode", Example: "1010", Description: "Street address postcode"},
+		{Name: "physical_state", Description: "Street address state"},
+		{Name: "physical_country_id", Aliases: []string{"physical_country"}, Example: "NZ", Description: "Street address country, as a two letter code"},
+		{Name: "postal_address1", Aliases: []string{"postal_address_1"}, Example: "PO Box 42", Description: "Postal address, first line"},
+		{Name: "postal_address2", Aliases: []string{"postal_address_2"}, Description: "Postal address, second line"},
+		{Name: "postal_suburb", Description: "Postal address suburb"},
+		{Name: "postal_city", Example: "Auckland", Description: "Postal address city"},
+		{Name: "postal_postcode", Example: "1140", Description: "Postal address postcode"},
+		{Name: "postal_state", Description: "Postal address state"},
+		{Name: "postal_country_id", Aliases: []string{"postal_country"}, Example: "NZ", Description: "Postal address country, as a two letter code"},
+	},
+}
 
-	headers := []string{"name", "description", "first_name", "last_name", "company_name",
-		"phone", "mobile", "fax", "email", "twitter", "website", "physical_address1",
-		"physical_address2", "physical_suburb", "physical_city",
-		"physical_postcode", "physical_state", "physical_country_id",
-		"postal_address1", "postal_address2", "postal_suburb", "postal_city",
-		"postal_postcode", "postal_state", "postal_country_id"}
+// validateSupplierFile checks no supplier in the file already exists or is listed twice, without importing anything
+func validateSupplierFile(filePath string) {
+	v := newValidation("import-suppliers")
 
-	p := pbar.CreateSingleBar()
-	bar, err := p.AddIndeterminateProgressBar("Reading CSV")
+	fmt.Println("\nReading Supplier CSV...")
+	table, err := readSchemaFile(filePath, supplierSchema)
 	if err != nil {
-		err = fmt.Errorf("error creating progress bar:%s", err)
-		return nil, err
+		err = fmt.Errorf("couldnt read Supplier CSV file, %s", err)
+		messenger.ExitWithError(err)
 	}
+	v.addRowErrors(table.Errors)
 
-	done := make(chan struct{})
-	go bar.AnimateIndeterminateBar(done)
-
-	// Open our provided CSV file.
-	file, err := os.Open(filePath)
+	fmt.Println("\nFetching suppliers...")
+	suppliers, err := vendClient.Suppliers()
 	if err != nil {
-		bar.AbortBar()
-		p.Wait()
-		err = fmt.Errorf(`%s - please check you've specified the right file path.%sTip: make sure you're in the same folder as your file. Use "cd ~/Downloads" to navigate to your Downloads folder`, err, "\n")
-		return nil, err
+		err = fmt.Errorf("failed to fetch suppliers: %w", err)
+		messenger.ExitWithError(err)
 	}
-	// Make sure to close at end.
-	defer file.Close()
-
-	// Create CSV reader on our file.
-	reader := csv.NewReader(file)
-
-	// Read and store our header line.
-	headerRow, err := reader.Read()
-	if err != nil {
-		bar.AbortBar()
-		p.Wait()
-		return nil, err
+	existing := map[string]bool{}
+	for _, supplier := range suppliers {
+		if supplier.Name != nil {
+			existing[strings.ToLower(*supplier.Name)] = true
+		}
 	}
 
-	// Check each header in the row is same as our template.
-	for i := range headerRow {
-		if headerRow[i] != headers[i] {
-			bar.AbortBar()
-			p.Wait()
-			err = fmt.Errorf("found error in header rows. No header match for: %s Instead got: %s",
-				string(headers[i]), string(headerRow[i]))
-			return nil, err
+	// supplier names are unique regardless of case
+	seen := map[string]int{}
+	for _, record := range table.Records {
+		v.rows++
+		name := record.String("name")
+		if existing[strings.ToLower(name)] {
+			v.add(record.Line, name, "supplier already exists")
 		}
+		v.unique(record.Line, "supplier", strings.ToLower(name), seen)
 	}
+	v.finish()
+}
+
+// Read passed CSV, returns a slice of suppliers
+func readSupplierCSV(filePath string) ([]vend.SupplierBase, error) {
 
-	// Read the rest of the data from the CSV.
-	rawData, err := reader.ReadAll()
+	table, err := readSchemaFile(filePath, supplierSchema)
 	if err != nil {
-		bar.AbortBar()
-		p.Wait()
 		return nil, err
 	}
 
+	for _, rowErr := range table.Errors {
+		failedSupplierImportRequests = append(failedSupplierImportRequests, FailedSupplierImportRequest{
+			Name:   fmt.Sprintf("Line %d", rowErr.Line),
+			Reason: rowErr.Error(),
+		})
+	}
+
 	var suppliers []vend.SupplierBase
 
 	// Loop through rows and assign them to supplier type.
-	for idx, row := range rawData {
-		// Check if supplier name is empty
-		if row[0] == "" {
-			reason := "supplier name is empty"
-			failedSupplierImportRequests = append(failedSupplierImportRequests, FailedSupplierImportRequest{
-				Name:   fmt.Sprintf("Row %d", idx+1), // csvs are 1-indexed
-				Reason: reason,
-			})
-			continue
+	for _, record := range table.Records {
+		value := func(column string) *string {
+			v := record.String(column)
+			return &v
 		}
 
 		supplier := vend.SupplierBase{
-			Name:        &row[0],
-			Description: &row[1],
+			Name:        value("name"),
+			Description: value("description"),
 			Contact: &vend.Contact{
-				FirstName:         &row[2],
-				LastName:          &row[3],
-				CompanyName:       &row[4],
-				Phone:             &row[5],
-				Mobile:            &row[6],
-				Fax:               &row[7],
-				Email:             &row[8],
-				Twitter:           &row[9],
-				Website:           &row[10],
-				PhysicalAddress1:  &row[11],
-				PhysicalAddress2:  &row[12],
-				PhysicalSuburb:    &row[13],
-				PhysicalCity:      &row[14],
-				PhysicalPostcode:  &row[15],
-				PhysicalState:     &row[16],
-				PhysicalCountryID: &row[17],
-				PostalAddress1:    &row[18],
-				PostalAddress2:    &row[19],
-				PostalSuburb:      &row[20],
-				PostalCity:        &row[21],
-				PostalPostcode:    &row[22],
-				PostalState:       &row[23],
-				PostalCountryID:   &row[24],
+				FirstName:         value("first_name"),
+				LastName:          value("last_name"),
+				CompanyName:       value("company_name"),
+				Phone:             value("phone"),
+				Mobile:            value("mobile"),
+				Fax:               value("fax"),
+				Email:             value("email"),
+				Twitter:           value("twitter"),
+				Website:           value("website"),
+				PhysicalAddress1:  value("physical_address1"),
+				PhysicalAddress2:  value("physical_address2"),
+				PhysicalSuburb:    value("physical_suburb"),
+				PhysicalCity:      value("physical_city"),
+				PhysicalPostcode:  value("physical_postcode"),
+				PhysicalState:     value("physical_state"),
+				PhysicalCountryID: value("physical_country_id"),
+				PostalAddress1:    value("postal_address1"),
+				PostalAddress2:    value("postal_address2"),
+				PostalSuburb:      value("postal_suburb"),
+				PostalCity:        value("postal_city"),
+				PostalPostcode:    value("postal_postcode"),
+				PostalState:       value("postal_state"),
+				PostalCountryID:   value("postal_country_id"),
 			},
 		}
 
@@ -188,10 +214,7 @@ func readSupplierCSV(filePath string) ([]vend.SupplierBase, error) {
 		suppliers = append(suppliers, supplier)
 	}
 
-	bar.SetIndeterminateBarComplete()
-	p.Wait()
-
-	return suppliers, err
+	return suppliers, nil
 }
 
 // Post each Supplier to Vend
@@ -1,9 +1,7 @@
 package cmd
 
 import (
-	"encoding/csv"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/vend/vend-cli/pkg/csvparser"
@@ -26,11 +24,12 @@ var loyaltyAdjustmentCmd = &cobra.Command{
 	Use:   "loyalty-adjustment",
 	Short: "Customer Loyalty Adjustment",
 	Long: fmt.Sprintf(`
-This tool requires the Customer Loyalty Adjustment CSV template, you can download it here: https://bit.ly/vendcli-csv-templates
+This tool requires the Customer Loyalty Adjustment CSV template, get it with "vendcli template loyalty-adjustment"
 
 Example:
 %s`, color.GreenString("vendcli loyalty-adjustment -d DOMAINPREFIX -t TOKEN -f FILENAME.csv")),
 
+	Annotations: map[string]string{mutatingAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		loyaltyAdjustment()
 	},
@@ -40,6 +39,7 @@ func init() {
 	// Flag
 	loyaltyAdjustmentCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv")
 	loyaltyAdjustmentCmd.MarkFlagRequired("Filename")
+	addValidateFlag(loyaltyAdjustmentCmd)
 
 	rootCmd.AddCommand(loyaltyAdjustmentCmd)
 }
@@ -48,6 +48,11 @@ var failedLoyaltyAdjustments []FailedLoyaltyAdjustment
 
 func loyaltyAdjustment() {
 
+	if validateOnly {
+		validateLoyaltyAdjustmentFile(FilePath)
+		return
+	}
+
 	// Read Loyalty Adjustemtns from CSV file
 	fmt.Println("\nReading Loyalty Adjustment CSV...")
 	loyaltyAdjustments, err := readLoyaltyAdjustmentCSV(FilePath)
@@ -60,15 +65,17 @@ func loyaltyAdjustment() {
 	fmt.Println("\nPosting Loyalty Adjustments to Vend...")
 	count := postLloyaltyAdjustments(loyaltyAdjustments)
 
+	var failureFile string
 	if len(failedLoyaltyAdjustments) > 0 {
 		fmt.Println(color.RedString("\nThere were some errors. Writing failures to csv.."))
-		fileName := fmt.Sprintf("%s_failed_loyalty_adjustment_requests_%v.csv", DomainPrefix, time.Now().Unix())
-		err := csvparser.WriteErrorCSV(fileName, failedLoyaltyAdjustments)
+		failureFile = fmt.Sprintf("%s_failed_loyalty_adjustment_requests_%v.csv", DomainPrefix, time.Now().Unix())
+		err := csvparser.WriteErrorCSV(failureFile, failedLoyaltyAdjustments)
 		if err != nil {
 			err = fmt.Errorf("couldnt write failed Loyalty Adjustments to CSV file: %s", err)
 			messenger.ExitWithError(err)
 		}
 	}
+	finishRun(count+len(failedLoyaltyAdjustments), count, failureFile)
 
 	fmt.Println(color.GreenString("\n\nFinished! 🎉\nSuccesfully adjusted %d of %d Customer Loyalty Balances", count, len(loyaltyAdjustments)))
 
@@ -107,87 +114,76 @@ func postLloyaltyAdjustments(loyaltyAdjustments []vend.Customer) int {
 	return count
 }
 
-// Read passed CSV, returns a slice of Loyalty Adjustments
-func readLoyaltyAdjustmentCSV(filePath string) ([]vend.Customer, error) {
+// loyaltyAdjustmentSchema is the layout of the Loyalty Adjustment CSV template
+var loyaltyAdjustmentSchema = csvparser.Schema{
+	Columns: []csvparser.Column{
+		{Name: "customer_id", Aliases: []string{"id", "customer"}, Required: true, Example: "0adfd74a-153e-11e9-fa42-67b5781ba1fb",
+			Description: "ID of the customer"},
+		{Name: "amount", Aliases: []string{"loyalty", "loyalty_adjustment", "adjustment"}, Type: csvparser.Number, Required: true, Example: "-5.50",
+			Description: "Loyalty to add to the balance, negative to take loyalty away"},
+	},
+}
 
-	headers := []string{"customer_id", "amount"}
+// validateLoyaltyAdjustmentFile checks every customer in the file exists, has loyalty enabled and appears once
+func validateLoyaltyAdjustmentFile(filePath string) {
+	v := newValidation("loyalty-adjustment")
 
-	p := pbar.CreateSingleBar()
-	bar, err := p.AddIndeterminateProgressBar("Reading CSV")
+	fmt.Println("\nReading Loyalty Adjustment CSV...")
+	table, err := readSchemaFile(filePath, loyaltyAdjustmentSchema)
 	if err != nil {
-		err = fmt.Errorf("error creating progress bar:%s", err)
-		return nil, err
+		err = fmt.Errorf("couldnt read Loyalty Adjustment CSV file: %s", err)
+		messenger.ExitWithError(err)
 	}
+	v.addRowErrors(table.Errors)
 
-	done := make(chan struct{})
-	go bar.AnimateIndeterminateBar(done)
-
-	// Open our provided CSV file
-	file, err := os.Open(filePath)
+	customers, err := fetchStoreRecords("customers", paginateVersion, "id")
 	if err != nil {
-		bar.AbortBar()
-		p.Wait()
-		err = fmt.Errorf(`%s - please check you've specified the right file path.%sTip: make sure you're in the same folder as your file. Use "cd ~/Downloads" to navigate to your Downloads folder`, err, "\n")
-		return nil, err
+		messenger.ExitWithError(err)
 	}
-	// Make sure to close at end
-	defer file.Close()
 
-	// Create CSV reader on our file
-	reader := csv.NewReader(file)
+	seen := map[string]int{}
+	for _, record := range table.Records {
+		v.rows++
+		customerID := record.String("customer_id")
+		customer, ok := v.exists(record.Line, "customer", customerID, customers[0])
+		if !ok {
+			continue
+		}
+		if enabled, found := customer["enable_loyalty"].(bool); found && !enabled {
+			v.add(record.Line, customerID, "loyalty is not enabled for this customer")
+		}
+		v.unique(record.Line, "customer", customerID, seen)
+	}
+	v.finish()
+}
 
-	var loyaltyAdjustments []vend.Customer
+// Read passed CSV, returns a slice of Loyalty Adjustments
+func readLoyaltyAdjustmentCSV(filePath string) ([]vend.Customer, error) {
 
-	// Read and store our header line.
-	headerRow, err := reader.Read()
+	table, err := readSchemaFile(filePath, loyaltyAdjustmentSchema)
 	if err != nil {
-		bar.AbortBar()
-		p.Wait()
 		return nil, err
 	}
 
-	// Check each header in the row is same as our template.
-	for i := range headerRow {
-		if headerRow[i] != headers[i] {
-			bar.AbortBar()
-			p.Wait()
-			err = fmt.Errorf("found error in hearder rows. No header match for: %s Instead got: %s",
-				string(headers[i]), string(headerRow[i]))
-			messenger.ExitWithError(err)
-
-		}
+	for _, rowErr := range table.Errors {
+		failedLoyaltyAdjustments = append(failedLoyaltyAdjustments, FailedLoyaltyAdjustment{
+			CustomerID: rowErr.Values["customer_id"],
+			Amount:     rowErr.Values["amount"],
+			Reason:     rowErr.Error(),
+		})
 	}
 
-	// Read the rest of the data from the CSV
-	rawData, err := reader.ReadAll()
-	if err != nil {
-		bar.AbortBar()
-		p.Wait()
-		return nil, err
-	}
+	var loyaltyAdjustments []vend.Customer
 
 	// Loop through rows and assign them to the Loyalty Adjustment type.
-	for _, row := range rawData {
-		// skip if its missing data
-		if row[0] == "" || row[1] == "" {
-			reason := "missing fields"
-			failedLoyaltyAdjustments = append(failedLoyaltyAdjustments, FailedLoyaltyAdjustment{
-				CustomerID: row[0],
-				Amount:     row[1],
-				Reason:     reason,
-			})
-			continue
-		}
-
-		loyaltyAdjustment := vend.Customer{
-			ID:                &row[0],
-			LoyaltyAdjustment: &row[1],
-		}
-		loyaltyAdjustments = append(loyaltyAdjustments, loyaltyAdjustment)
+	for _, record := range table.Records {
+		customerID := record.String("customer_id")
+		amount := record.String("amount")
+		loyaltyAdjustments = append(loyaltyAdjustments, vend.Customer{
+			ID:                &customerID,
+			LoyaltyAdjustment: &amount,
+		})
 	}
 
-	bar.SetIndeterminateBarComplete()
-	p.Wait()
-
-	return loyaltyAdjustments, err
+	return loyaltyAdjustments, nil
 }
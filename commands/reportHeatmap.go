@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vend/vend-cli/pkg/messenger"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vend/govend/vend"
+)
+
+// Command config
+var (
+	heatmapFilters salesFilterFlags
+	heatmapMetric  string
+	heatmapPrint   string
+
+	reportHeatmapCmd = &cobra.Command{
+		Use:   "report-heatmap",
+		Short: "Report Sales by Hour and Weekday",
+		Long: fmt.Sprintf(`
+Adds up the sales of each outlet by day of the week and hour of the day, in the store's timezone, for planning
+rosters. The metric is the number of sales (default), revenue excluding tax, or items sold.
+
+The heatmap is written as a CSV matrix, and as a self-contained HTML page (default) or Markdown with --print.
+
+Example:
+%s`, color.GreenString("vendcli report-heatmap -d DOMAINPREFIX -t TOKEN -z TIMEZONE -F DATEFROM -T DATETO --metric revenue")),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			reportHeatmap()
+		},
+	}
+)
+
+// heatmapMetrics are what a heatmap can count
+var heatmapMetrics = []string{"sales", "revenue", "items"}
+
+// heatmapDays are the days of the week from Monday, as the rows of a heatmap
+var heatmapDays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+
+func init() {
+	// Flags
+	addSalesRangeFlags(reportHeatmapCmd, &heatmapFilters)
+	reportHeatmapCmd.Flags().StringVar(&heatmapMetric, "metric", "sales", fmt.Sprintf("What to add up: %s", strings.Join(heatmapMetrics, ", ")))
+	reportHeatmapCmd.Flags().StringVar(&heatmapPrint, "print", "html", "Also write the heatmap as: html, markdown, none")
+
+	rootCmd.AddCommand(reportHeatmapCmd)
+}
+
+// heatmap is the metric of an outlet by day of the week and hour
+type heatmap struct {
+	Outlet string
+	Cells  [7][24]float64
+}
+
+func reportHeatmap() {
+	heatmapMetric = strings.ToLower(heatmapMetric)
+	if !containsString(heatmapMetrics, heatmapMetric) {
+		err := fmt.Errorf("'%s' is not a valid option for --metric, use %s", heatmapMetric, strings.Join(heatmapMetrics, ", "))
+		messenger.ExitWithError(err)
+	}
+	if err := checkReportFormat(heatmapPrint); err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	vc := vend.NewClient(Token, DomainPrefix, timeZone)
+	fmt.Println("Creating Sales Heatmap...")
+	sales, data := fetchReportSales(vc, heatmapFilters, false)
+
+	heatmaps := buildHeatmaps(sales, data, heatmapMetric, timeZone)
+	table := heatmapTable(heatmaps, heatmapMetric)
+
+	fileName := fmt.Sprintf("%s_sales_heatmap_f%s_t%s.csv", DomainPrefix, dateFrom, dateTo)
+	format := heatmapPrint
+	if strings.EqualFold(format, "html") {
+		// the HTML is coloured by value, so it is written here rather than as a plain table
+		format = "none"
+	}
+	files, err := table.write(fileName, format)
+	if err == nil && strings.EqualFold(heatmapPrint, "html") {
+		htmlFile := strings.TrimSuffix(fileName, ".csv") + ".html"
+		err = writeHeatmapHTML(htmlFile, table.Title, table.Notes, heatmaps, heatmapMetric)
+		files = append(files, htmlFile)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to write sales heatmap: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	fmt.Println(color.GreenString("\n\nFinished!🎉\nMapped %d sales: %s", len(sales), strings.Join(files, ", ")))
+}
+
+// buildHeatmaps adds up the sales of each outlet by weekday and hour, in outlet order. With more than one outlet,
+// a heatmap of every outlet together comes last.
+func buildHeatmaps(sales []vend.Sale, data salesReportData, metric string, timeZone string) []*heatmap {
+	var heatmaps []*heatmap
+	byOutlet := map[string]*heatmap{}
+	all := &heatmap{Outlet: "All Outlets"}
+
+	for _, sale := range sales {
+		saleDate, err := vend.ParseVendDT(*sale.SaleDate, timeZone)
+		if err != nil {
+			fmt.Printf("Error parsing date: %s\n", err)
+			continue
+		}
+		var outlet string
+		if sale.OutletID != nil {
+			outlet = data.Outlets[*sale.OutletID]
+		}
+		h, ok := byOutlet[outlet]
+		if !ok {
+			h = &heatmap{Outlet: outlet}
+			byOutlet[outlet] = h
+			heatmaps = append(heatmaps, h)
+		}
+
+		value := heatmapValue(sale, metric)
+		// Monday is the first row
+		day := (int(saleDate.Weekday()) + 6) % 7
+		h.Cells[day][saleDate.Hour()] += value
+		all.Cells[day][saleDate.Hour()] += value
+	}
+
+	sort.SliceStable(heatmaps, func(i, j int) bool {
+		return heatmaps[i].Outlet < heatmaps[j].Outlet
+	})
+	if len(heatmaps) > 1 {
+		heatmaps = append(heatmaps, all)
+	}
+	return heatmaps
+}
+
+// heatmapValue is what a sale adds to its cell
+func heatmapValue(sale vend.Sale, metric string) float64 {
+	if metric == "sales" {
+		return 1
+	}
+	var value float64
+	if sale.LineItems != nil {
+		for _, lineitem := range *sale.LineItems {
+			if lineitem.Quantity == nil {
+				continue
+			}
+			switch {
+			case metric == "items":
+				value += *lineitem.Quantity
+			case lineitem.Price != nil:
+				value += *lineitem.Price * *lineitem.Quantity
+			}
+		}
+	}
+	return value
+}
+
+// max is the largest cell of the heatmap
+func (h heatmap) max() float64 {
+	var max float64
+	for _, hours := range h.Cells {
+		for _, value := range hours {
+			if value > max {
+				max = value
+			}
+		}
+	}
+	return max
+}
+
+func formatHeatmapValue(value float64, metric string) string {
+	if metric == "revenue" {
+		return formatCents(value)
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// heatmapTable lays the heatmaps out as a matrix of weekdays by hour, one block of rows per outlet
+func heatmapTable(heatmaps []*heatmap, metric string) reportTable {
+	header := []string{"Outlet", "Day"}
+	for hour := 0; hour < 24; hour++ {
+		header = append(header, fmt.Sprintf("%02d:00", hour))
+	}
+	header = append(header, "Total")
+
+	var rows [][]string
+	for _, h := range heatmaps {
+		for day, hours := range h.Cells {
+			row := []string{h.Outlet, heatmapDays[day].String()}
+			var total float64
+			for _, value := range hours {
+				row = append(row, formatHeatmapValue(value, metric))
+				total += value
+			}
+			rows = append(rows, append(row, formatHeatmapValue(total, metric)))
+		}
+	}
+
+	return reportTable{
+		Title: fmt.Sprintf("Sales Heatmap %s", DomainPrefix),
+		Notes: []string{
+			fmt.Sprintf("%s to %s, %s", dateFrom, dateTo, timeZone),
+			fmt.Sprintf("Metric: %s", metric),
+		},
+		Header:      header,
+		Rows:        rows,
+		NumericFrom: 2,
+	}
+}
+
+// heatmapCell is a cell of the HTML heatmap
+type heatmapCell struct {
+	Value string
+	Style template.CSS
+}
+
+var heatmapHTMLTemplate = template.Must(template.New("heatmap").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+h2 { font-size: 1.1em; margin-top: 2em; }
+p { margin: 0.2em 0; color: #555; }
+table { border-collapse: collapse; font-size: 0.75em; }
+th, td { border: 1px solid #ddd; padding: 4px 6px; text-align: right; font-variant-numeric: tabular-nums; }
+th { background: #f2f2f2; }
+th.day { text-align: left; }
+@media print { body { margin: 0; } h2 { page-break-before: auto; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Notes}}<p>{{.}}</p>
+{{end}}{{$hours := .Hours}}{{range .Outlets}}<h2>{{.Name}}</h2>
+<table>
+<tr><th></th>{{range $hours}}<th>{{.}}</th>{{end}}</tr>
+{{range .Days}}<tr><th class="day">{{.Day}}</th>{{range .Cells}}<td style="{{.Style}}">{{.Value}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+// writeHeatmapHTML writes the heatmaps as a page with no external assets, shading each cell by its share of the
+// busiest hour of the outlet
+func writeHeatmapHTML(fileName, title string, notes []string, heatmaps []*heatmap, metric string) error {
+	type dayRow struct {
+		Day   string
+		Cells []heatmapCell
+	}
+	type outletMap struct {
+		Name string
+		Days []dayRow
+	}
+	page := struct {
+		Title   string
+		Notes   []string
+		Hours   []string
+		Outlets []outletMap
+	}{Title: title, Notes: notes}
+
+	for hour := 0; hour < 24; hour++ {
+		page.Hours = append(page.Hours, fmt.Sprintf("%02d", hour))
+	}
+	for _, h := range heatmaps {
+		max := h.max()
+		outlet := outletMap{Name: h.Outlet}
+		for day, hours := range h.Cells {
+			row := dayRow{Day: heatmapDays[day].String()[:3]}
+			for _, value := range hours {
+				var share float64
+				if max > 0 {
+					share = value / max
+				}
+				cell := heatmapCell{Style: template.CSS(fmt.Sprintf("background: rgba(220, 60, 40, %.2f)", share))}
+				if value != 0 {
+					cell.Value = formatHeatmapValue(value, metric)
+				}
+				if share > 0.6 {
+					cell.Style += "; color: #fff"
+				}
+				row.Cells = append(row.Cells, cell)
+			}
+			outlet.Days = append(outlet.Days, row)
+		}
+		page.Outlets = append(page.Outlets, outlet)
+	}
+
+	file, err := os.Create(fmt.Sprintf("./%s", fileName))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return heatmapHTMLTemplate.Execute(file, page)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/govend/vend"
+)
+
+func TestBuildHeatmaps(t *testing.T) {
+	newmarket, ponsonby := "o1", "o2"
+	lines := []lineFixture{{Quantity: 2, Price: 5}}
+
+	// 2024-03-04 is a Monday: 9pm UTC is 10am on Tuesday in Auckland
+	sales := []vend.Sale{
+		testSale(saleFixture{Outlet: newmarket, Date: "2024-03-04T21:15:00Z", Lines: lines}),
+		testSale(saleFixture{Outlet: newmarket, Date: "2024-03-04T21:45:00Z", Lines: lines}),
+		testSale(saleFixture{Outlet: ponsonby, Date: "2024-03-10T02:00:00Z", Lines: lines}),
+	}
+	data := newSalesReportData(map[string]string{newmarket: "Newmarket", ponsonby: "Ponsonby"}, nil, nil, nil, nil, nil, nil)
+
+	heatmaps := buildHeatmaps(sales, data, "revenue", "Pacific/Auckland")
+	assert.Len(t, heatmaps, 3)
+	assert.Equal(t, "Newmarket", heatmaps[0].Outlet)
+	assert.Equal(t, 20.0, heatmaps[0].Cells[1][10])
+	assert.Equal(t, 10.0, heatmaps[1].Cells[6][15])
+	assert.Equal(t, "All Outlets", heatmaps[2].Outlet)
+	assert.Equal(t, 20.0, heatmaps[2].max())
+
+	table := heatmapTable(heatmaps, "revenue")
+	assert.Len(t, table.Rows, 21)
+	assert.Equal(t, []string{"Newmarket", "Tuesday", "20.00", "20.00"}, []string{table.Rows[1][0], table.Rows[1][1], table.Rows[1][12], table.Rows[1][26]})
+
+	// reports are written to the working directory
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(t.TempDir())
+
+	assert.Nil(t, writeHeatmapHTML("heatmap.html", table.Title, table.Notes, heatmaps, "revenue"))
+	page, err := os.ReadFile("heatmap.html")
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(page), "background: rgba(220, 60, 40, 1.00)"))
+	assert.False(t, strings.Contains(string(page), "http"))
+}
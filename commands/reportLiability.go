@@ -0,0 +1,390 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vend/vend-cli/pkg/messenger"
+	pbar "github.com/vend/vend-cli/pkg/progressbar"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vend/govend/vend"
+)
+
+// Command config
+var (
+	liabilityAsOf  string
+	liabilityFrom  string
+	liabilityPrint string
+
+	reportLiabilityCmd = &cobra.Command{
+		Use:   "report-liability",
+		Short: "Report Gift Card and Store Credit Liability",
+		Long: fmt.Sprintf(`
+Replays the transactions of every gift card and store credit account to work out what the store owed at the end of
+--as-of, in the store's timezone, for month-end accounts. The report shows the liability at the start of the period,
+what was issued, redeemed, expired and voided in it, and the liability at the end. The period starts on --from, by
+default the first of the month of --as-of.
+
+Gift cards past their expiry date with no expiry transaction are treated as expiring with their balance on that
+date. Transaction types that are not an issue, redemption, expiry or void are shown as adjustments. The balance of
+every account at --as-of is written to its own CSV.
+
+Example:
+%s`, color.GreenString("vendcli report-liability -d DOMAINPREFIX -t TOKEN -z TIMEZONE --as-of 2024-03-31")),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			reportLiability()
+		},
+	}
+)
+
+func init() {
+	// Flags
+	reportLiabilityCmd.Flags().StringVarP(&timeZone, "Timezone", "z", "", "Timezone of the store in zoneinfo format.")
+	reportLiabilityCmd.Flags().StringVar(&liabilityAsOf, "as-of", "", "Report the liability at the end of this date (YYYY-MM-DD)")
+	reportLiabilityCmd.Flags().StringVar(&liabilityFrom, "from", "", "Start of the period of movements (YYYY-MM-DD), by default the first of the month")
+	reportLiabilityCmd.Flags().StringVar(&liabilityPrint, "print", "markdown", "Also write the report as: markdown, html, none")
+	reportLiabilityCmd.MarkFlagRequired("Timezone")
+	reportLiabilityCmd.MarkFlagRequired("as-of")
+
+	rootCmd.AddCommand(reportLiabilityCmd)
+}
+
+// liabilityAccount is a gift card or store credit account and its transactions
+type liabilityAccount struct {
+	Kind         string
+	Reference    string
+	ExpiresAt    *time.Time
+	Balance      float64
+	Transactions []liabilityTransaction
+}
+
+// liabilityTransaction is a change to the balance of an account, negative when it lowers what is owed
+type liabilityTransaction struct {
+	Time   time.Time
+	Type   string
+	Amount float64
+}
+
+// liabilitySummary is the liability of a kind of account over the period
+type liabilitySummary struct {
+	Kind        string
+	Opening     float64
+	Issued      float64
+	Redeemed    float64
+	Expired     float64
+	Voided      float64
+	Adjusted    float64
+	Closing     float64
+	Outstanding int
+}
+
+// liabilityBalance is the balance of an account at the end of the period
+type liabilityBalance struct {
+	Kind      string
+	Reference string
+	AsOf      float64
+	Current   float64
+}
+
+func reportLiability() {
+	validateDateInput(liabilityAsOf, "as of")
+	validateTimeZone(liabilityAsOf+"T00:00:00Z", timeZone)
+	if liabilityFrom == "" {
+		liabilityFrom = liabilityAsOf[:8] + "01"
+	}
+	validateDateInput(liabilityFrom, "from")
+	if liabilityFrom > liabilityAsOf {
+		messenger.ExitWithError(fmt.Errorf("--from %s is after --as-of %s", liabilityFrom, liabilityAsOf))
+	}
+	if err := checkReportFormat(liabilityPrint); err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	location, _ := time.LoadLocation(timeZone)
+	start, _ := time.ParseInLocation("2006-01-02", liabilityFrom, location)
+	end, _ := time.ParseInLocation("2006-01-02", liabilityAsOf, location)
+	end = end.AddDate(0, 0, 1)
+
+	fmt.Println("Creating Liability Report...")
+	giftCards, storeCredits := fetchBalances()
+	accounts := append(giftCardAccounts(giftCards), storeCreditAccounts(storeCredits)...)
+
+	summaries, balances, unreconciled := replayLiability(accounts, start, end)
+	if len(unreconciled) > 0 {
+		fmt.Println(color.YellowString("\nThe transactions of these accounts do not add up to their current balance, their liability may be off:"))
+		for _, reference := range unreconciled {
+			fmt.Println(" -", reference)
+		}
+	}
+
+	fileName := fmt.Sprintf("%s_liability_report_f%s_t%s.csv", DomainPrefix, liabilityFrom, liabilityAsOf)
+	files, err := liabilityTable(summaries).write(fileName, liabilityPrint)
+	if err == nil {
+		balancesFile := fmt.Sprintf("%s_liability_balances_%s.csv", DomainPrefix, liabilityAsOf)
+		err = liabilityBalancesTable(balances).writeCSV(balancesFile)
+		files = append(files, balancesFile)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to write liability report: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	fmt.Println(color.GreenString("\n\nFinished!🎉\nReplayed %d gift cards and %d store credit accounts: %s", len(giftCards),
+		len(storeCredits), strings.Join(files, ", ")))
+}
+
+func fetchBalances() ([]vend.GiftCard, []vend.StoreCredit) {
+	p, err := pbar.CreateMultiBarGroup(2, Token, DomainPrefix)
+	if err != nil {
+		fmt.Println("error creating progress bar group: ", err)
+	}
+	p.FetchDataWithProgressBar("gift-cards")
+	p.FetchDataWithProgressBar("store-credits")
+	p.MultiBarGroupWait()
+
+	for err = range p.ErrorChannel {
+		err = fmt.Errorf("failed to get gift cards and store credits: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	var giftCards []vend.GiftCard
+	var storeCredits []vend.StoreCredit
+	for data := range p.DataChannel {
+		switch d := data.(type) {
+		case []vend.GiftCard:
+			giftCards = d
+		case []vend.StoreCredit:
+			storeCredits = d
+		}
+	}
+	return giftCards, storeCredits
+}
+
+func giftCardAccounts(giftCards []vend.GiftCard) []liabilityAccount {
+	var accounts []liabilityAccount
+	for _, card := range giftCards {
+		account := liabilityAccount{Kind: "Gift Card", Reference: stringOf(card.Number)}
+		if account.Reference == "" {
+			account.Reference = stringOf(card.ID)
+		}
+		if card.Balance != nil {
+			account.Balance = *card.Balance
+		}
+		if card.ExpiresAt != nil && *card.ExpiresAt != "" {
+			if expiresAt, err := parseBalanceTime(*card.ExpiresAt); err == nil {
+				account.ExpiresAt = &expiresAt
+			}
+		}
+		for _, transaction := range card.GiftCardTransactions {
+			if transaction.CreatedAt == nil || transaction.Amount == nil {
+				continue
+			}
+			created, err := parseBalanceTime(*transaction.CreatedAt)
+			if err != nil {
+				fmt.Printf("Error parsing date: %s\n", err)
+				continue
+			}
+			account.Transactions = append(account.Transactions, liabilityTransaction{Time: created, Type: stringOf(transaction.Type), Amount: *transaction.Amount})
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts
+}
+
+func storeCreditAccounts(storeCredits []vend.StoreCredit) []liabilityAccount {
+	var accounts []liabilityAccount
+	for _, credit := range storeCredits {
+		account := liabilityAccount{Kind: "Store Credit", Reference: stringOf(credit.CustomerCode)}
+		if account.Reference == "" {
+			account.Reference = stringOf(credit.CustomerID)
+		}
+		if credit.Balance != nil {
+			account.Balance = *credit.Balance
+		}
+		for _, transaction := range credit.StoreCreditTransactions {
+			if transaction.CreatedAt == nil {
+				continue
+			}
+			created, err := parseBalanceTime(*transaction.CreatedAt)
+			if err != nil {
+				fmt.Printf("Error parsing date: %s\n", err)
+				continue
+			}
+			account.Transactions = append(account.Transactions, liabilityTransaction{Time: created, Type: transaction.Type, Amount: transaction.Amount})
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts
+}
+
+// parseBalanceTime reads the times of the balances API, which are RFC 3339 or UTC without a zone
+func parseBalanceTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02 15:04:05", value)
+}
+
+// liabilityMovement is the column a transaction type is reported in
+func liabilityMovement(transactionType string) string {
+	upper := strings.ToUpper(transactionType)
+	switch {
+	case strings.Contains(upper, "EXPIR"):
+		return "expired"
+	case strings.Contains(upper, "VOID"), strings.Contains(upper, "CANCEL"), strings.Contains(upper, "REVERS"):
+		return "voided"
+	case strings.Contains(upper, "REDE"):
+		return "redeemed"
+	case strings.Contains(upper, "ISSU"), strings.Contains(upper, "SALE"), strings.Contains(upper, "SOLD"), strings.Contains(upper, "LOAD"):
+		return "issued"
+	}
+	return "adjusted"
+}
+
+// replayLiability adds up the transactions of the accounts before start as the opening liability, and from start up
+// to end as the movements of the period, by kind of account. It returns the balance at end of every account that
+// had one, and the accounts whose transactions do not add up to their current balance.
+func replayLiability(accounts []liabilityAccount, start, end time.Time) ([]*liabilitySummary, []liabilityBalance, []string) {
+	summaries := []*liabilitySummary{{Kind: "Gift Card"}, {Kind: "Store Credit"}}
+	byKind := map[string]*liabilitySummary{}
+	for _, summary := range summaries {
+		byKind[summary.Kind] = summary
+	}
+	var balances []liabilityBalance
+	var unreconciled []string
+
+	for _, account := range accounts {
+		summary, ok := byKind[account.Kind]
+		if !ok {
+			summary = &liabilitySummary{Kind: account.Kind}
+			byKind[account.Kind] = summary
+			summaries = append(summaries, summary)
+		}
+
+		transactions := append([]liabilityTransaction{}, account.Transactions...)
+		sort.SliceStable(transactions, func(i, j int) bool {
+			return transactions[i].Time.Before(transactions[j].Time)
+		})
+
+		var replayed float64
+		expired := false
+		for _, transaction := range transactions {
+			replayed += transaction.Amount
+			expired = expired || liabilityMovement(transaction.Type) == "expired"
+		}
+		if math.Abs(replayed-account.Balance) > 0.005 {
+			unreconciled = append(unreconciled, fmt.Sprintf("%s %s: %s from transactions, %s balance", account.Kind,
+				account.Reference, formatCents(replayed), formatCents(account.Balance)))
+		}
+
+		// the balance left on an expired card is written off when it expires, unless there is a transaction for it
+		if account.ExpiresAt != nil && !expired && account.ExpiresAt.Before(end) {
+			var left float64
+			for _, transaction := range transactions {
+				if transaction.Time.Before(*account.ExpiresAt) {
+					left += transaction.Amount
+				}
+			}
+			if left > 0.005 {
+				transactions = append(transactions, liabilityTransaction{Time: *account.ExpiresAt, Type: "EXPIRY", Amount: -left})
+			}
+		}
+
+		var balance float64
+		for _, transaction := range transactions {
+			if !transaction.Time.Before(end) {
+				continue
+			}
+			balance += transaction.Amount
+			if transaction.Time.Before(start) {
+				summary.Opening += transaction.Amount
+				continue
+			}
+			switch liabilityMovement(transaction.Type) {
+			case "issued":
+				summary.Issued += transaction.Amount
+			case "redeemed":
+				summary.Redeemed += transaction.Amount
+			case "expired":
+				summary.Expired += transaction.Amount
+			case "voided":
+				summary.Voided += transaction.Amount
+			default:
+				summary.Adjusted += transaction.Amount
+			}
+		}
+		summary.Closing += balance
+
+		if math.Abs(balance) > 0.005 {
+			summary.Outstanding++
+			balances = append(balances, liabilityBalance{Kind: account.Kind, Reference: account.Reference, AsOf: balance, Current: account.Balance})
+		}
+	}
+
+	sort.SliceStable(balances, func(i, j int) bool {
+		if balances[i].Kind != balances[j].Kind {
+			return balances[i].Kind < balances[j].Kind
+		}
+		return balances[i].Reference < balances[j].Reference
+	})
+	return summaries, balances, unreconciled
+}
+
+func (s liabilitySummary) row() []string {
+	return []string{
+		s.Kind,
+		formatCents(s.Opening),
+		formatCents(s.Issued),
+		formatCents(s.Redeemed),
+		formatCents(s.Expired),
+		formatCents(s.Voided),
+		formatCents(s.Adjusted),
+		formatCents(s.Closing),
+		fmt.Sprint(s.Outstanding),
+	}
+}
+
+func liabilityTable(summaries []*liabilitySummary) reportTable {
+	total := liabilitySummary{Kind: "Total"}
+	var rows [][]string
+	for _, summary := range summaries {
+		rows = append(rows, summary.row())
+		total.Opening += summary.Opening
+		total.Issued += summary.Issued
+		total.Redeemed += summary.Redeemed
+		total.Expired += summary.Expired
+		total.Voided += summary.Voided
+		total.Adjusted += summary.Adjusted
+		total.Closing += summary.Closing
+		total.Outstanding += summary.Outstanding
+	}
+	rows = append(rows, total.row())
+
+	return reportTable{
+		Title: fmt.Sprintf("Liability Report %s", DomainPrefix),
+		Notes: []string{
+			fmt.Sprintf("%s to the end of %s, %s", liabilityFrom, liabilityAsOf, timeZone),
+			"Movements are signed by their effect on what is owed",
+		},
+		Header:      []string{"Type", "Opening Liability", "Issued", "Redeemed", "Expired", "Voided", "Adjusted", "Closing Liability", "Accounts Outstanding"},
+		Rows:        rows,
+		NumericFrom: 1,
+	}
+}
+
+func liabilityBalancesTable(balances []liabilityBalance) reportTable {
+	var rows [][]string
+	for _, balance := range balances {
+		rows = append(rows, []string{balance.Kind, balance.Reference, formatCents(balance.AsOf), formatCents(balance.Current)})
+	}
+	return reportTable{
+		Header: []string{"Type", "Number or Customer Code", "Balance As Of " + liabilityAsOf, "Current Balance"},
+		Rows:   rows,
+	}
+}
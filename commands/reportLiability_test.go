@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/govend/vend"
+)
+
+func TestReplayLiability(t *testing.T) {
+	transaction := func(kind string, amount float64, created string) vend.GiftCardTransaction {
+		return vend.GiftCardTransaction{Type: &kind, Amount: &amount, CreatedAt: &created}
+	}
+	card := func(number string, balance float64, expiresAt string, transactions ...vend.GiftCardTransaction) vend.GiftCard {
+		return vend.GiftCard{Number: &number, Balance: &balance, ExpiresAt: &expiresAt, GiftCardTransactions: transactions}
+	}
+	credit := func(code string, balance float64, transactions ...vend.StoreCreditTransaction) vend.StoreCredit {
+		return vend.StoreCredit{CustomerCode: &code, Balance: &balance, StoreCreditTransactions: transactions}
+	}
+	creditTransaction := func(kind string, amount float64, created string) vend.StoreCreditTransaction {
+		return vend.StoreCreditTransaction{Type: kind, Amount: amount, CreatedAt: &created}
+	}
+
+	giftCards := []vend.GiftCard{
+		card("GC1", 30, "", transaction("ISSUING", 50, "2024-02-10T00:00:00Z"), transaction("REDEEMING", -20, "2024-03-05T00:00:00Z")),
+		// issued in the period and voided after it
+		card("GC2", 0, "", transaction("ISSUING", 25, "2024-03-10T00:00:00Z"), transaction("VOIDING", -25, "2024-04-02T00:00:00Z")),
+		// expires in the period without an expiry transaction
+		card("GC3", 10, "2024-03-15T00:00:00Z", transaction("ISSUING", 10, "2024-01-01T00:00:00Z")),
+	}
+	storeCredits := []vend.StoreCredit{
+		credit("C1", 5, creditTransaction("ISSUE", 15, "2024-03-01T00:00:00Z"), creditTransaction("REDEMPTION", -10, "2024-03-31T23:00:00Z")),
+		// the balance does not match the transactions
+		credit("C2", 8, creditTransaction("ISSUE", 4, "2024-02-01T00:00:00Z")),
+	}
+	accounts := append(giftCardAccounts(giftCards), storeCreditAccounts(storeCredits)...)
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	summaries, balances, unreconciled := replayLiability(accounts, start, end)
+
+	assert.Equal(t, []string{"Store Credit C2: 4.00 from transactions, 8.00 balance"}, unreconciled)
+	assert.Equal(t, []*liabilitySummary{
+		{Kind: "Gift Card", Opening: 60, Issued: 25, Redeemed: -20, Expired: -10, Closing: 55, Outstanding: 2},
+		{Kind: "Store Credit", Opening: 4, Issued: 15, Redeemed: -10, Closing: 9, Outstanding: 2},
+	}, summaries)
+	assert.Equal(t, []liabilityBalance{
+		{Kind: "Gift Card", Reference: "GC1", AsOf: 30, Current: 30},
+		{Kind: "Gift Card", Reference: "GC2", AsOf: 25, Current: 0},
+		{Kind: "Store Credit", Reference: "C1", AsOf: 5, Current: 5},
+		{Kind: "Store Credit", Reference: "C2", AsOf: 4, Current: 8},
+	}, balances)
+
+	assert.Equal(t, "voided", liabilityMovement("VOIDING"))
+	assert.Equal(t, "redeemed", liabilityMovement("REDEMPTION"))
+	assert.Equal(t, "adjusted", liabilityMovement("CORRECTION"))
+}
@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vend/vend-cli/pkg/messenger"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vend/govend/vend"
+)
+
+// Command config
+var (
+	marginFilters salesFilterFlags
+	marginGroupBy []string
+	marginPrint   string
+
+	reportMarginCmd = &cobra.Command{
+		Use:   "report-margin",
+		Short: "Report Gross Margin",
+		Long: fmt.Sprintf(`
+Reports the revenue, cost, gross profit and margin of the sales between the dates, grouped by one or more of:
+%s. Revenue excludes tax and is after discounts.
+
+Lines sold below cost are counted per group and listed in a separate _below_cost CSV.
+
+Example:
+%s`, strings.Join(marginGroups, ", "),
+			color.GreenString("vendcli report-margin -d DOMAINPREFIX -t TOKEN -z TIMEZONE -F DATEFROM -T DATETO --group-by brand,outlet")),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			reportMargin()
+		},
+	}
+)
+
+// marginGroups are what a margin report can be grouped by
+var marginGroups = []string{"product", "parent", "brand", "type", "supplier", "outlet", "user"}
+
+// marginColumns are the column names of the groups
+var marginColumns = map[string]string{
+	"product": "Product", "parent": "Variant Parent", "brand": "Brand", "type": "Product Type",
+	"supplier": "Supplier", "outlet": "Outlet", "user": "User",
+}
+
+func init() {
+	// Flags
+	addSalesRangeFlags(reportMarginCmd, &marginFilters)
+	reportMarginCmd.Flags().StringSliceVar(&marginGroupBy, "group-by", []string{"product"}, fmt.Sprintf("Group the report by one or more of: %s", strings.Join(marginGroups, ", ")))
+	reportMarginCmd.Flags().StringVar(&marginPrint, "print", "markdown", "Also write the report as: markdown, html, none")
+
+	rootCmd.AddCommand(reportMarginCmd)
+}
+
+// marginGroup is the revenue and cost of a group of sale lines
+type marginGroup struct {
+	Keys           []string
+	Units          float64
+	Revenue        float64
+	Cost           float64
+	BelowCostLines int
+}
+
+// belowCostLine is a sale line sold for less than it cost
+type belowCostLine struct {
+	SaleDate      string
+	InvoiceNumber string
+	Outlet        string
+	User          string
+	Product       string
+	SKU           string
+	Quantity      float64
+	Price         float64
+	UnitCost      float64
+}
+
+func reportMargin() {
+	groupBy, err := parseMarginGroups(marginGroupBy)
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+	if err := checkReportFormat(marginPrint); err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	vc := vend.NewClient(Token, DomainPrefix, timeZone)
+	fmt.Println("Creating Margin Report...")
+	sales, data := fetchReportSales(vc, marginFilters, false)
+
+	groups, belowCost := buildMarginGroups(sales, data, groupBy, timeZone)
+
+	fileName := fmt.Sprintf("%s_margin_report_f%s_t%s.csv", DomainPrefix, dateFrom, dateTo)
+	files, err := marginTable(groups, groupBy).write(fileName, marginPrint)
+	if err != nil {
+		err = fmt.Errorf("failed to write margin report: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	if len(belowCost) > 0 {
+		belowCostFile := fmt.Sprintf("%s_margin_report_f%s_t%s_below_cost.csv", DomainPrefix, dateFrom, dateTo)
+		if err := belowCostTable(belowCost).writeCSV(belowCostFile); err != nil {
+			err = fmt.Errorf("failed to write below cost lines: %w", err)
+			messenger.ExitWithError(err)
+		}
+		files = append(files, belowCostFile)
+		fmt.Println(color.YellowString("\n%d lines were sold below cost", len(belowCost)))
+	}
+
+	fmt.Println(color.GreenString("\n\nFinished!🎉\nReported the margin of %d sales: %s", len(sales), strings.Join(files, ", ")))
+}
+
+// parseMarginGroups checks the values of --group-by
+func parseMarginGroups(groupBy []string) ([]string, error) {
+	var groups []string
+	for _, group := range groupBy {
+		group = strings.ToLower(strings.TrimSpace(group))
+		if group == "variant-parent" || group == "variantparent" {
+			group = "parent"
+		}
+		if !containsString(marginGroups, group) {
+			return nil, fmt.Errorf("'%s' is not a valid option for --group-by, use %s", group, strings.Join(marginGroups, ", "))
+		}
+		if !containsString(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("--group-by needs at least one of %s", strings.Join(marginGroups, ", "))
+	}
+	return groups, nil
+}
+
+// buildMarginGroups adds up the sale lines by the groups, most profitable first, and collects the lines sold below
+// cost
+func buildMarginGroups(sales []vend.Sale, data salesReportData, groupBy []string, timeZone string) ([]*marginGroup, []belowCostLine) {
+	var groups []*marginGroup
+	byKey := map[string]*marginGroup{}
+	var belowCost []belowCostLine
+
+	for _, sale := range sales {
+		if sale.LineItems == nil {
+			continue
+		}
+		for _, lineitem := range *sale.LineItems {
+			if lineitem.Quantity == nil || lineitem.Price == nil {
+				continue
+			}
+			var product vend.Product
+			if lineitem.ProductID != nil {
+				product = data.Products[*lineitem.ProductID]
+			}
+
+			var keys []string
+			for _, group := range groupBy {
+				keys = append(keys, marginKey(group, sale, product, data))
+			}
+			key := strings.Join(keys, "|")
+			group, ok := byKey[key]
+			if !ok {
+				group = &marginGroup{Keys: keys}
+				byKey[key] = group
+				groups = append(groups, group)
+			}
+
+			quantity := *lineitem.Quantity
+			cost := lineCostTotal(lineitem)
+			group.Units += quantity
+			group.Revenue += *lineitem.Price * quantity
+			group.Cost += cost
+
+			if quantity > 0 && *lineitem.Price*quantity < cost {
+				group.BelowCostLines++
+				belowCost = append(belowCost, newBelowCostLine(sale, lineitem, product, data, cost/quantity, timeZone))
+			}
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.profit() != b.profit() {
+			return a.profit() > b.profit()
+		}
+		return strings.Join(a.Keys, "|") < strings.Join(b.Keys, "|")
+	})
+	return groups, belowCost
+}
+
+// marginKey is the name of the group a sale line falls in
+func marginKey(group string, sale vend.Sale, product vend.Product, data salesReportData) string {
+	var key string
+	switch group {
+	case "product":
+		key = productName(product)
+	case "parent":
+		key = productName(product)
+		if product.VariantParentID != nil {
+			if parent, ok := data.Products[*product.VariantParentID]; ok && parent.Name != nil {
+				key = *parent.Name
+			}
+		} else if product.Name != nil {
+			key = *product.Name
+		}
+	case "brand":
+		if product.Brand.Name != nil {
+			key = *product.Brand.Name
+		}
+	case "type":
+		if product.Type.Name != nil {
+			key = *product.Type.Name
+		}
+	case "supplier":
+		key, _ = primarySupplier(product)
+	case "outlet":
+		if sale.OutletID != nil {
+			key = data.Outlets[*sale.OutletID]
+		}
+	case "user":
+		key = data.userName(sale.UserID)
+	}
+	if key == "" {
+		return fmt.Sprintf("<No %s>", marginColumns[group])
+	}
+	return key
+}
+
+func newBelowCostLine(sale vend.Sale, lineitem vend.LineItem, product vend.Product, data salesReportData, unitCost float64, timeZone string) belowCostLine {
+	line := belowCostLine{
+		Product:  productName(product),
+		User:     data.userName(sale.UserID),
+		Quantity: *lineitem.Quantity,
+		Price:    *lineitem.Price,
+		UnitCost: unitCost,
+	}
+	if saleDate, err := vend.ParseVendDT(*sale.SaleDate, timeZone); err == nil {
+		line.SaleDate = saleDate.Format("2006-01-02 15:04:05")
+	}
+	if sale.InvoiceNumber != nil {
+		line.InvoiceNumber = *sale.InvoiceNumber
+	}
+	if sale.OutletID != nil {
+		line.Outlet = data.Outlets[*sale.OutletID]
+	}
+	if product.SKU != nil {
+		line.SKU = *product.SKU
+	}
+	return line
+}
+
+func (g marginGroup) profit() float64 {
+	return g.Revenue - g.Cost
+}
+
+// margin is the gross profit as a percentage of revenue, blank without revenue
+func (g marginGroup) margin() string {
+	if g.Revenue == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(g.profit()/g.Revenue*100, 'f', 1, 64)
+}
+
+func marginTable(groups []*marginGroup, groupBy []string) reportTable {
+	var header []string
+	for _, group := range groupBy {
+		header = append(header, marginColumns[group])
+	}
+	header = append(header, "Units Sold", "Revenue", "Cost", "Gross Profit", "Margin %", "Lines Below Cost")
+
+	total := marginGroup{}
+	var rows [][]string
+	for _, group := range groups {
+		rows = append(rows, group.row())
+		total.Units += group.Units
+		total.Revenue += group.Revenue
+		total.Cost += group.Cost
+		total.BelowCostLines += group.BelowCostLines
+	}
+	total.Keys = make([]string, len(groupBy))
+	total.Keys[0] = "Total"
+	rows = append(rows, total.row())
+
+	return reportTable{
+		Title: fmt.Sprintf("Margin Report %s", DomainPrefix),
+		Notes: []string{
+			fmt.Sprintf("%s to %s", dateFrom, dateTo),
+			"Revenue excludes tax and is after discounts",
+		},
+		Header:      header,
+		Rows:        rows,
+		NumericFrom: len(groupBy),
+	}
+}
+
+func (g marginGroup) row() []string {
+	return append(append([]string{}, g.Keys...),
+		strconv.FormatFloat(g.Units, 'f', -1, 64),
+		formatCents(g.Revenue),
+		formatCents(g.Cost),
+		formatCents(g.profit()),
+		g.margin(),
+		strconv.Itoa(g.BelowCostLines),
+	)
+}
+
+func belowCostTable(lines []belowCostLine) reportTable {
+	var rows [][]string
+	for _, line := range lines {
+		rows = append(rows, []string{
+			line.SaleDate,
+			line.InvoiceNumber,
+			line.Outlet,
+			line.User,
+			line.Product,
+			line.SKU,
+			strconv.FormatFloat(line.Quantity, 'f', -1, 64),
+			formatCents(line.Price),
+			formatCents(line.UnitCost),
+			formatCents((line.UnitCost - line.Price) * line.Quantity),
+		})
+	}
+	return reportTable{
+		Header: []string{"Sale Date", "Invoice Number", "Outlet", "User", "Product", "SKU", "Quantity", "Price", "Unit Cost", "Loss"},
+		Rows:   rows,
+	}
+}
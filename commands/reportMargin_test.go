@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/govend/vend"
+)
+
+func TestBuildMarginGroups(t *testing.T) {
+	outletID, parentID, smallID, largeID, mugID := "o1", "p0", "p1", "p2", "p3"
+	shirt, small, large, mug, brand := "Shirt", "Shirt / Small", "Shirt / Large", "Mug", "Acme"
+	date, invoice := "2024-03-01T01:00:00Z", "42"
+
+	sales := []vend.Sale{testSale(saleFixture{Outlet: outletID, Date: date, Invoice: invoice, Lines: []lineFixture{
+		{Product: smallID, Quantity: 2, Price: 20, Cost: 16},
+		{Product: largeID, Quantity: 1, Price: 20, Cost: 8},
+		// sold below cost
+		{Product: mugID, Quantity: 2, Price: 4, Cost: 10},
+	}})}
+	products := []vend.Product{
+		{ID: &parentID, Name: &shirt, Brand: vend.Brand{Name: &brand}},
+		{ID: &smallID, Name: &shirt, VariantName: &small, VariantParentID: &parentID, Brand: vend.Brand{Name: &brand}},
+		{ID: &largeID, Name: &shirt, VariantName: &large, VariantParentID: &parentID, Brand: vend.Brand{Name: &brand}},
+		{ID: &mugID, Name: &mug},
+	}
+	data := newSalesReportData(map[string]string{outletID: "Newmarket"}, nil, nil, nil, nil, products, nil)
+
+	groups, belowCost := buildMarginGroups(sales, data, []string{"parent"}, "UTC")
+	assert.Len(t, groups, 2)
+	assert.Equal(t, []string{"Shirt"}, groups[0].Keys)
+	assert.Equal(t, 3.0, groups[0].Units)
+	assert.Equal(t, 60.0, groups[0].Revenue)
+	assert.Equal(t, 24.0, groups[0].Cost)
+	assert.Equal(t, "60.0", groups[0].margin())
+	assert.Equal(t, 1, groups[1].BelowCostLines)
+
+	assert.Len(t, belowCost, 1)
+	assert.Equal(t, "Mug", belowCost[0].Product)
+	assert.Equal(t, "42", belowCost[0].InvoiceNumber)
+	assert.Equal(t, 5.0, belowCost[0].UnitCost)
+
+	groups, _ = buildMarginGroups(sales, data, []string{"brand", "outlet"}, "UTC")
+	assert.Equal(t, []string{"Acme", "Newmarket"}, groups[0].Keys)
+	assert.Equal(t, []string{"<No Brand>", "Newmarket"}, groups[1].Keys)
+
+	_, err := parseMarginGroups([]string{"colour"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vend/vend-cli/pkg/messenger"
+	pbar "github.com/vend/vend-cli/pkg/progressbar"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vend/govend/vend"
+)
+
+// Command config
+var (
+	reorderFilters   salesFilterFlags
+	reorderCoverDays int
+	reorderPrint     string
+	reorderAll       bool
+
+	reportReorderCmd = &cobra.Command{
+		Use:   "report-reorder",
+		Short: "Report Sales Velocity and Reorder Suggestions",
+		Long: fmt.Sprintf(`
+Reports the units of each product sold at each outlet between the dates, the average daily sales, the days of
+stock left at that rate, and a suggested order quantity, grouped by supplier.
+
+A product is suggested for reorder when its stock is at or below its reorder point, or when it has no reorder point
+and less than --cover-days of stock left. The suggestion covers --cover-days of sales, and is at least the
+product's reorder amount.
+
+Example:
+%s`, color.GreenString("vendcli report-reorder -d DOMAINPREFIX -t TOKEN -z TIMEZONE -F DATEFROM -T DATETO --cover-days 14")),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			reportReorder()
+		},
+	}
+)
+
+func init() {
+	// Flags
+	addSalesRangeFlags(reportReorderCmd, &reorderFilters)
+	reportReorderCmd.Flags().IntVar(&reorderCoverDays, "cover-days", 30, "Days of sales a suggested order should cover")
+	reportReorderCmd.Flags().BoolVar(&reorderAll, "all", false, "Include products that did not sell and need no reorder")
+	reportReorderCmd.Flags().StringVar(&reorderPrint, "print", "markdown", "Also write the report as: markdown, html, none")
+
+	rootCmd.AddCommand(reportReorderCmd)
+}
+
+// reorderLine is the sales and stock of a product at an outlet
+type reorderLine struct {
+	Supplier      string
+	SupplierCode  string
+	Product       string
+	SKU           string
+	Outlet        string
+	Sold          float64
+	Velocity      float64
+	Stock         float64
+	ReorderPoint  float64
+	ReorderAmount float64
+	// DaysOfCover is negative when the product did not sell, so its stock lasts indefinitely
+	DaysOfCover float64
+	Suggested   float64
+}
+
+func reportReorder() {
+	if err := checkReportFormat(reorderPrint); err != nil {
+		messenger.ExitWithError(err)
+	}
+	if reorderCoverDays < 1 {
+		messenger.ExitWithError(fmt.Errorf("--cover-days must be at least 1"))
+	}
+
+	vc := vend.NewClient(Token, DomainPrefix, timeZone)
+	fmt.Println("Creating Reorder Report...")
+	sales, data := fetchReportSales(vc, reorderFilters, false)
+	inventory := fetchInventory()
+
+	days, err := windowDays(dateFrom, dateTo)
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	lines := buildReorderLines(sales, inventory, data, outletsToReport(data, reorderFilters), days, float64(reorderCoverDays), reorderAll)
+	table := reorderTable(lines, days)
+
+	fileName := fmt.Sprintf("%s_reorder_report_f%s_t%s.csv", DomainPrefix, dateFrom, dateTo)
+	files, err := table.write(fileName, reorderPrint)
+	if err != nil {
+		err = fmt.Errorf("failed to write reorder report: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	var suggested int
+	for _, line := range lines {
+		if line.Suggested > 0 {
+			suggested++
+		}
+	}
+	fmt.Println(color.GreenString("\n\nFinished!🎉\n%d products to reorder: %s", suggested, strings.Join(files, ", ")))
+}
+
+// fetchInventory fetches the inventory record of every product at every outlet
+func fetchInventory() []vend.InventoryRecord {
+	p, err := pbar.CreateMultiBarGroup(1, Token, DomainPrefix)
+	if err != nil {
+		fmt.Println("error creating progress bar group: ", err)
+	}
+	p.FetchDataWithProgressBar("inventory")
+	p.MultiBarGroupWait()
+
+	for err = range p.ErrorChannel {
+		err = fmt.Errorf("failed to get inventory: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	var inventory []vend.InventoryRecord
+	for data := range p.DataChannel {
+		if records, ok := data.([]vend.InventoryRecord); ok {
+			inventory = records
+		}
+	}
+	return inventory
+}
+
+// outletsToReport are the ids of the outlets selected by --Outlet, or nil for every outlet
+func outletsToReport(data salesReportData, filters salesFilterFlags) map[string]bool {
+	if len(filters.Outlets) == 1 && strings.EqualFold(filters.Outlets[0], "all") {
+		return nil
+	}
+	var outletNames []namedID
+	for id, name := range data.Outlets {
+		outletNames = append(outletNames, namedID{ID: id, Name: name})
+	}
+	// the names were already checked when the sales were filtered
+	outlets, _ := matchNames("outlet", filters.Outlets, outletNames)
+	return outlets
+}
+
+// windowDays is the number of days from one date to another, including both
+func windowDays(from, to string) (float64, error) {
+	fromDate, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return 0, err
+	}
+	toDate, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return 0, err
+	}
+	days := toDate.Sub(fromDate).Hours()/24 + 1
+	if days < 1 {
+		return 0, fmt.Errorf("date to %s is before date from %s", to, from)
+	}
+	return days, nil
+}
+
+// buildReorderLines joins the units sold of each product at each outlet to its inventory record, sorted by supplier,
+// product and outlet. Products that did not sell and need no reorder are left out unless all is set.
+func buildReorderLines(sales []vend.Sale, inventory []vend.InventoryRecord, data salesReportData, outlets map[string]bool,
+	days, coverDays float64, all bool) []reorderLine {
+
+	// units sold less units returned, by outlet and product
+	sold := map[string]float64{}
+	for _, sale := range sales {
+		if sale.OutletID == nil || sale.LineItems == nil {
+			continue
+		}
+		for _, lineitem := range *sale.LineItems {
+			if lineitem.ProductID != nil && lineitem.Quantity != nil {
+				sold[*sale.OutletID+"|"+*lineitem.ProductID] += *lineitem.Quantity
+			}
+		}
+	}
+
+	var lines []reorderLine
+	for _, record := range inventory {
+		if record.OutletID == nil || record.ProductID == nil || record.DeletedAt != nil {
+			continue
+		}
+		if len(outlets) > 0 && !outlets[*record.OutletID] {
+			continue
+		}
+		product, ok := data.Products[*record.ProductID]
+		if !ok || product.DeletedAt != nil || !product.TrackInventory {
+			continue
+		}
+
+		line := reorderLine{
+			Product: productName(product),
+			Outlet:  data.Outlets[*record.OutletID],
+			Sold:    math.Max(sold[*record.OutletID+"|"+*record.ProductID], 0),
+		}
+		if product.SKU != nil {
+			line.SKU = *product.SKU
+		}
+		line.Supplier, line.SupplierCode = primarySupplier(product)
+		if record.CurrentAmount != nil {
+			line.Stock = *record.CurrentAmount
+		}
+		if record.ReorderPoint != nil {
+			line.ReorderPoint = *record.ReorderPoint
+		}
+		if record.ReorderAmount != nil {
+			line.ReorderAmount = *record.ReorderAmount
+		}
+		line.suggest(days, coverDays)
+
+		if all || line.Sold > 0 || line.Suggested > 0 {
+			lines = append(lines, line)
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		a, b := lines[i], lines[j]
+		if a.Supplier != b.Supplier {
+			// products without a supplier go last
+			return b.Supplier == "" || (a.Supplier != "" && a.Supplier < b.Supplier)
+		}
+		if a.Product != b.Product {
+			return a.Product < b.Product
+		}
+		return a.Outlet < b.Outlet
+	})
+	return lines
+}
+
+// suggest works out the velocity, days of cover and suggested order of a line
+func (l *reorderLine) suggest(days, coverDays float64) {
+	l.Velocity = l.Sold / days
+	l.DaysOfCover = -1
+	if l.Velocity > 0 {
+		l.DaysOfCover = math.Max(l.Stock, 0) / l.Velocity
+	}
+
+	reorder := false
+	if l.ReorderPoint > 0 {
+		reorder = l.Stock <= l.ReorderPoint
+	} else {
+		reorder = l.Velocity > 0 && l.DaysOfCover < coverDays
+	}
+	if !reorder {
+		return
+	}
+	l.Suggested = math.Max(math.Ceil(l.Velocity*coverDays-l.Stock), l.ReorderAmount)
+	if l.Suggested < 0 {
+		l.Suggested = 0
+	}
+}
+
+// primarySupplier is the name and supplier code of a product's first supplier
+func primarySupplier(product vend.Product) (string, string) {
+	for _, supplier := range product.ProductSuppliers {
+		if supplier.SupplierName != nil && *supplier.SupplierName != "" {
+			var code string
+			if supplier.Code != nil {
+				code = *supplier.Code
+			}
+			return *supplier.SupplierName, code
+		}
+	}
+	return "", ""
+}
+
+func reorderTable(lines []reorderLine, days float64) reportTable {
+	var rows [][]string
+	for _, line := range lines {
+		supplier := line.Supplier
+		if supplier == "" {
+			supplier = "<No Supplier>"
+		}
+		daysOfCover := ""
+		if line.DaysOfCover >= 0 {
+			daysOfCover = strconv.FormatFloat(line.DaysOfCover, 'f', 1, 64)
+		}
+		rows = append(rows, []string{
+			supplier,
+			line.SupplierCode,
+			line.Product,
+			line.SKU,
+			line.Outlet,
+			strconv.FormatFloat(line.Sold, 'f', -1, 64),
+			strconv.FormatFloat(line.Velocity, 'f', 2, 64),
+			strconv.FormatFloat(line.Stock, 'f', -1, 64),
+			strconv.FormatFloat(line.ReorderPoint, 'f', -1, 64),
+			strconv.FormatFloat(line.ReorderAmount, 'f', -1, 64),
+			daysOfCover,
+			strconv.FormatFloat(line.Suggested, 'f', -1, 64),
+		})
+	}
+
+	return reportTable{
+		Title: fmt.Sprintf("Reorder Report %s", DomainPrefix),
+		Notes: []string{
+			fmt.Sprintf("Sales from %s to %s (%g days)", dateFrom, dateTo, days),
+			fmt.Sprintf("Suggested orders cover %d days of sales", reorderCoverDays),
+		},
+		Header: []string{"Supplier", "Supplier Code", "Product", "SKU", "Outlet", "Units Sold", "Daily Velocity",
+			"Current Stock", "Reorder Point", "Reorder Amount", "Days of Cover", "Suggested Order"},
+		Rows:        rows,
+		NumericFrom: 5,
+	}
+}
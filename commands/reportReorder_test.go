@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/govend/vend"
+)
+
+func TestBuildReorderLines(t *testing.T) {
+	outletID, widgetID, gadgetID, idleID := "o1", "p1", "p2", "p3"
+	widget, gadget, idle, supplier, code := "Widget", "Gadget", "Idle", "Acme", "AC-1"
+
+	sales := []vend.Sale{testSale(saleFixture{Outlet: outletID, Lines: []lineFixture{
+		{Product: widgetID, Quantity: 10}, {Product: gadgetID, Quantity: 3}, {Product: widgetID, Quantity: -2},
+	}})}
+
+	record := func(productID *string, stock, point, amount float64) vend.InventoryRecord {
+		return vend.InventoryRecord{OutletID: &outletID, ProductID: productID, CurrentAmount: &stock, ReorderPoint: &point, ReorderAmount: &amount}
+	}
+	inventory := []vend.InventoryRecord{
+		// at its reorder point: orders at least the reorder amount
+		record(&widgetID, 5, 5, 20),
+		// no reorder point: orders up to 30 days of cover
+		record(&gadgetID, 1, 0, 0),
+		record(&idleID, 4, 0, 0),
+	}
+	products := []vend.Product{
+		{ID: &widgetID, Name: &widget, TrackInventory: true, ProductSuppliers: []vend.ProductSuppliers{{SupplierName: &supplier, Code: &code}}},
+		{ID: &gadgetID, Name: &gadget, TrackInventory: true},
+		{ID: &idleID, Name: &idle, TrackInventory: true},
+	}
+	data := newSalesReportData(map[string]string{outletID: "Newmarket"}, nil, nil, nil, nil, products, nil)
+
+	reorder := buildReorderLines(sales, inventory, data, nil, 10, 30, false)
+	assert.Len(t, reorder, 2)
+
+	assert.Equal(t, "Acme", reorder[0].Supplier)
+	assert.Equal(t, "AC-1", reorder[0].SupplierCode)
+	assert.Equal(t, 8.0, reorder[0].Sold)
+	assert.InDelta(t, 0.8, reorder[0].Velocity, 0.0001)
+	assert.InDelta(t, 6.25, reorder[0].DaysOfCover, 0.0001)
+	assert.Equal(t, 20.0, reorder[0].Suggested)
+
+	assert.Equal(t, "", reorder[1].Supplier)
+	assert.Equal(t, 8.0, reorder[1].Suggested)
+
+	reorder = buildReorderLines(sales, inventory, data, nil, 10, 30, true)
+	assert.Len(t, reorder, 3)
+	assert.Equal(t, -1.0, reorder[2].DaysOfCover)
+	assert.Equal(t, 0.0, reorder[2].Suggested)
+}
+
+func TestWindowDays(t *testing.T) {
+	days, err := windowDays("2024-03-01", "2024-03-31")
+	assert.Nil(t, err)
+	assert.Equal(t, 31.0, days)
+
+	_, err = windowDays("2024-03-02", "2024-03-01")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vend/vend-cli/pkg/messenger"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vend/govend/vend"
+)
+
+// Command config
+var (
+	staffFilters    salesFilterFlags
+	staffPrint      string
+	staffNoAuditLog bool
+
+	reportStaffCmd = &cobra.Command{
+		Use:   "report-staff",
+		Short: "Report Staff Performance",
+		Long: fmt.Sprintf(`
+Reports per user and outlet the number of sales, revenue, average basket, items per sale, discount given, returns
+processed and sales voided between the dates. Revenue excludes tax and is after discounts.
+
+Voids are taken from the audit log, so they are counted against the user who voided the sale rather than the one
+who rang it up. Voided sales with no audit log event, or every voided sale with --no-auditlog, count against the
+sale's user.
+
+Example:
+%s`, color.GreenString("vendcli report-staff -d DOMAINPREFIX -t TOKEN -z TIMEZONE -F DATEFROM -T DATETO")),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			reportStaff()
+		},
+	}
+)
+
+func init() {
+	// Flags
+	addSalesRangeFlags(reportStaffCmd, &staffFilters)
+	reportStaffCmd.Flags().BoolVar(&staffNoAuditLog, "no-auditlog", false, "Count voids by the sale's user without reading the audit log")
+	reportStaffCmd.Flags().StringVar(&staffPrint, "print", "markdown", "Also write the report as: markdown, html, none")
+
+	rootCmd.AddCommand(reportStaffCmd)
+}
+
+// staffStats is the performance of a user at an outlet
+type staffStats struct {
+	User        string
+	Outlet      string
+	SaleCount   int
+	Revenue     float64
+	SalesValue  float64
+	Items       float64
+	Discount    float64
+	ReturnCount int
+	ReturnValue float64
+	Voids       int
+}
+
+func reportStaff() {
+	if err := checkReportFormat(staffPrint); err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	vc := vend.NewClient(Token, DomainPrefix, timeZone)
+	fmt.Println("Creating Staff Report...")
+	sales, data := fetchReportSales(vc, staffFilters, true)
+
+	var events []vend.AuditLog
+	if !staffNoAuditLog {
+		fmt.Println("\nRetrieving Audit Log from Vend...")
+		utcDateFrom, _ := getUtcTime(dateFrom+"T00:00:00Z", timeZone)
+		utcDateTo, _ := getUtcTime(dateTo+"T23:59:59Z", timeZone)
+		var err error
+		events, err = fetchAuditLog(strings.TrimSuffix(utcDateFrom, "Z"), strings.TrimSuffix(utcDateTo, "Z"))
+		if err != nil {
+			// the report is still useful without the audit log, so fall back to the sale's user
+			fmt.Println(color.YellowString("\nCould not read the audit log, voids are counted by the sale's user: %s", err))
+			events = nil
+		}
+	}
+
+	stats := buildStaffStats(sales, voidEvents(events), data)
+
+	fileName := fmt.Sprintf("%s_staff_report_f%s_t%s.csv", DomainPrefix, dateFrom, dateTo)
+	files, err := staffTable(stats).write(fileName, staffPrint)
+	if err != nil {
+		err = fmt.Errorf("failed to write staff report: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	fmt.Println(color.GreenString("\n\nFinished!🎉\nReported on %d users: %s", len(stats), strings.Join(files, ", ")))
+}
+
+// voidEvents returns the user who voided each sale, by sale id, from the audit log
+func voidEvents(events []vend.AuditLog) map[string]string {
+	voids := map[string]string{}
+	for _, event := range events {
+		if event.EntityID == nil || event.UserID == nil {
+			continue
+		}
+		var kind, action string
+		if event.Kind != nil {
+			kind = *event.Kind
+		}
+		if event.Action != nil {
+			action = *event.Action
+		}
+		if strings.Contains(strings.ToLower(kind+" "+action), "void") {
+			voids[*event.EntityID] = *event.UserID
+		}
+	}
+	return voids
+}
+
+// buildStaffStats adds up the sales by user and outlet, sorted by user then outlet. voidedBy is the user who voided
+// each sale; voided sales not in it count against the sale's user.
+func buildStaffStats(sales []vend.Sale, voidedBy map[string]string, data salesReportData) []*staffStats {
+	var stats []*staffStats
+	byKey := map[string]*staffStats{}
+	statsFor := func(userID *string, outletID *string) *staffStats {
+		user := data.userName(userID)
+		if user == "" {
+			user = "<Unknown User>"
+		}
+		var outlet string
+		if outletID != nil {
+			outlet = data.Outlets[*outletID]
+		}
+		key := user + "|" + outlet
+		s, ok := byKey[key]
+		if !ok {
+			s = &staffStats{User: user, Outlet: outlet}
+			byKey[key] = s
+			stats = append(stats, s)
+		}
+		return s
+	}
+
+	for _, sale := range sales {
+		if sale.Status != nil && *sale.Status == "VOIDED" {
+			userID := sale.UserID
+			if sale.ID != nil {
+				if voider, ok := voidedBy[*sale.ID]; ok {
+					userID = &voider
+				}
+			}
+			statsFor(userID, sale.OutletID).Voids++
+			continue
+		}
+		statsFor(sale.UserID, sale.OutletID).add(sale)
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].User != stats[j].User {
+			return stats[i].User < stats[j].User
+		}
+		return stats[i].Outlet < stats[j].Outlet
+	})
+	return stats
+}
+
+// add adds a sale to the stats. A sale that returns more than it sells counts as a return.
+func (s *staffStats) add(sale vend.Sale) {
+	var sold, returned, items, discount float64
+	if sale.LineItems != nil {
+		for _, lineitem := range *sale.LineItems {
+			if lineitem.Quantity == nil || lineitem.Price == nil {
+				continue
+			}
+			value := *lineitem.Price * *lineitem.Quantity
+			if *lineitem.Quantity < 0 {
+				returned -= value
+				continue
+			}
+			sold += value
+			items += *lineitem.Quantity
+			discount += lineDiscountTotal(lineitem)
+		}
+	}
+
+	s.Revenue += sold - returned
+	s.Discount += discount
+	s.ReturnValue += returned
+	if returned > sold {
+		s.ReturnCount++
+		return
+	}
+	s.SaleCount++
+	s.SalesValue += sold
+	s.Items += items
+}
+
+// perSale divides a total over the sales, blank without sales
+func (s staffStats) perSale(total float64, precision int) string {
+	if s.SaleCount == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(total/float64(s.SaleCount), 'f', precision, 64)
+}
+
+func (s staffStats) row() []string {
+	return []string{
+		s.User,
+		s.Outlet,
+		strconv.Itoa(s.SaleCount),
+		formatCents(s.Revenue),
+		s.perSale(s.SalesValue, 2),
+		s.perSale(s.Items, 1),
+		formatCents(s.Discount),
+		strconv.Itoa(s.ReturnCount),
+		formatCents(s.ReturnValue),
+		strconv.Itoa(s.Voids),
+	}
+}
+
+func staffTable(stats []*staffStats) reportTable {
+	total := staffStats{User: "Total"}
+	var rows [][]string
+	for _, s := range stats {
+		rows = append(rows, s.row())
+		total.SaleCount += s.SaleCount
+		total.Revenue += s.Revenue
+		total.SalesValue += s.SalesValue
+		total.Items += s.Items
+		total.Discount += s.Discount
+		total.ReturnCount += s.ReturnCount
+		total.ReturnValue += s.ReturnValue
+		total.Voids += s.Voids
+	}
+	rows = append(rows, total.row())
+
+	return reportTable{
+		Title: fmt.Sprintf("Staff Report %s", DomainPrefix),
+		Notes: []string{
+			fmt.Sprintf("%s to %s", dateFrom, dateTo),
+			"Revenue excludes tax and is after discounts and returns",
+		},
+		Header: []string{"User", "Outlet", "Sales", "Revenue", "Average Basket", "Items per Sale", "Discount Given",
+			"Returns", "Returns Value", "Voids"},
+		Rows:        rows,
+		NumericFrom: 2,
+	}
+}
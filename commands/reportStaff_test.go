@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/govend/vend"
+)
+
+func TestBuildStaffStats(t *testing.T) {
+	outletID, annaID, benID := "o1", "u1", "u2"
+	anna, ben := "Anna", "Ben"
+	voidedID := "s3"
+
+	sales := []vend.Sale{
+		testSale(saleFixture{Outlet: outletID, User: annaID, Lines: []lineFixture{{Quantity: 2, Price: 10, Discount: 1}, {Quantity: 1, Price: 30}}}),
+		testSale(saleFixture{Outlet: outletID, User: annaID, Lines: []lineFixture{{Quantity: 1, Price: 20}}}),
+		testSale(saleFixture{Outlet: outletID, User: annaID, Lines: []lineFixture{{Quantity: -1, Price: 10}}}),
+		testSale(saleFixture{ID: voidedID, Status: "VOIDED", Outlet: outletID, User: annaID, Lines: []lineFixture{{Quantity: 1, Price: 10}}}),
+		testSale(saleFixture{ID: "s4", Status: "VOIDED", Outlet: outletID, User: annaID, Lines: []lineFixture{{Quantity: 1, Price: 10}}}),
+	}
+	data := newSalesReportData(map[string]string{outletID: "Newmarket"}, nil,
+		[]vend.User{{ID: &annaID, DisplayName: &anna}, {ID: &benID, DisplayName: &ben}}, nil, nil, nil, nil)
+
+	kind, action := "sale", "sale.void"
+	events := []vend.AuditLog{{UserID: &benID, EntityID: &voidedID, Kind: &kind, Action: &action}}
+
+	stats := buildStaffStats(sales, voidEvents(events), data)
+	assert.Len(t, stats, 2)
+
+	assert.Equal(t, "Anna", stats[0].User)
+	assert.Equal(t, 2, stats[0].SaleCount)
+	assert.Equal(t, 60.0, stats[0].Revenue)
+	assert.Equal(t, 2.0, stats[0].Discount)
+	assert.Equal(t, 1, stats[0].ReturnCount)
+	assert.Equal(t, 10.0, stats[0].ReturnValue)
+	assert.Equal(t, 1, stats[0].Voids)
+	assert.Equal(t, "35.00", stats[0].perSale(stats[0].SalesValue, 2))
+	assert.Equal(t, "2.0", stats[0].perSale(stats[0].Items, 1))
+
+	assert.Equal(t, "Ben", stats[1].User)
+	assert.Equal(t, 0, stats[1].SaleCount)
+	assert.Equal(t, 1, stats[1].Voids)
+}
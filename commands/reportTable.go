@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+)
+
+// reportTable is a report that can be written as CSV, Markdown or a printable HTML page
+type reportTable struct {
+	Title  string
+	Notes  []string
+	Header []string
+	Rows   [][]string
+	// NumericFrom is the first column holding numbers, which are right aligned
+	NumericFrom int
+}
+
+// reportFormats are the printable formats a report can also be written as
+var reportFormats = []string{"markdown", "html", "none"}
+
+// checkReportFormat checks the value of a --print flag
+func checkReportFormat(format string) error {
+	if !containsString(reportFormats, strings.ToLower(format)) {
+		return fmt.Errorf("'%s' is not a valid format, use %s", format, strings.Join(reportFormats, ", "))
+	}
+	return nil
+}
+
+// write writes the report as CSV to fileName, and also as Markdown or HTML next to it. It returns the files written.
+func (t reportTable) write(fileName, format string) ([]string, error) {
+	if err := t.writeCSV(fileName); err != nil {
+		return nil, err
+	}
+	files := []string{fileName}
+
+	base := strings.TrimSuffix(fileName, ".csv")
+	var err error
+	switch strings.ToLower(format) {
+	case "markdown":
+		err = os.WriteFile(base+".md", []byte(t.markdown()), 0644)
+		files = append(files, base+".md")
+	case "html":
+		err = t.writeHTML(base + ".html")
+		files = append(files, base+".html")
+	}
+	return files, err
+}
+
+func (t reportTable) writeCSV(fileName string) error {
+	file, err := os.Create(fmt.Sprintf("./%s", fileName))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(t.Header)
+	writer.WriteAll(t.Rows)
+	return writer.Error()
+}
+
+// markdown returns the report as a Markdown table
+func (t reportTable) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", t.Title)
+	for _, note := range t.Notes {
+		fmt.Fprintf(&b, "%s  \n", note)
+	}
+	if len(t.Notes) > 0 {
+		b.WriteString("\n")
+	}
+
+	escape := strings.NewReplacer("|", "\\|", "\n", " ")
+	b.WriteString("|")
+	for _, column := range t.Header {
+		fmt.Fprintf(&b, " %s |", escape.Replace(column))
+	}
+	b.WriteString("\n|")
+	for i := range t.Header {
+		if t.NumericFrom > 0 && i >= t.NumericFrom {
+			b.WriteString(" ---: |")
+		} else {
+			b.WriteString(" --- |")
+		}
+	}
+	b.WriteString("\n")
+	for _, row := range t.Rows {
+		b.WriteString("|")
+		for _, cell := range row {
+			fmt.Fprintf(&b, " %s |", escape.Replace(cell))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+var reportHTMLTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"numeric": func(t reportTable, i int) bool { return t.NumericFrom > 0 && i >= t.NumericFrom },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+p { margin: 0.2em 0; color: #555; }
+table { border-collapse: collapse; margin-top: 1em; font-size: 0.85em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; }
+th { background: #f2f2f2; text-align: left; }
+td.number { text-align: right; font-variant-numeric: tabular-nums; }
+tr:nth-child(even) td { background: #fafafa; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Notes}}<p>{{.}}</p>
+{{end}}<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{$t := .}}{{range .Rows}}<tr>{{range $i, $cell := .}}<td{{if numeric $t $i}} class="number"{{end}}>{{$cell}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+func (t reportTable) writeHTML(fileName string) error {
+	file, err := os.Create(fmt.Sprintf("./%s", fileName))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return reportHTMLTemplate.Execute(file, t)
+}
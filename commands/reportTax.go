@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vend/vend-cli/pkg/messenger"
+	pbar "github.com/vend/vend-cli/pkg/progressbar"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vend/govend/vend"
+)
+
+// Command config
+var (
+	taxFilters salesFilterFlags
+	taxPeriod  string
+	taxPrint   string
+
+	reportTaxCmd = &cobra.Command{
+		Use:   "report-tax",
+		Short: "Report Sales Tax by Rate and Outlet",
+		Long: fmt.Sprintf(`
+Reports per period and outlet the taxable and exempt sales, the returns of each, and the tax collected per named
+rate, for GST and VAT filings. Lines with no tax are exempt. Tax is split across the rates of the line's tax, and
+lines without a tax take the outlet's tax for the product. Rate columns are net of returns, the tax given back
+on returns is also shown on its own.
+
+Amounts exclude tax and are after discounts. Periods are day, week (from Monday) or month (default).
+
+Example:
+%s`, color.GreenString("vendcli report-tax -d DOMAINPREFIX -t TOKEN -z TIMEZONE -F DATEFROM -T DATETO --period month")),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			reportTax()
+		},
+	}
+)
+
+// taxPeriods are the periods the tax report can be split by
+var taxPeriods = []string{"day", "week", "month"}
+
+func init() {
+	// Flags
+	addSalesRangeFlags(reportTaxCmd, &taxFilters)
+	reportTaxCmd.Flags().StringVar(&taxPeriod, "period", "month", fmt.Sprintf("One row per outlet and: %s", strings.Join(taxPeriods, ", ")))
+	reportTaxCmd.Flags().StringVar(&taxPrint, "print", "markdown", "Also write the report as: markdown, html, none")
+
+	rootCmd.AddCommand(reportTaxCmd)
+}
+
+// taxSummary is the tax of an outlet over a period
+type taxSummary struct {
+	Period         string
+	Outlet         string
+	TaxableSales   float64
+	ExemptSales    float64
+	TaxableReturns float64
+	ExemptReturns  float64
+	ReturnTax      float64
+	Rates          map[string]float64
+	// EstimatedLines are the taxed lines with no tax components, whose tax was split by rate
+	EstimatedLines int
+}
+
+func reportTax() {
+	taxPeriod = strings.ToLower(taxPeriod)
+	if !containsString(taxPeriods, taxPeriod) {
+		err := fmt.Errorf("'%s' is not a valid period, use %s", taxPeriod, strings.Join(taxPeriods, ", "))
+		messenger.ExitWithError(err)
+	}
+	if err := checkReportFormat(taxPrint); err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	vc := vend.NewClient(Token, DomainPrefix, timeZone)
+	fmt.Println("Creating Tax Report...")
+	sales, data := fetchReportSales(vc, taxFilters, false)
+	outletTaxes := fetchOutletTaxes()
+
+	summaries, rateNames := buildTaxSummaries(sales, data, outletTaxMap(outletTaxes), taxPeriod, timeZone)
+
+	fileName := fmt.Sprintf("%s_tax_report_f%s_t%s.csv", DomainPrefix, dateFrom, dateTo)
+	files, err := taxTable(summaries, rateNames).write(fileName, taxPrint)
+	if err != nil {
+		err = fmt.Errorf("failed to write tax report: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	fmt.Println(color.GreenString("\n\nFinished!🎉\nReported the tax of %d sales: %s", len(sales), strings.Join(files, ", ")))
+}
+
+func fetchOutletTaxes() []vend.OutletTaxes {
+	p, err := pbar.CreateMultiBarGroup(1, Token, DomainPrefix)
+	if err != nil {
+		fmt.Println("error creating progress bar group: ", err)
+	}
+	p.FetchDataWithProgressBar("outlet-taxes")
+	p.MultiBarGroupWait()
+
+	for err = range p.ErrorChannel {
+		err = fmt.Errorf("failed to get outlet taxes: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	var outletTaxes []vend.OutletTaxes
+	for data := range p.DataChannel {
+		if records, ok := data.([]vend.OutletTaxes); ok {
+			outletTaxes = records
+		}
+	}
+	return outletTaxes
+}
+
+// outletTaxMap is the tax id of each product at each outlet, keyed by outlet id and product id
+func outletTaxMap(outletTaxes []vend.OutletTaxes) map[string]string {
+	taxIDs := map[string]string{}
+	for _, outletTax := range outletTaxes {
+		if outletTax.OutletID == nil || outletTax.ProductID == nil || outletTax.TaxID == nil || outletTax.DeletedAt != nil {
+			continue
+		}
+		taxIDs[*outletTax.OutletID+"|"+*outletTax.ProductID] = *outletTax.TaxID
+	}
+	return taxIDs
+}
+
+// buildTaxSummaries adds up the tax of the sales by period and outlet, in that order, and returns the names of the
+// rates found in alphabetical order
+func buildTaxSummaries(sales []vend.Sale, data salesReportData, outletTaxIDs map[string]string, period, timeZone string) ([]*taxSummary, []string) {
+	var summaries []*taxSummary
+	byKey := map[string]*taxSummary{}
+	rates := map[string]bool{}
+
+	for _, sale := range sales {
+		saleDate, err := vend.ParseVendDT(*sale.SaleDate, timeZone)
+		if err != nil {
+			fmt.Printf("Error parsing date: %s\n", err)
+			continue
+		}
+		var outletID string
+		if sale.OutletID != nil {
+			outletID = *sale.OutletID
+		}
+		periodName := taxPeriodName(saleDate, period)
+		key := periodName + "|" + outletID
+		summary, ok := byKey[key]
+		if !ok {
+			summary = &taxSummary{Period: periodName, Outlet: data.Outlets[outletID], Rates: map[string]float64{}}
+			byKey[key] = summary
+			summaries = append(summaries, summary)
+		}
+
+		for _, lineitem := range saleLines(sale) {
+			if lineitem.Quantity == nil || lineitem.Price == nil {
+				continue
+			}
+			if lineitem.TaxID == nil && lineitem.ProductID != nil {
+				if taxID, ok := outletTaxIDs[outletID+"|"+*lineitem.ProductID]; ok {
+					lineitem.TaxID = &taxID
+				}
+			}
+			summary.add(lineitem, data)
+		}
+		for name := range summary.Rates {
+			rates[name] = true
+		}
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if summaries[i].Period != summaries[j].Period {
+			return summaries[i].Period < summaries[j].Period
+		}
+		return summaries[i].Outlet < summaries[j].Outlet
+	})
+	var rateNames []string
+	for name := range rates {
+		rateNames = append(rateNames, name)
+	}
+	sort.Strings(rateNames)
+	return summaries, rateNames
+}
+
+// add adds a line to the summary. Lines with a negative quantity are returns.
+func (s *taxSummary) add(lineitem vend.LineItem, data salesReportData) {
+	value := *lineitem.Price * *lineitem.Quantity
+	var lineTax float64
+	if lineitem.Tax != nil {
+		lineTax = *lineitem.Tax * *lineitem.Quantity
+	}
+
+	switch {
+	case *lineitem.Quantity < 0 && lineTax == 0:
+		s.ExemptReturns += value
+	case *lineitem.Quantity < 0:
+		s.TaxableReturns += value
+		s.ReturnTax += lineTax
+	case lineTax == 0:
+		s.ExemptSales += value
+	default:
+		s.TaxableSales += value
+	}
+	if lineTax == 0 {
+		return
+	}
+
+	if lineitem.ID == nil || len(data.LineTaxes[*lineitem.ID]) == 0 {
+		s.EstimatedLines++
+	}
+	components := lineTaxComponents(lineitem, data)
+	if len(components) == 0 {
+		components = []taxComponent{{Name: "Tax", Amount: lineTax}}
+	}
+	for _, component := range components {
+		s.Rates[component.Name] += component.Amount
+	}
+}
+
+func (s taxSummary) row(rateNames []string) []string {
+	row := []string{
+		s.Period,
+		s.Outlet,
+		formatCents(s.TaxableSales),
+		formatCents(s.ExemptSales),
+		formatCents(s.TaxableReturns),
+		formatCents(s.ExemptReturns),
+		formatCents(s.TaxableSales + s.TaxableReturns),
+	}
+	var total float64
+	for _, name := range rateNames {
+		row = append(row, formatCents(s.Rates[name]))
+		total += s.Rates[name]
+	}
+	return append(row, formatCents(s.ReturnTax), formatCents(total))
+}
+
+// taxPeriodName is the period a sale falls in: its date, the Monday of its week or its month
+func taxPeriodName(date time.Time, period string) string {
+	switch period {
+	case "week":
+		return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7)).Format("2006-01-02")
+	case "month":
+		return date.Format("2006-01")
+	}
+	return date.Format("2006-01-02")
+}
+
+// taxTable lays the summaries out with a column per rate and a total row
+func taxTable(summaries []*taxSummary, rateNames []string) reportTable {
+	header := []string{"Period", "Outlet", "Taxable Sales", "Exempt Sales", "Taxable Returns", "Exempt Returns", "Net Taxable"}
+	for _, name := range rateNames {
+		header = append(header, "Tax: "+name)
+	}
+	header = append(header, "Tax on Returns", "Net Tax")
+
+	total := taxSummary{Period: "Total", Rates: map[string]float64{}}
+	var rows [][]string
+	for _, summary := range summaries {
+		rows = append(rows, summary.row(rateNames))
+		total.TaxableSales += summary.TaxableSales
+		total.ExemptSales += summary.ExemptSales
+		total.TaxableReturns += summary.TaxableReturns
+		total.ExemptReturns += summary.ExemptReturns
+		total.ReturnTax += summary.ReturnTax
+		total.EstimatedLines += summary.EstimatedLines
+		for name, amount := range summary.Rates {
+			total.Rates[name] += amount
+		}
+	}
+	rows = append(rows, total.row(rateNames))
+
+	notes := []string{
+		fmt.Sprintf("%s to %s, by %s", dateFrom, dateTo, taxPeriod),
+		"Amounts exclude tax and are after discounts, tax per rate is net of returns and as Vend recorded it on each line",
+	}
+	if total.EstimatedLines > 0 {
+		notes = append(notes, fmt.Sprintf("Tax per rate is estimated for %d line(s) with no tax components, by splitting their tax in proportion to each rate", total.EstimatedLines))
+	}
+	return reportTable{
+		Title:       fmt.Sprintf("Tax Report %s", DomainPrefix),
+		Notes:       notes,
+		Header:      header,
+		Rows:        rows,
+		NumericFrom: 2,
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/govend/vend"
+)
+
+func TestBuildTaxSummaries(t *testing.T) {
+	outletID, productID, exemptID, otherID := "o1", "p1", "p2", "p3"
+	gstID, zeroID, gstRateID, pstRateID := "t1", "t0", "r1", "r2"
+	gst, pst, taxName, zeroName := "GST", "PST", "GST + PST", "No Tax"
+	gstRate, pstRate, zeroRate := 0.05, 0.07, 0.0
+
+	sales := []vend.Sale{
+		testSale(saleFixture{Outlet: outletID, Date: "2024-03-01T01:00:00Z", Lines: []lineFixture{
+			{ID: "l1", Product: productID, TaxID: gstID, Quantity: 2, Price: 10, Tax: 1.2},
+			{Product: exemptID, TaxID: zeroID, Quantity: 1, Price: 5},
+		}}),
+		testSale(saleFixture{Outlet: outletID, Date: "2024-03-20T01:00:00Z", Lines: []lineFixture{
+			{Product: productID, TaxID: gstID, Quantity: -1, Price: 10, Tax: 1.2},
+		}}),
+		// no tax on the line, the outlet's tax for the product is used
+		testSale(saleFixture{Outlet: outletID, Date: "2024-04-02T01:00:00Z", Lines: []lineFixture{
+			{Product: otherID, Quantity: 1, Price: 100, Tax: 12},
+		}}),
+	}
+	data := newSalesReportData(map[string]string{outletID: "Newmarket"}, nil, nil, nil, nil, nil, map[string]vend.Taxes{
+		gstID:  {Name: &taxName, TaxRates: []vend.TaxRates{{ID: &gstRateID, Name: &gst, Rate: &gstRate}, {ID: &pstRateID, Name: &pst, Rate: &pstRate}}},
+		zeroID: {Name: &zeroName, TaxRates: []vend.TaxRates{{Name: &zeroName, Rate: &zeroRate}}},
+	})
+	// a compound tax recorded by Vend, which a split by rate would get wrong
+	data.LineTaxes = map[string][]lineTaxComponent{"l1": {{RateID: gstRateID, TotalTax: 0.9}, {RateID: pstRateID, TotalTax: 1.5}}}
+	outletTaxes := outletTaxMap([]vend.OutletTaxes{{OutletID: &outletID, ProductID: &otherID, TaxID: &gstID}})
+
+	summaries, rateNames := buildTaxSummaries(sales, data, outletTaxes, "month", "UTC")
+	assert.Equal(t, []string{"GST", "PST"}, rateNames)
+	assert.Len(t, summaries, 2)
+
+	table := taxTable(summaries, rateNames)
+	assert.Equal(t, []string{"Period", "Outlet", "Taxable Sales", "Exempt Sales", "Taxable Returns", "Exempt Returns",
+		"Net Taxable", "Tax: GST", "Tax: PST", "Tax on Returns", "Net Tax"}, table.Header)
+	assert.Equal(t, [][]string{
+		{"2024-03", "Newmarket", "20.00", "5.00", "-10.00", "0.00", "10.00", "0.40", "0.80", "-1.20", "1.20"},
+		{"2024-04", "Newmarket", "100.00", "0.00", "0.00", "0.00", "100.00", "5.00", "7.00", "0.00", "12.00"},
+		{"Total", "", "120.00", "5.00", "-10.00", "0.00", "110.00", "5.40", "7.80", "-1.20", "13.20"},
+	}, table.Rows)
+	assert.Contains(t, table.Notes, "Tax per rate is estimated for 2 line(s) with no tax components, by splitting their tax in proportion to each rate")
+
+	summaries, _ = buildTaxSummaries(sales[:2], data, outletTaxes, "week", "UTC")
+	assert.Equal(t, "2024-02-26", summaries[0].Period)
+	assert.Equal(t, "2024-03-18", summaries[1].Period)
+}
@@ -0,0 +1,420 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vend/vend-cli/pkg/csvparser"
+	"github.com/vend/vend-cli/pkg/messenger"
+	pbar "github.com/vend/vend-cli/pkg/progressbar"
+	"github.com/vend/vend-cli/pkg/snapshot"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vend/govend/vend"
+)
+
+// number of differences printed per sale in the restore preview
+const restorePreviewLines = 20
+
+var (
+	errSaleNotFound     = errors.New("sale not found. check that your sale_id is valid")
+	errCustomerNotFound = errors.New("customer not found. check that your customer_id is valid")
+)
+
+type FailedRestoreRequest struct {
+	ID     string
+	Entity string
+	Reason string
+}
+
+// saleRestore is a snapshotted sale or customer alongside the state it is currently in
+type saleRestore struct {
+	Snapshot snapshot.Snapshot
+	Exists   bool
+	Diff     []string
+}
+
+// Command config
+var (
+	restoreSaleIDs []string
+	restorePreview bool
+
+	restoreCmd = &cobra.Command{
+		Use:   "restore",
+		Short: "Restore sales or customers from a snapshot file",
+		Long: fmt.Sprintf(`
+Posts sales and customers back to the state they were in before vendcli changed them.
+
+Commands that change sales (update-sale-user-id, update-sale-invoice-number, void-sales and
+fix-errored-sales in overwrite mode) save every sale to a snapshot file before changing it:
+DOMAINPREFIX_COMMAND_snapshots_TIMESTAMP.jsonl
+export-customer-value saves the customer fields it writes to the same kind of file.
+
+restore compares each snapshot with the sale or customer as it is now and prints the differences
+before asking for confirmation. Those that already match their snapshot are skipped. Restore
+everything in the file, or pick some with --sales. The current state is snapshotted again before
+restoring, so a restore can itself be restored.
+
+Example:
+%s
+%s`,
+			color.GreenString("vendcli restore -d DOMAINPREFIX -t TOKEN -f DOMAINPREFIX_void-sales_snapshots_1700000000.jsonl --preview"),
+			color.GreenString("vendcli restore -d DOMAINPREFIX -t TOKEN -f DOMAINPREFIX_void-sales_snapshots_1700000000.jsonl --sales SALEID1,SALEID2")),
+		Annotations: map[string]string{mutatingAnnotation: "true"},
+		Run: func(cmd *cobra.Command, args []string) {
+			restoreSales()
+		},
+	}
+)
+
+func init() {
+	// Flags
+	restoreCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The snapshot file: DOMAINPREFIX_COMMAND_snapshots_TIMESTAMP.jsonl")
+	restoreCmd.MarkFlagRequired("Filename")
+	restoreCmd.Flags().StringSliceVarP(&restoreSaleIDs, "sales", "s", nil, "Only restore these sale or customer IDs (comma separated)")
+	restoreCmd.Flags().BoolVar(&restorePreview, "preview", false, "Print the differences without restoring anything")
+
+	rootCmd.AddCommand(restoreCmd)
+}
+
+func restoreSales() {
+
+	// Create new Vend Client.
+	vc := vend.NewClient(Token, DomainPrefix, "")
+	vendClient = &vc
+
+	fmt.Println("\nReading snapshot file...")
+	snapshots, err := snapshot.Read(FilePath)
+	if err != nil {
+		err = fmt.Errorf("failed to read snapshots from the file: %s, error: %w", FilePath, err)
+		messenger.ExitWithError(err)
+	}
+
+	selected, err := selectSnapshots(snapshots, DomainPrefix, restoreSaleIDs)
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	entities := snapshotEntities(selected)
+	fmt.Printf("\nComparing %d %s with their current state...\n", len(selected), entities)
+	restores := compareSnapshots(selected)
+
+	var toRestore []saleRestore
+	for _, restore := range restores {
+		if len(restore.Diff) == 0 {
+			continue
+		}
+		toRestore = append(toRestore, restore)
+		printRestoreDiff(restore)
+	}
+
+	if len(toRestore) == 0 {
+		skipRun()
+		fmt.Println(color.GreenString("\nEverything already matches its snapshot, nothing to restore"))
+		return
+	}
+	fmt.Printf("\n%d of %d %s differ from their snapshot\n", len(toRestore), len(restores), entities)
+
+	if restorePreview {
+		skipRun()
+		fmt.Println(color.YellowString("\n--preview passed, nothing was restored"))
+		return
+	}
+
+	ids := make([]string, 0, len(toRestore))
+	diffs := map[string]int{}
+	for _, restore := range toRestore {
+		ids = append(ids, restore.Snapshot.ID())
+		diffs[restore.Snapshot.ID()] = len(restore.Diff)
+	}
+	confirmDestructiveAction(destructiveAction{
+		Command: "restore",
+		Entity:  entities,
+		IDs:     ids,
+		Describe: func(id string) string {
+			return fmt.Sprintf("%d differences", diffs[id])
+		},
+	})
+
+	startSnapshots("restore", entities)
+	defer stopSnapshots()
+
+	fmt.Printf("\nRestoring %s...\n", entities)
+	failedRequests := postSaleRestores(toRestore)
+
+	var failureFile string
+	if len(failedRequests) > 0 {
+		fmt.Println(color.RedString("\n\nThere were some errors. Writing failures to csv.."))
+		failureFile = fmt.Sprintf("%s_failed_restore_requests_%v.csv", DomainPrefix, time.Now().Unix())
+		err := csvparser.WriteErrorCSV(failureFile, failedRequests)
+		if err != nil {
+			messenger.ExitWithError(err)
+		}
+	}
+	finishRun(len(toRestore), len(toRestore)-len(failedRequests), failureFile)
+
+	fmt.Println(color.GreenString("\n\nFinished! 🎉\nRestored %d out of %d %s", len(toRestore)-len(failedRequests), len(toRestore), entities))
+}
+
+// snapshotEntities names what a set of snapshots is of, for messages
+func snapshotEntities(snapshots []snapshot.Snapshot) string {
+	var sales, customers bool
+	for _, s := range snapshots {
+		if s.CustomerID != "" {
+			customers = true
+		} else {
+			sales = true
+		}
+	}
+	switch {
+	case sales && customers:
+		return "sales and customers"
+	case customers:
+		return "customers"
+	}
+	return "sales"
+}
+
+// selectSnapshots picks the earliest snapshot of each sale or customer, optionally limited to the given IDs.
+// The earliest snapshot is the state it was in before vendcli first touched it.
+func selectSnapshots(snapshots []snapshot.Snapshot, domainPrefix string, saleIDs []string) ([]snapshot.Snapshot, error) {
+	wanted := map[string]bool{}
+	for _, id := range saleIDs {
+		if id = strings.TrimSpace(id); id != "" {
+			wanted[id] = true
+		}
+	}
+
+	var selected []snapshot.Snapshot
+	seen := map[string]bool{}
+	for _, s := range snapshots {
+		if !strings.EqualFold(s.Domain, domainPrefix) {
+			return nil, fmt.Errorf("snapshot of %s %s was taken on '%s', not '%s'", s.Entity(), s.ID(), s.Domain, domainPrefix)
+		}
+		if seen[s.ID()] || (len(wanted) > 0 && !wanted[s.ID()]) {
+			continue
+		}
+		seen[s.ID()] = true
+		selected = append(selected, s)
+	}
+
+	for id := range wanted {
+		if !seen[id] {
+			return nil, fmt.Errorf("%s is not in the snapshot file", id)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no snapshots found")
+	}
+	return selected, nil
+}
+
+// compareSnapshots fetches the current state of each snapshotted sale or customer and works out what restoring would change
+func compareSnapshots(snapshots []snapshot.Snapshot) []saleRestore {
+	p := pbar.CreateSingleBar()
+	bar, err := p.AddProgressBar(len(snapshots), "Comparing")
+	if err != nil {
+		fmt.Printf("Error creating progress bar:%s\n", err)
+	}
+
+	var restores []saleRestore
+	for _, s := range snapshots {
+		bar.Increment()
+		restore := saleRestore{Snapshot: s}
+
+		current, original, err := fetchSnapshotCurrent(s)
+		switch {
+		case errors.Is(err, errSaleNotFound):
+			restore.Diff = []string{"sale no longer exists and will be re-created"}
+		case errors.Is(err, errCustomerNotFound):
+			restore.Diff = []string{"customer no longer exists and can not be restored"}
+		case err != nil:
+			restore.Exists = true
+			restore.Diff = []string{fmt.Sprintf("could not fetch the current %s (%s), it will be overwritten", s.Entity(), err)}
+		default:
+			restore.Exists = true
+			restore.Diff, err = diffSaleJSON(current, original)
+			if err != nil {
+				restore.Diff = []string{fmt.Sprintf("could not compare with the current %s (%s), it will be overwritten", s.Entity(), err)}
+			}
+		}
+		restores = append(restores, restore)
+	}
+	p.Wait()
+	return restores
+}
+
+// fetchSnapshotCurrent returns the current state of a snapshotted sale or customer, and the snapshot to compare it with
+func fetchSnapshotCurrent(s snapshot.Snapshot) (json.RawMessage, json.RawMessage, error) {
+	if s.CustomerID == "" {
+		current, err := fetchRegisterSale(s.SaleID)
+		return current, s.Sale, err
+	}
+	fields, err := snapshotCustomerFields(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	current, err := fetchCustomerFields(s.CustomerID, fields)
+	return current, s.Customer, err
+}
+
+// snapshotCustomerFields lists the fields a customer snapshot holds, other than its id
+func snapshotCustomerFields(s snapshot.Snapshot) ([]string, error) {
+	var values map[string]string
+	if err := json.Unmarshal(s.Customer, &values); err != nil {
+		return nil, fmt.Errorf("customer snapshot is malformed: %w", err)
+	}
+	var fields []string
+	for field := range values {
+		if field != "id" {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+func printRestoreDiff(restore saleRestore) {
+	fmt.Printf("\n%s  %s\n", color.CyanString(restore.Snapshot.ID()),
+		color.YellowString("snapshot taken by %s at %s", restore.Snapshot.Command, restore.Snapshot.Timestamp.Local().Format("2006-01-02 15:04:05")))
+
+	lines := restore.Diff
+	if len(lines) > restorePreviewLines {
+		lines = lines[:restorePreviewLines]
+	}
+	for _, line := range lines {
+		fmt.Printf("   %s\n", line)
+	}
+	if len(restore.Diff) > len(lines) {
+		fmt.Printf("   ... and %d more\n", len(restore.Diff)-len(lines))
+	}
+}
+
+// postSaleRestores posts each sale and customer snapshot back to Vend, snapshotting the current state first
+func postSaleRestores(restores []saleRestore) []FailedRestoreRequest {
+	p := pbar.CreateSingleBar()
+	bar, err := p.AddProgressBar(len(restores), "Restoring")
+	if err != nil {
+		fmt.Printf("Error creating progress bar:%s\n", err)
+	}
+
+	var failedRequests []FailedRestoreRequest
+	for _, restore := range restores {
+		bar.Increment()
+		id, entity := restore.Snapshot.ID(), restore.Snapshot.Entity()
+
+		url := fmt.Sprintf("https://%s.vendhq.com/api/register_sales", DomainPrefix)
+		body := restore.Snapshot.Sale
+		var err error
+		if restore.Snapshot.CustomerID != "" {
+			url = fmt.Sprintf("https://%s.vendhq.com/api/customers", DomainPrefix)
+			body = restore.Snapshot.Customer
+			err = snapshotExistingCustomer(restore.Snapshot)
+		} else {
+			err = snapshotExistingSale(id)
+		}
+		if err != nil {
+			failedRequests = append(failedRequests, FailedRestoreRequest{ID: id, Entity: entity, Reason: err.Error()})
+			continue
+		}
+
+		resp, err := vendClient.MakeRequest("POST", url, body)
+		if err != nil {
+			err = fmt.Errorf("error restoring %s: %s, response: %s", entity, err, string(resp))
+			failedRequests = append(failedRequests, FailedRestoreRequest{ID: id, Entity: entity, Reason: err.Error()})
+			continue
+		}
+	}
+	p.Wait()
+	return failedRequests
+}
+
+// diffSaleJSON lists the fields that differ between the current sale and its snapshot as
+// "path: current -> snapshot", with nested fields and line items joined by dots e.g. register_sale_products.0.price
+func diffSaleJSON(current, original json.RawMessage) ([]string, error) {
+	var currentValue, originalValue interface{}
+	if err := json.Unmarshal(current, &currentValue); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(original, &originalValue); err != nil {
+		return nil, err
+	}
+
+	var diff []string
+	diffValues("", currentValue, originalValue, &diff)
+	return diff, nil
+}
+
+func diffValues(path string, current, original interface{}, diff *[]string) {
+	currentMap, currentIsMap := current.(map[string]interface{})
+	originalMap, originalIsMap := original.(map[string]interface{})
+	if currentIsMap && originalIsMap {
+		keys := map[string]bool{}
+		for key := range currentMap {
+			keys[key] = true
+		}
+		for key := range originalMap {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+		for _, key := range sorted {
+			diffValues(joinDiffPath(path, key), currentMap[key], originalMap[key], diff)
+		}
+		return
+	}
+
+	currentSlice, currentIsSlice := current.([]interface{})
+	originalSlice, originalIsSlice := original.([]interface{})
+	if currentIsSlice && originalIsSlice {
+		length := len(currentSlice)
+		if len(originalSlice) > length {
+			length = len(originalSlice)
+		}
+		for i := 0; i < length; i++ {
+			var c, o interface{}
+			if i < len(currentSlice) {
+				c = currentSlice[i]
+			}
+			if i < len(originalSlice) {
+				o = originalSlice[i]
+			}
+			diffValues(joinDiffPath(path, fmt.Sprint(i)), c, o, diff)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(current, original) {
+		*diff = append(*diff, fmt.Sprintf("%s: %s -> %s", path, formatDiffValue(current), formatDiffValue(original)))
+	}
+}
+
+func joinDiffPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func formatDiffValue(value interface{}) string {
+	if value == nil {
+		return "(none)"
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	if len(encoded) > 60 {
+		return string(encoded[:57]) + "..."
+	}
+	return string(encoded)
+}
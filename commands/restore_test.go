@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/vend-cli/pkg/snapshot"
+)
+
+func TestDiffSaleJSON(t *testing.T) {
+	current := json.RawMessage(`{"id":"1","status":"VOIDED","register_sale_products":[{"price":10},{"price":5}]}`)
+	original := json.RawMessage(`{"id":"1","status":"CLOSED","register_sale_products":[{"price":12}],"note":"hi"}`)
+
+	diff, err := diffSaleJSON(current, original)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		`note: (none) -> "hi"`,
+		`register_sale_products.0.price: 10 -> 12`,
+		`register_sale_products.1: {"price":5} -> (none)`,
+		`status: "VOIDED" -> "CLOSED"`,
+	}, diff)
+
+	diff, err = diffSaleJSON(original, original)
+	assert.Nil(t, err)
+	assert.Empty(t, diff)
+}
+
+func TestSelectSnapshots(t *testing.T) {
+	snapshots := []snapshot.Snapshot{
+		{Domain: "store", SaleID: "a", Command: "void-sales"},
+		{Domain: "store", SaleID: "b", Command: "void-sales"},
+		{Domain: "store", SaleID: "a", Command: "restore"},
+		{Domain: "store", CustomerID: "c1", Command: "export-customer-value", Customer: json.RawMessage(`{"id":"c1","custom_field_2":"Lost"}`)},
+	}
+
+	selected, err := selectSnapshots(snapshots, "Store", nil)
+	assert.Nil(t, err)
+	assert.Len(t, selected, 3)
+	assert.Equal(t, "void-sales", selected[0].Command)
+	assert.Equal(t, "sales and customers", snapshotEntities(selected))
+
+	fields, err := snapshotCustomerFields(selected[2])
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"custom_field_2"}, fields)
+
+	selected, err = selectSnapshots(snapshots, "store", []string{"b"})
+	assert.Nil(t, err)
+	assert.Len(t, selected, 1)
+	assert.Equal(t, "b", selected[0].SaleID)
+
+	_, err = selectSnapshots(snapshots, "store", []string{"c"})
+	assert.NotNil(t, err)
+
+	_, err = selectSnapshots(snapshots, "otherstore", nil)
+	assert.NotNil(t, err)
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/fatih/color"
 	homedir "github.com/mitchellh/go-homedir"
@@ -13,6 +14,16 @@ import (
 
 const version = "1.8"
 
+// Command annotations
+const (
+	// mutatingAnnotation marks commands that change data in a store, these are recorded in the run log
+	mutatingAnnotation = "mutating"
+	// offlineAnnotation marks commands that do not need store credentials
+	offlineAnnotation = "offline"
+	// tokenEnvVar is read when no -t flag is given, so the token can be kept off the command line
+	tokenEnvVar = "VENDCLI_TOKEN"
+)
+
 // Variables for Client authentication details and flags
 var (
 	DomainPrefix string
@@ -32,24 +43,59 @@ var rootCmd = &cobra.Command{
 	Use:     "vendcli",
 	Version: version,
 	Short: fmt.Sprintf(`
-%s`, logo)}
+%s`, logo),
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if Token == "" {
+			Token = viper.GetString("token")
+		}
+		checkCredentialFlags(cmd)
+		if cmd.Annotations[mutatingAnnotation] == "true" {
+			startRun(cmd)
+		}
+	},
+}
 
 func init() {
 	cobra.OnInitialize(initConfig)
 
 	// Get store info from command line flags.
 	rootCmd.PersistentFlags().StringVarP(&DomainPrefix, "Domain", "d", "", "The Vend store name (prefix in xxxx.vendhq.com)")
-	rootCmd.PersistentFlags().StringVarP(&Token, "Token", "t", "", "API Access Token for the store, Setup -> Personal Tokens.")
-	rootCmd.MarkPersistentFlagRequired("Domain")
-	rootCmd.MarkPersistentFlagRequired("Token")
+	rootCmd.PersistentFlags().StringVarP(&Token, "Token", "t", "", "API Access Token for the store, Setup -> Personal Tokens. Read from "+tokenEnvVar+" if not set.")
 }
 
 func Execute() {
+	// record runs that exit early before handing the panic back to main
+	defer func() {
+		if r := recover(); r != nil {
+			abortRun(r)
+			panic(r)
+		}
+	}()
+
 	if err := rootCmd.Execute(); err != nil {
 		messenger.ExitWithError(err)
 	}
 }
 
+// checkCredentialFlags makes sure the store flags are set for commands that talk to Vend
+func checkCredentialFlags(cmd *cobra.Command) {
+	if cmd.Annotations[offlineAnnotation] == "true" || cmd.Name() == "help" {
+		return
+	}
+
+	var missing []string
+	if DomainPrefix == "" {
+		missing = append(missing, `"Domain"`)
+	}
+	if Token == "" {
+		missing = append(missing, `"Token"`)
+	}
+	if len(missing) > 0 {
+		err := fmt.Errorf("required flag(s) %s not set", strings.Join(missing, ", "))
+		messenger.ExitWithError(err)
+	}
+}
+
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
 	if cfgFile != "" {
@@ -68,6 +114,7 @@ func initConfig() {
 	}
 
 	viper.AutomaticEnv() // read in environment variables that match
+	viper.BindEnv("token", tokenEnvVar)
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
@@ -0,0 +1,398 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vend/vend-cli/pkg/messenger"
+	"github.com/vend/vend-cli/pkg/runbook"
+	"github.com/vend/vend-cli/pkg/runlog"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// Step statuses shown in the runbook summary
+const (
+	stepSucceeded = "succeeded"
+	stepFailed    = "failed"
+	stepSkipped   = "skipped"
+)
+
+type stepResult struct {
+	ID       string
+	Status   string
+	Attempts int
+	Duration time.Duration
+	Outputs  []string
+	// FailureFile is the failures CSV the step's run recorded in the run log
+	FailureFile string
+	Reason      string
+}
+
+// Command config
+var (
+	runOutputDir string
+	runCheck     bool
+
+	runCmd = &cobra.Command{
+		Use:   "run RUNBOOK.yaml",
+		Short: "Run a sequence of commands from a runbook file",
+		Long: fmt.Sprintf(`
+Runs the steps of a runbook file in order, against one store.
+
+Domain, token and output folder are set once at the top of the runbook (or with -d, -t and -o).
+${NAME} in the domain and token is read from the environment. Every step runs inside the output
+folder, and the files a step creates are its outputs. Later steps refer to them with:
+  {{steps.ID.output}}            the file the step created (the failures CSV its run recorded is ignored)
+  {{steps.ID.output:PATTERN}}    the file matching PATTERN, when a step creates several
+  {{domain}} {{output_dir}}
+
+A step fails when its command exits with an error, or records failed rows in the run log unless allow_row_failures
+is set. The token is passed to the commands in the `+tokenEnvVar+` environment variable, not on their command line.
+on_failure decides what happens next: stop (default), continue, or retry (retries times, default 1).
+exec steps run any other program, e.g. a script that transforms an export before it is imported.
+
+Example runbook:
+  name: product codes migration
+  domain: ${VEND_DOMAIN}
+  token: ${VEND_TOKEN}
+  output_dir: ./migration
+  steps:
+    - id: export
+      command: export-products
+    - id: transform
+      exec: [python3, make_codes.py, "{{steps.export.output}}", codes.csv]
+    - id: import
+      command: import-product-codes
+      flags:
+        Filename: "{{steps.transform.output}}"
+    - id: verify
+      command: export-products
+      on_failure: continue
+
+Example:
+%s`, color.GreenString("vendcli run migration.yaml")),
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{offlineAnnotation: "true"},
+		Run: func(cmd *cobra.Command, args []string) {
+			runRunbook(args[0])
+		},
+	}
+)
+
+func init() {
+	// Flags
+	runCmd.Flags().StringVarP(&runOutputDir, "output", "o", "", "Folder for the files the steps create, overrides output_dir")
+	runCmd.Flags().BoolVar(&runCheck, "check", false, "Validate the runbook and print the steps without running them")
+
+	rootCmd.AddCommand(runCmd)
+}
+
+func runRunbook(path string) {
+
+	book, err := runbook.Load(path)
+	if err != nil {
+		err = fmt.Errorf("invalid runbook %s: %w", path, err)
+		messenger.ExitWithError(err)
+	}
+
+	// command line credentials win over the runbook
+	if DomainPrefix != "" {
+		book.Domain = DomainPrefix
+	}
+	if Token != "" {
+		book.Token = Token
+	}
+	if book.Domain == "" || book.Token == "" {
+		err = fmt.Errorf("the runbook needs a domain and token, set them in the file or pass -d and -t")
+		messenger.ExitWithError(err)
+	}
+
+	for _, step := range book.Steps {
+		if step.Command == "" {
+			continue
+		}
+		if found, _, err := rootCmd.Find([]string{step.Command}); err != nil || found == rootCmd || found.Name() == "run" {
+			err = fmt.Errorf("step %q: unknown command %q", step.ID, step.Command)
+			messenger.ExitWithError(err)
+		}
+	}
+
+	outputDir := runbookOutputDir(book)
+
+	fmt.Printf("\nRunbook: %s\n", color.CyanString(book.Name))
+	fmt.Printf("Domain:  %s\n", color.RedString(book.Domain))
+	fmt.Printf("Output:  %s\n", outputDir)
+	for i, step := range book.Steps {
+		fmt.Printf("  %d. %s  %s\n", i+1, step.ID, color.YellowString(describeStep(step)))
+	}
+	if runCheck {
+		fmt.Println(color.GreenString("\nRunbook is valid, nothing was run"))
+		return
+	}
+
+	if err = os.MkdirAll(outputDir, 0755); err != nil {
+		err = fmt.Errorf("failed to create output folder: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		err = fmt.Errorf("failed to find vendcli executable: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	outputs := runbook.Outputs{}
+	var results []stepResult
+	stopped := false
+	for i, step := range book.Steps {
+		if stopped {
+			results = append(results, stepResult{ID: step.ID, Status: stepSkipped})
+			continue
+		}
+
+		fmt.Println(color.CyanString("\n━━ Step %d of %d: %s ━━", i+1, len(book.Steps), step.ID))
+		result := runStep(book, step, executable, outputDir, outputs)
+		results = append(results, result)
+
+		if result.Status == stepSucceeded {
+			outputs[step.ID] = runbook.StepOutput{Files: result.Outputs, FailureFile: result.FailureFile}
+			continue
+		}
+		fmt.Println(color.RedString("\nStep %s failed: %s", step.ID, result.Reason))
+		if step.OnFailure != runbook.OnFailureContinue {
+			stopped = true
+		}
+	}
+
+	printRunbookSummary(results)
+
+	for _, result := range results {
+		if result.Status != stepSucceeded {
+			err = fmt.Errorf("runbook %s did not complete, see the summary above", book.Name)
+			messenger.ExitWithError(err)
+		}
+	}
+	fmt.Println(color.GreenString("\nFinished! 🎉\nAll %d steps succeeded", len(results)))
+}
+
+// runStep runs a step, retrying it if its failure policy asks for that
+func runStep(book *runbook.Runbook, step runbook.Step, executable, outputDir string, outputs runbook.Outputs) (result stepResult) {
+	result.ID = step.ID
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+
+	var args, env []string
+	var err error
+	if step.Command != "" {
+		args, err = book.Args(step, outputDir, outputs)
+		if err == nil {
+			args = append([]string{executable}, append(args, "-d", book.Domain)...)
+			env = []string{tokenEnvVar + "=" + book.Token}
+		}
+	} else {
+		args, err = book.ExecArgs(step, outputDir, outputs)
+	}
+	if err != nil {
+		result.Status = stepFailed
+		result.Reason = err.Error()
+		return result
+	}
+
+	attempts := 1
+	if step.OnFailure == runbook.OnFailureRetry {
+		attempts += step.Retries
+	}
+
+	for result.Attempts < attempts {
+		result.Attempts++
+		if result.Attempts > 1 {
+			fmt.Println(color.YellowString("\nRetrying step %s (attempt %d of %d)", step.ID, result.Attempts, attempts))
+		}
+
+		before, err := listFiles(outputDir)
+		if err != nil {
+			result.Status = stepFailed
+			result.Reason = err.Error()
+			return result
+		}
+
+		logged, err := runLogLength()
+		if err != nil {
+			result.Status = stepFailed
+			result.Reason = err.Error()
+			return result
+		}
+
+		runErr := runStepProcess(args, env, outputDir)
+
+		after, err := listFiles(outputDir)
+		if err != nil {
+			result.Status = stepFailed
+			result.Reason = err.Error()
+			return result
+		}
+		result.Outputs = newFiles(before, after)
+
+		var run *runlog.Run
+		if step.Command != "" {
+			if run, err = loggedStepRun(logged, book.Domain, step.Command); err != nil {
+				result.Status = stepFailed
+				result.Reason = err.Error()
+				return result
+			}
+		}
+		result.FailureFile = ""
+		if run != nil && run.FailureFile != "" {
+			result.FailureFile = run.FailureFile
+			if !filepath.IsAbs(result.FailureFile) {
+				result.FailureFile = filepath.Join(outputDir, result.FailureFile)
+			}
+		}
+
+		switch {
+		case runErr != nil:
+			result.Status = stepFailed
+			result.Reason = runErr.Error()
+		case !step.AllowRowFailures && run != nil && run.Failed > 0:
+			result.Status = stepFailed
+			result.Reason = fmt.Sprintf("%d of %d rows failed, see %s", run.Failed, run.Attempted, filepath.Base(run.FailureFile))
+		default:
+			result.Status = stepSucceeded
+			result.Reason = ""
+			return result
+		}
+	}
+	return result
+}
+
+func runStepProcess(args, env []string, dir string) error {
+	process := exec.Command(args[0], args[1:]...)
+	process.Dir = dir
+	process.Env = append(os.Environ(), env...)
+	process.Stdin = os.Stdin
+	process.Stdout = os.Stdout
+	process.Stderr = os.Stderr
+	return process.Run()
+}
+
+// runbookOutputDir returns the absolute output folder, a new timestamped folder next to the runbook by default
+func runbookOutputDir(book *runbook.Runbook) string {
+	dir := book.OutputDir
+	if runOutputDir != "" {
+		dir = runOutputDir
+	} else if dir == "" {
+		name := strings.ReplaceAll(strings.ToLower(book.Name), " ", "-")
+		dir = fmt.Sprintf("%s_%s_%v", book.Domain, name, time.Now().Unix())
+	}
+
+	// relative output_dir in the file is relative to the runbook, on the command line to where vendcli is run
+	if !filepath.IsAbs(dir) {
+		base := book.Dir
+		if runOutputDir != "" {
+			base, _ = os.Getwd()
+		}
+		dir = filepath.Join(base, dir)
+	}
+	return dir
+}
+
+func describeStep(step runbook.Step) string {
+	if step.Command == "" {
+		return strings.Join(step.Exec, " ")
+	}
+	description := step.Command
+	names := make([]string, 0, len(step.Flags))
+	for flag := range step.Flags {
+		names = append(names, flag)
+	}
+	sort.Strings(names)
+	for _, flag := range names {
+		description += fmt.Sprintf(" --%s %s", strings.TrimLeft(flag, "-"), step.Flags[flag])
+	}
+	if step.OnFailure != runbook.OnFailureStop {
+		description += fmt.Sprintf(" (on failure: %s)", step.OnFailure)
+	}
+	return description
+}
+
+// listFiles returns the modification time of every file in a folder
+func listFiles(dir string) (map[string]time.Time, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list output folder: %w", err)
+	}
+	files := map[string]time.Time{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files[filepath.Join(dir, entry.Name())] = entry.ModTime()
+		}
+	}
+	return files, nil
+}
+
+// newFiles returns the files that were created or rewritten between two listings
+func newFiles(before, after map[string]time.Time) []string {
+	var created []string
+	for file, modified := range after {
+		if previous, ok := before[file]; !ok || modified.After(previous) {
+			created = append(created, file)
+		}
+	}
+	sort.Strings(created)
+	return created
+}
+
+// runLogLength returns how many runs the run log holds, so the run a step adds can be found after it
+func runLogLength() (int, error) {
+	path, err := runlog.DefaultPath()
+	if err != nil {
+		return 0, fmt.Errorf("failed to find the run log: %w", err)
+	}
+	runs, err := runlog.Read(path)
+	if err != nil {
+		return 0, err
+	}
+	return len(runs), nil
+}
+
+// loggedStepRun returns the run a step's command added to the run log, or nil for commands that do not record runs
+func loggedStepRun(logged int, domain, command string) (*runlog.Run, error) {
+	path, err := runlog.DefaultPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the run log: %w", err)
+	}
+	runs, err := runlog.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(runs) - 1; i >= logged; i-- {
+		if runs[i].Domain == domain && runs[i].Command == command {
+			return &runs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func printRunbookSummary(results []stepResult) {
+	fmt.Println(color.CyanString("\n━━ Summary ━━"))
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "STEP\tSTATUS\tATTEMPTS\tDURATION\tOUTPUTS")
+	for _, result := range results {
+		var outputs []string
+		for _, file := range result.Outputs {
+			outputs = append(outputs, filepath.Base(file))
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%s\t%s\n", result.ID, result.Status, result.Attempts,
+			result.Duration.Round(time.Second), strings.Join(outputs, ", "))
+	}
+	writer.Flush()
+}
@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/viper"
+	"github.com/vend/govend/vend"
+	"github.com/vend/vend-cli/pkg/csvparser"
+	"github.com/vend/vend-cli/pkg/messenger"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// number of entities shown in the confirmation summary
+const confirmationSampleSize = 5
+
+// Safety flags shared by every destructive command
+var (
+	assumeYes         bool
+	iKnowIsProduction bool
+)
+
+// destructiveAction describes a command that is about to delete or void data in a store.
+// Describe is optional and is used to turn a sample of the ids into something a human recognises.
+type destructiveAction struct {
+	Command  string
+	Entity   string
+	IDs      []string
+	Describe func(id string) string
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Skip the confirmation prompt for destructive commands")
+	rootCmd.PersistentFlags().BoolVar(&iKnowIsProduction, "i-know-this-is-production", false, "Allow destructive commands against a protected store")
+}
+
+// confirmDestructiveAction prints an impact summary and asks the user to confirm before
+// anything is sent to Vend. Exits if the store is protected or the user does not confirm.
+func confirmDestructiveAction(action destructiveAction) {
+
+	if isProtectedDomain(DomainPrefix, protectedDomains()) && !iKnowIsProduction {
+		err := fmt.Errorf("'%s' is a protected store. %s will not run against it unless --i-know-this-is-production is passed",
+			DomainPrefix, action.Command)
+		messenger.ExitWithError(err)
+	}
+
+	fmt.Println(color.YellowString("\nYou are about to run a destructive command"))
+	fmt.Printf("  Domain:   %s\n", color.RedString(DomainPrefix))
+	fmt.Printf("  Command:  %s\n", action.Command)
+	fmt.Printf("  Rows:     %d %s\n", len(action.IDs), action.Entity)
+
+	sample := action.IDs
+	if len(sample) > confirmationSampleSize {
+		sample = sample[:confirmationSampleSize]
+	}
+	if len(sample) > 0 {
+		fmt.Println("  Sample:")
+		for _, id := range sample {
+			if action.Describe != nil {
+				fmt.Printf("   - %s  %s\n", id, color.CyanString(action.Describe(id)))
+			} else {
+				fmt.Printf("   - %s\n", id)
+			}
+		}
+		if len(action.IDs) > len(sample) {
+			fmt.Printf("   ... and %d more\n", len(action.IDs)-len(sample))
+		}
+	}
+
+	if assumeYes {
+		fmt.Println(color.YellowString("\n--yes passed, skipping confirmation"))
+		return
+	}
+
+	input, err := confirmationInput()
+	if err != nil {
+		err = fmt.Errorf("refusing to run %s without confirmation, %s. Pass --yes to run non-interactively", action.Command, err)
+		messenger.ExitWithError(err)
+	}
+	defer input.Close()
+
+	fmt.Printf("\nType the domain prefix (%s) to continue: ", color.RedString(DomainPrefix))
+	answer, err := bufio.NewReader(input).ReadString('\n')
+	if err != nil {
+		err = fmt.Errorf("failed to read confirmation: %w", err)
+		messenger.ExitWithError(err)
+	}
+	if strings.TrimSpace(answer) != DomainPrefix {
+		err = fmt.Errorf("confirmation did not match '%s', nothing was changed", DomainPrefix)
+		messenger.ExitWithError(err)
+	}
+}
+
+// confirmationInput returns the terminal to read the confirmation from. When the ids were piped in on
+// standard input, the answer has to come from the controlling terminal instead.
+func confirmationInput() (io.ReadCloser, error) {
+	if FilePath != csvparser.Stdin {
+		if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+			return nil, fmt.Errorf("standard input is not a terminal")
+		}
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return nil, fmt.Errorf("the ids were read from standard input and there is no terminal to confirm on")
+	}
+	return tty, nil
+}
+
+// protectedDomains reads the protected domain prefixes from the config file or
+// the PROTECTED_DOMAINS environment variable. Entries may be comma separated.
+func protectedDomains() []string {
+	var domains []string
+	for _, entry := range viper.GetStringSlice("protected_domains") {
+		for _, domain := range strings.Split(entry, ",") {
+			domain = strings.TrimSpace(domain)
+			if domain != "" {
+				domains = append(domains, domain)
+			}
+		}
+	}
+	return domains
+}
+
+// isProtectedDomain checks the domain prefix against the protected list.
+// Entries are matched case insensitively and can use shell style wildcards e.g. "acme*"
+func isProtectedDomain(domainPrefix string, protected []string) bool {
+	domainPrefix = strings.ToLower(strings.TrimSpace(domainPrefix))
+	for _, pattern := range protected {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == domainPrefix {
+			return true
+		}
+		if matched, err := filepath.Match(pattern, domainPrefix); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// describeProduct returns the name of a product for the confirmation summary
+func describeProduct(id string) string {
+	url := fmt.Sprintf("https://%s.vendhq.com/api/2.0/products/%s", DomainPrefix, id)
+	payload := vend.ProductPayload{}
+	if err := getForDescription(url, &payload); err != nil {
+		return err.Error()
+	}
+	if payload.Data.Name != nil {
+		return *payload.Data.Name
+	}
+	return ""
+}
+
+// describeCustomer returns the name and code of a customer for the confirmation summary
+func describeCustomer(id string) string {
+	url := fmt.Sprintf("https://%s.vendhq.com/api/2.0/customers/%s", DomainPrefix, id)
+	payload := struct {
+		Data vend.Customer `json:"data"`
+	}{}
+	if err := getForDescription(url, &payload); err != nil {
+		return err.Error()
+	}
+
+	var name []string
+	if payload.Data.FirstName != nil {
+		name = append(name, *payload.Data.FirstName)
+	}
+	if payload.Data.LastName != nil {
+		name = append(name, *payload.Data.LastName)
+	}
+	if payload.Data.Code != nil {
+		name = append(name, fmt.Sprintf("(%s)", *payload.Data.Code))
+	}
+	return strings.Join(name, " ")
+}
+
+// describeSale returns the invoice number and total of a sale for the confirmation summary
+func describeSale(id string) string {
+	url := fmt.Sprintf("https://%s.vendhq.com/api/2.0/sales/%s", DomainPrefix, id)
+	payload := struct {
+		Data vend.Sale `json:"data"`
+	}{}
+	if err := getForDescription(url, &payload); err != nil {
+		return err.Error()
+	}
+
+	var description []string
+	if payload.Data.InvoiceNumber != nil {
+		description = append(description, fmt.Sprintf("invoice %s", *payload.Data.InvoiceNumber))
+	}
+	if payload.Data.TotalPrice != nil && payload.Data.TotalTax != nil {
+		description = append(description, fmt.Sprintf("total %.2f", *payload.Data.TotalPrice+*payload.Data.TotalTax))
+	}
+	if payload.Data.Status != nil {
+		description = append(description, *payload.Data.Status)
+	}
+	return strings.Join(description, ", ")
+}
+
+// describeConsignment returns the name of a consignment for the confirmation summary
+func describeConsignment(id string) string {
+	url := fmt.Sprintf("https://%s.vendhq.com/api/2.0/consignments/%s", DomainPrefix, id)
+	payload := struct {
+		Data vend.Consignment `json:"data"`
+	}{}
+	if err := getForDescription(url, &payload); err != nil {
+		return err.Error()
+	}
+	if payload.Data.Name != nil {
+		return *payload.Data.Name
+	}
+	return ""
+}
+
+// describeImage returns the product an image belongs to for the confirmation summary
+func describeImage(id string) string {
+	url := fmt.Sprintf("https://%s.vendhq.com/api/2.0/product_images/%s", DomainPrefix, id)
+	payload := vend.ImageDetailsPayload{}
+	if err := getForDescription(url, &payload); err != nil {
+		return err.Error()
+	}
+	if payload.Data.ProductID != nil {
+		return fmt.Sprintf("product %s", describeProduct(*payload.Data.ProductID))
+	}
+	return ""
+}
+
+func getForDescription(url string, payload interface{}) error {
+	res, err := vendClient.MakeRequest("GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("(not found)")
+	}
+	if err = json.Unmarshal(res, payload); err != nil {
+		return fmt.Errorf("(unreadable)")
+	}
+	return nil
+}
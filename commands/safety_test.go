@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsProtectedDomain(t *testing.T) {
+	protected := []string{"acmeretail", "bigstore*"}
+
+	assert.True(t, isProtectedDomain("acmeretail", protected))
+	assert.True(t, isProtectedDomain("AcmeRetail", protected))
+	assert.True(t, isProtectedDomain("bigstore-nz", protected))
+	assert.False(t, isProtectedDomain("acmeretail-sandbox", protected))
+	assert.False(t, isProtectedDomain("acmeretail", nil))
+}
@@ -0,0 +1,360 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vend/govend/vend"
+)
+
+// saleStatuses are the statuses a sale can have in Vend
+var saleStatuses = []string{
+	"OPEN", "SAVED", "CLOSED", "LAYBY", "LAYBY_CLOSED", "ONACCOUNT", "ONACCOUNT_CLOSED", "VOIDED",
+	"AWAITING_DISPATCH", "AWAITING_PICKUP", "DISPATCHED_CLOSED", "PICKED_UP_CLOSED",
+}
+
+// salesFilterFlags are the filters passed to a sales command
+type salesFilterFlags struct {
+	Outlets        []string
+	Registers      []string
+	Users          []string
+	CustomerGroups []string
+	Products       []string
+	Statuses       []string
+}
+
+// addSalesFilterFlags adds the register, user, customer group, product and status filters to a sales command
+func addSalesFilterFlags(cmd *cobra.Command, flags *salesFilterFlags) {
+	cmd.Flags().StringSliceVar(&flags.Registers, "Register", nil, "Only include sales from these registers")
+	cmd.Flags().StringSliceVar(&flags.Users, "User", nil, "Only include sales by these users (display name, username or email)")
+	cmd.Flags().StringSliceVar(&flags.CustomerGroups, "CustomerGroup", nil, "Only include sales to customers in these groups")
+	cmd.Flags().StringSliceVar(&flags.Products, "Product", nil, "Only include sales of these products (id, SKU or handle)")
+	cmd.Flags().StringSliceVar(&flags.Statuses, "Status", nil, fmt.Sprintf("Only include sales with these statuses, or all (%s)", strings.Join(saleStatuses, ", ")))
+}
+
+// salesFilter selects sales by the ids of their outlet, register, user, customer group and products, and by status.
+// An empty set matches every sale. Deleted sales never match.
+type salesFilter struct {
+	outlets        map[string]bool
+	registers      map[string]bool
+	users          map[string]bool
+	customerGroups map[string]bool
+	products       map[string]bool
+	statuses       map[string]bool
+
+	// customerGroupByCustomer is the group id of every customer, used to filter by customer group
+	customerGroupByCustomer map[string]string
+}
+
+// namedID is a name a record can be found by
+type namedID struct {
+	ID   string
+	Name string
+}
+
+// newSalesFilter resolves the names given in flags to ids. Names are matched ignoring case, spaces and punctuation,
+// and a name that is one or two letters off a single store name is taken to mean that name.
+func newSalesFilter(flags salesFilterFlags, oidToOutletName map[string]string, registers []vend.Register, users []vend.User,
+	customers []vend.Customer, customerGroupMap map[string]string, products []vend.Product) (salesFilter, error) {
+
+	var err error
+	filter := salesFilter{}
+
+	var outletNames []namedID
+	for id, name := range oidToOutletName {
+		outletNames = append(outletNames, namedID{ID: id, Name: name})
+	}
+	if !(len(flags.Outlets) == 1 && strings.EqualFold(flags.Outlets[0], "all")) {
+		if filter.outlets, err = matchNames("outlet", flags.Outlets, outletNames); err != nil {
+			return filter, err
+		}
+	}
+
+	var registerNames []namedID
+	for _, register := range registers {
+		if register.ID != nil && register.Name != nil {
+			registerNames = append(registerNames, namedID{ID: *register.ID, Name: *register.Name})
+		}
+	}
+	if filter.registers, err = matchNames("register", flags.Registers, registerNames); err != nil {
+		return filter, err
+	}
+
+	var userNames []namedID
+	for _, user := range users {
+		if user.ID == nil {
+			continue
+		}
+		for _, name := range []*string{user.DisplayName, user.Username, user.Email} {
+			if name != nil && *name != "" {
+				userNames = append(userNames, namedID{ID: *user.ID, Name: *name})
+			}
+		}
+	}
+	if filter.users, err = matchNames("user", flags.Users, userNames); err != nil {
+		return filter, err
+	}
+
+	var groupNames []namedID
+	for id, name := range customerGroupMap {
+		groupNames = append(groupNames, namedID{ID: id, Name: name})
+	}
+	if filter.customerGroups, err = matchNames("customer group", flags.CustomerGroups, groupNames); err != nil {
+		return filter, err
+	}
+	if len(filter.customerGroups) > 0 {
+		filter.customerGroupByCustomer = map[string]string{}
+		for _, customer := range customers {
+			if customer.ID != nil && customer.GroupId != nil {
+				filter.customerGroupByCustomer[*customer.ID] = *customer.GroupId
+			}
+		}
+	}
+
+	if filter.products, err = matchProducts(flags.Products, products); err != nil {
+		return filter, err
+	}
+
+	if filter.statuses, err = parseSaleStatuses(flags.Statuses); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// matches reports whether a sale passes every filter
+func (f salesFilter) matches(sale vend.Sale) bool {
+	if sale.DeletedAt != nil {
+		return false
+	}
+
+	var status string
+	if sale.Status != nil {
+		status = *sale.Status
+	}
+	if len(f.statuses) > 0 {
+		if !f.statuses[status] {
+			return false
+		}
+	} else if status == "OPEN" {
+		// open sales are still being rung up, so they are left out unless asked for
+		return false
+	}
+
+	if !matchesID(f.outlets, sale.OutletID) || !matchesID(f.registers, sale.RegisterID) || !matchesID(f.users, sale.UserID) {
+		return false
+	}
+
+	if len(f.customerGroups) > 0 {
+		if sale.CustomerID == nil || !f.customerGroups[f.customerGroupByCustomer[*sale.CustomerID]] {
+			return false
+		}
+	}
+
+	if len(f.products) > 0 {
+		if sale.LineItems == nil {
+			return false
+		}
+		for _, lineitem := range *sale.LineItems {
+			if lineitem.ProductID != nil && f.products[*lineitem.ProductID] {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+func matchesID(ids map[string]bool, id *string) bool {
+	if len(ids) == 0 {
+		return true
+	}
+	return id != nil && ids[*id]
+}
+
+// matchNames returns the ids of the records named by wanted
+func matchNames(kind string, wanted []string, names []namedID) (map[string]bool, error) {
+	ids := map[string]bool{}
+	for _, want := range wanted {
+		want = strings.TrimSpace(want)
+		if want == "" {
+			continue
+		}
+		key := normaliseName(want)
+
+		// exact matches, or the id itself
+		found := false
+		for _, named := range names {
+			if normaliseName(named.Name) == key || named.ID == want {
+				ids[named.ID] = true
+				found = true
+			}
+		}
+		if found {
+			continue
+		}
+
+		// near matches: a few letters off, or the start of a longer name
+		candidates := map[string][]string{}
+		for _, named := range names {
+			name := normaliseName(named.Name)
+			if levenshtein(key, name) <= maxTypos(key) || (len(key) >= 3 && strings.HasPrefix(name, key)) {
+				candidates[named.Name] = append(candidates[named.Name], named.ID)
+			}
+		}
+
+		var candidateNames []string
+		for name := range candidates {
+			candidateNames = append(candidateNames, name)
+		}
+		sort.Strings(candidateNames)
+
+		if len(candidateNames) == 0 {
+			return nil, fmt.Errorf("'%s' %s does not exist in the '%s' account", want, kind, DomainPrefix)
+		}
+		// a user can be matched by more than one of their names, which is still one match
+		for _, name := range candidateNames[1:] {
+			if !sameIDs(candidates[name], candidates[candidateNames[0]]) {
+				return nil, fmt.Errorf("'%s' matches more than one %s: %s", want, kind, strings.Join(candidateNames, ", "))
+			}
+		}
+		fmt.Println(color.YellowString("Matched %s '%s' to '%s'", kind, want, candidateNames[0]))
+		for _, id := range candidates[candidateNames[0]] {
+			ids[id] = true
+		}
+	}
+	return ids, nil
+}
+
+func sameIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]string{}, a...), append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// matchProducts returns the ids of the products given by id, SKU or handle. A handle selects every variant.
+func matchProducts(wanted []string, products []vend.Product) (map[string]bool, error) {
+	ids := map[string]bool{}
+	for _, want := range wanted {
+		want = strings.TrimSpace(want)
+		if want == "" {
+			continue
+		}
+		found := false
+		for _, product := range products {
+			if product.ID == nil {
+				continue
+			}
+			if *product.ID == want ||
+				(product.SKU != nil && strings.EqualFold(*product.SKU, want)) ||
+				(product.Handle != nil && strings.EqualFold(*product.Handle, want)) {
+				ids[*product.ID] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no product has the id, SKU or handle '%s'", want)
+		}
+	}
+	return ids, nil
+}
+
+// parseSaleStatuses checks the statuses passed to a command. "all" selects every status, including OPEN.
+func parseSaleStatuses(wanted []string) (map[string]bool, error) {
+	statuses := map[string]bool{}
+	for _, want := range wanted {
+		// "on account", "layby-closed" and "LAYBY_CLOSED" are all accepted
+		key := normaliseName(want)
+		if key == "" {
+			continue
+		}
+		if key == "all" {
+			for _, saleStatus := range saleStatuses {
+				statuses[saleStatus] = true
+			}
+			continue
+		}
+
+		known := false
+		for _, saleStatus := range saleStatuses {
+			if key == normaliseName(saleStatus) {
+				statuses[saleStatus] = true
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("'%s' is not a sale status, statuses are: %s", want, strings.Join(saleStatuses, ", "))
+		}
+	}
+	return statuses, nil
+}
+
+// normaliseName lower cases a name and drops everything but letters and digits
+func normaliseName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// maxTypos is how many letters a name can be off by and still match: none for short names
+func maxTypos(name string) int {
+	switch n := len([]rune(name)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// levenshtein is the number of single letter edits between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current := make([]int, len(rb)+1)
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(rb)]
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+	for _, value := range values[1:] {
+		if value < min {
+			min = value
+		}
+	}
+	return min
+}
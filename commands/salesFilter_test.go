@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/govend/vend"
+)
+
+func TestMatchNames(t *testing.T) {
+	names := []namedID{
+		{ID: "1", Name: "Newmarket"},
+		{ID: "2", Name: "Ponsonby Road"},
+		{ID: "3", Name: "Ponsonby Central"},
+		{ID: "4", Name: "Sam Smith"},
+		{ID: "4", Name: "sam.smith"},
+	}
+
+	ids, err := matchNames("outlet", []string{"newmarket", "ponsonby-road"}, names)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{"1": true, "2": true}, ids)
+
+	ids, err = matchNames("outlet", []string{"Newmarkt"}, names)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{"1": true}, ids)
+
+	ids, err = matchNames("user", []string{"Sam Smit"}, names)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{"4": true}, ids)
+
+	_, err = matchNames("outlet", []string{"Ponsonby"}, names)
+	assert.EqualError(t, err, "'Ponsonby' matches more than one outlet: Ponsonby Central, Ponsonby Road")
+
+	_, err = matchNames("outlet", []string{"Albany"}, names)
+	assert.Error(t, err)
+}
+
+func TestSalesFilterMatches(t *testing.T) {
+	outletA, outletB, customer, group, product := "a", "b", "c1", "g1", "p1"
+	layby, open, deleted := "LAYBY", "OPEN", "2024-01-01"
+
+	statuses, err := parseSaleStatuses([]string{"layby", "on account"})
+	assert.Nil(t, err)
+	filter := salesFilter{
+		outlets:                 map[string]bool{outletA: true},
+		customerGroups:          map[string]bool{group: true},
+		customerGroupByCustomer: map[string]string{customer: group},
+		products:                map[string]bool{product: true},
+		statuses:                statuses,
+	}
+
+	lines := []vend.LineItem{{ProductID: &product}}
+	sale := vend.Sale{OutletID: &outletA, Status: &layby, CustomerID: &customer, LineItems: &lines}
+	assert.True(t, filter.matches(sale))
+
+	other := sale
+	other.OutletID = &outletB
+	assert.False(t, filter.matches(other))
+
+	other = sale
+	other.DeletedAt = &deleted
+	assert.False(t, filter.matches(other))
+
+	other = sale
+	other.LineItems = &[]vend.LineItem{}
+	assert.False(t, filter.matches(other))
+
+	assert.False(t, salesFilter{}.matches(vend.Sale{Status: &open}))
+	assert.True(t, salesFilter{}.matches(vend.Sale{Status: &layby}))
+
+	_, err = parseSaleStatuses([]string{"PARKED"})
+	assert.Error(t, err)
+}
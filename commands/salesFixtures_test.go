@@ -0,0 +1,77 @@
+package cmd
+
+import "github.com/vend/govend/vend"
+
+// saleFixture is a sale for the report tests. Empty ids and strings are left nil on the sale, amounts are always set.
+type saleFixture struct {
+	ID, Outlet, Register, User, Customer string
+	Status, Invoice, Date, DeletedAt     string
+	Sequence                             int64
+	Total, Tax, Loyalty                  float64
+	Lines                                []lineFixture
+	Payments                             []vend.Payment
+}
+
+// lineFixture is a sale line for the report tests, with the price, discount and tax per unit and the cost of the line
+type lineFixture struct {
+	ID, Product, TaxID                   string
+	Quantity, Price, Discount, Tax, Cost float64
+	Return                               bool
+}
+
+// testSale builds a vend.Sale from a fixture
+func testSale(f saleFixture) vend.Sale {
+	lines, payments := []vend.LineItem{}, f.Payments
+	for _, line := range f.Lines {
+		lines = append(lines, testLine(line))
+	}
+	total, tax, loyalty := f.Total, f.Tax, f.Loyalty
+	sale := vend.Sale{
+		ID:            optionalString(f.ID),
+		OutletID:      optionalString(f.Outlet),
+		RegisterID:    optionalString(f.Register),
+		UserID:        optionalString(f.User),
+		CustomerID:    optionalString(f.Customer),
+		Status:        optionalString(f.Status),
+		InvoiceNumber: optionalString(f.Invoice),
+		SaleDate:      optionalString(f.Date),
+		DeletedAt:     optionalString(f.DeletedAt),
+		TotalPrice:    &total,
+		TotalTax:      &tax,
+		TotalLoyalty:  &loyalty,
+		LineItems:     &lines,
+		Payments:      &payments,
+	}
+	if f.Sequence != 0 {
+		sequence := f.Sequence
+		sale.InvoiceSequence = &sequence
+	}
+	return sale
+}
+
+// testLine builds a vend.LineItem from a fixture
+func testLine(f lineFixture) vend.LineItem {
+	return vend.LineItem{
+		ID:        optionalString(f.ID),
+		ProductID: optionalString(f.Product),
+		TaxID:     optionalString(f.TaxID),
+		Quantity:  &f.Quantity,
+		Price:     &f.Price,
+		Discount:  &f.Discount,
+		Tax:       &f.Tax,
+		TotalCost: &f.Cost,
+		IsReturn:  &f.Return,
+	}
+}
+
+// testPayment builds a payment of a payment type, with no name when name is empty
+func testPayment(name string, amount float64) vend.Payment {
+	return vend.Payment{Name: optionalString(name), Amount: &amount}
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,393 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vend/vend-cli/pkg/messenger"
+
+	"github.com/spf13/cobra"
+	"github.com/vend/govend/vend"
+)
+
+// salesColumns are the columns a sales report can have. The first 35 are the default layout, which mimics the
+// sales history report of the Vend UI.
+var salesColumns = []string{
+	"Sale UUID",
+	"Sale Date",
+	"Sale Time",
+	"Invoice Number",
+	"Line Type",
+	"Customer Code",
+	"Customer Name",
+	"Customer Email",
+	"Customer Group",
+	"Customer Address1",
+	"Customer Address2",
+	"Customer City",
+	"Customer State",
+	"Customer Postcode",
+	"Customer CountryID",
+	"Do not email",
+	"Sale Note",
+	"Quantity",
+	"Cost",
+	"Price",
+	"Tax",
+	"Discount",
+	"Loyalty",
+	"Total",
+	"Paid",
+	"Details",
+	"Register",
+	"User",
+	"Status",
+	"Product Sku",
+	"Tax Name",
+	"Tax Components",
+	"Return For",
+	"Is Return",
+	"Line Status",
+	// not in the default layout
+	"Outlet",
+	"Product ID",
+	"Product Handle",
+	"Brand",
+	"Supplier",
+	"Product Type",
+}
+
+var customerAddressColumns = []string{
+	"Customer Address1", "Customer Address2", "Customer City", "Customer State", "Customer Postcode", "Customer CountryID",
+}
+
+// salesProfiles are the layouts that can be picked with --profile
+var salesProfiles = map[string]salesLayout{
+	"default":    {Columns: salesColumns[:35]},
+	"no-address": {Columns: withoutColumns(salesColumns[:35], customerAddressColumns)},
+	"full":       {Columns: salesColumns},
+	// one row per line item, with the sale's details on every row, for pivot tables
+	"lines": {LinesOnly: true, Columns: []string{
+		"Sale UUID", "Sale Date", "Sale Time", "Invoice Number", "Outlet", "Register", "User", "Status",
+		"Customer Code", "Customer Name", "Customer Group", "Product ID", "Product Sku", "Product Handle", "Details",
+		"Brand", "Supplier", "Product Type", "Quantity", "Cost", "Price", "Tax", "Discount", "Loyalty", "Total",
+		"Tax Name", "Tax Components", "Return For", "Is Return", "Line Status",
+	}},
+}
+
+// salesLayout is the columns of a sales report, and whether it has only line item rows
+type salesLayout struct {
+	Columns   []string
+	LinesOnly bool
+}
+
+// salesRow is a row of a sales report by column name
+type salesRow map[string]string
+
+// with returns a copy of the row with the values of other added
+func (r salesRow) with(other salesRow) salesRow {
+	row := salesRow{}
+	for column, value := range r {
+		row[column] = value
+	}
+	for column, value := range other {
+		row[column] = value
+	}
+	return row
+}
+
+// record returns the values of a row in the order of the layout
+func (l salesLayout) record(row salesRow) []string {
+	record := make([]string, len(l.Columns))
+	for i, column := range l.Columns {
+		record[i] = row[column]
+	}
+	return record
+}
+
+// newSalesLayout builds the layout from a profile and --columns. Columns either replace the profile's columns, or
+// when every one starts with + or -, add to or drop from them. Combined reports always have an Outlet column.
+func newSalesLayout(profile string, columns []string, combine bool) (salesLayout, error) {
+	layout, ok := salesProfiles[strings.ToLower(profile)]
+	if !ok {
+		return layout, fmt.Errorf("'%s' is not a column profile, profiles are: %s", profile, strings.Join(salesProfileNames(), ", "))
+	}
+	layout.Columns = append([]string{}, layout.Columns...)
+
+	modify := len(columns) > 0
+	for _, column := range columns {
+		if !strings.HasPrefix(column, "+") && !strings.HasPrefix(column, "-") {
+			modify = false
+		}
+	}
+	if len(columns) > 0 && !modify {
+		layout.Columns = nil
+	}
+
+	for _, column := range columns {
+		name, err := salesColumnName(strings.TrimLeft(column, "+-"))
+		if err != nil {
+			return layout, err
+		}
+		if modify && strings.HasPrefix(column, "-") {
+			layout.Columns = withoutColumns(layout.Columns, []string{name})
+		} else if !containsString(layout.Columns, name) {
+			layout.Columns = append(layout.Columns, name)
+		}
+	}
+
+	if combine && !containsString(layout.Columns, "Outlet") {
+		layout.Columns = insertColumnAfter(layout.Columns, "Invoice Number", "Outlet")
+	}
+	if len(layout.Columns) == 0 {
+		return layout, fmt.Errorf("the report has no columns")
+	}
+	return layout, nil
+}
+
+// salesColumnName finds a column ignoring case, spaces and punctuation
+func salesColumnName(name string) (string, error) {
+	for _, column := range salesColumns {
+		if normaliseName(column) == normaliseName(name) {
+			return column, nil
+		}
+	}
+	return "", fmt.Errorf("'%s' is not a sales report column, columns are: %s", name, strings.Join(salesColumns, ", "))
+}
+
+func salesProfileNames() []string {
+	var names []string
+	for name := range salesProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func withoutColumns(columns []string, drop []string) []string {
+	var kept []string
+	for _, column := range columns {
+		if !containsString(drop, column) {
+			kept = append(kept, column)
+		}
+	}
+	return kept
+}
+
+// insertColumnAfter inserts a column after another one, or first when that one is not in the layout
+func insertColumnAfter(columns []string, after, name string) []string {
+	position := 0
+	for i, column := range columns {
+		if column == after {
+			position = i + 1
+		}
+	}
+	inserted := append([]string{}, columns[:position]...)
+	inserted = append(inserted, name)
+	return append(inserted, columns[position:]...)
+}
+
+// salesReportData is the store data sales reports look up records in, by id
+type salesReportData struct {
+	Outlets        map[string]string
+	Registers      map[string]vend.Register
+	Users          map[string]vend.User
+	Customers      map[string]vend.Customer
+	CustomerGroups map[string]string
+	Products       map[string]vend.Product
+	Taxes          map[string]vend.Taxes
+	// LineTaxes are the tax components Vend recorded on each sale line, by line id
+	LineTaxes map[string][]lineTaxComponent
+}
+
+func newSalesReportData(oidToOutletName map[string]string, registers []vend.Register, users []vend.User, customers []vend.Customer,
+	customerGroupMap map[string]string, products []vend.Product, taxes map[string]vend.Taxes) salesReportData {
+
+	data := salesReportData{
+		Outlets:        oidToOutletName,
+		Registers:      map[string]vend.Register{},
+		Users:          map[string]vend.User{},
+		Customers:      map[string]vend.Customer{},
+		CustomerGroups: customerGroupMap,
+		Products:       map[string]vend.Product{},
+		Taxes:          taxes,
+	}
+	for _, register := range registers {
+		if register.ID != nil {
+			data.Registers[*register.ID] = register
+		}
+	}
+	for _, user := range users {
+		if user.ID != nil {
+			data.Users[*user.ID] = user
+		}
+	}
+	for _, customer := range customers {
+		if customer.ID != nil {
+			data.Customers[*customer.ID] = customer
+		}
+	}
+	for _, product := range products {
+		if product.ID != nil {
+			data.Products[*product.ID] = product
+		}
+	}
+	return data
+}
+
+// taxRateName is the name of a tax rate, or its id when no tax has the rate
+func (d salesReportData) taxRateName(rateID string) string {
+	for _, tax := range d.Taxes {
+		for _, rate := range tax.TaxRates {
+			if rate.ID != nil && *rate.ID == rateID && rate.Name != nil {
+				return *rate.Name
+			}
+		}
+	}
+	return rateID
+}
+
+// registerName is the name of a sale's register, marked when the register is deleted
+func (d salesReportData) registerName(registerID *string) string {
+	if registerID == nil || len(d.Registers) == 0 {
+		return ""
+	}
+	register, ok := d.Registers[*registerID]
+	if !ok || register.Name == nil {
+		// Should no longer happen as the registers endpoint now returns deleted registers.
+		return "<Deleted Register>"
+	}
+	if register.DeletedAt != nil {
+		return *register.Name + " (Deleted)"
+	}
+	return *register.Name
+}
+
+// userName is the display name of a user, or their username when they have none
+func (d salesReportData) userName(userID *string) string {
+	if userID == nil {
+		return ""
+	}
+	user := d.Users[*userID]
+	if user.DisplayName != nil {
+		return *user.DisplayName
+	}
+	if user.Username != nil {
+		return *user.Username
+	}
+	return ""
+}
+
+// productName is the full name of a product, including its variant
+func productName(product vend.Product) string {
+	if product.VariantName != nil && *product.VariantName != "" {
+		return *product.VariantName
+	}
+	if product.Name != nil {
+		return *product.Name
+	}
+	return ""
+}
+
+// productSuppliers are the names of a product's suppliers
+func productSuppliers(product vend.Product) string {
+	var names []string
+	for _, supplier := range product.ProductSuppliers {
+		if supplier.SupplierName != nil && *supplier.SupplierName != "" && !containsString(names, *supplier.SupplierName) {
+			names = append(names, *supplier.SupplierName)
+		}
+	}
+	return strings.Join(names, "; ")
+}
+
+// addSalesRangeFlags adds the timezone, date range, outlet and filter flags of commands that report on sales
+func addSalesRangeFlags(cmd *cobra.Command, filters *salesFilterFlags) {
+	cmd.Flags().StringVarP(&timeZone, "Timezone", "z", "", "Timezone of the store in zoneinfo format.")
+	cmd.Flags().StringVarP(&dateFrom, "DateFrom", "F", "", "Date from (YYYY-MM-DD)")
+	cmd.Flags().StringVarP(&dateTo, "DateTo", "T", "", "Date to (YYYY-MM-DD)")
+	cmd.Flags().StringSliceVarP(&filters.Outlets, "Outlet", "o", []string{"all"}, "Outlets to include the sales of, or all")
+	addSalesFilterFlags(cmd, filters)
+	cmd.MarkFlagRequired("Timezone")
+	cmd.MarkFlagRequired("DateFrom")
+	cmd.MarkFlagRequired("DateTo")
+}
+
+// fetchReportSales fetches the sales in the date range that pass the filters, oldest first, and the store data
+// to report on them. Voided sales are left out unless includeVoided is set or they are asked for with --Status.
+func fetchReportSales(vc vend.Client, filters salesFilterFlags, includeVoided bool) ([]vend.Sale, salesReportData) {
+	// Validate date input
+	validateDateInput(dateFrom, "date from")
+	validateDateInput(dateTo, "date to")
+
+	// Validate provided timezone
+	validateTimeZone(dateTo+"T00:00:00Z", timeZone)
+
+	utcDateFrom, utcDateTo, versionAfter := prepareDateAndVersion(vc)
+	sales, lineTaxes, registers, users, customers, customerGroupMap, products, taxes := getAllSalesData(versionAfter)
+	oidToOutletName := getOutletsAndOutletNameMap(vc)
+
+	filter, err := newSalesFilter(filters, oidToOutletName, registers, users, customers, customerGroupMap, products)
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	var filteredSales []vend.Sale
+	for _, outletSales := range getFilteredSales(sales, utcDateFrom, utcDateTo, oidToOutletName, filter) {
+		for _, sale := range outletSales {
+			if !includeVoided && len(filter.statuses) == 0 && sale.Status != nil && *sale.Status == "VOIDED" {
+				continue
+			}
+			filteredSales = append(filteredSales, sale)
+		}
+	}
+	sortBySaleDate(filteredSales)
+
+	data := newSalesReportData(oidToOutletName, registers, users, customers, customerGroupMap, products, taxes)
+	data.LineTaxes = lineTaxes
+	return filteredSales, data
+}
+
+// fetchSalesInRange fetches every sale in the date range on the selected outlets and registers, of any status and
+// including deleted ones
+func fetchSalesInRange(vc vend.Client, filters salesFilterFlags) ([]vend.Sale, salesReportData) {
+	validateDateInput(dateFrom, "date from")
+	validateDateInput(dateTo, "date to")
+	validateTimeZone(dateTo+"T00:00:00Z", timeZone)
+
+	utcDateFrom, utcDateTo, versionAfter := prepareDateAndVersion(vc)
+	sales, lineTaxes, registers, users, customers, customerGroupMap, products, taxes := getAllSalesData(versionAfter)
+	oidToOutletName := getOutletsAndOutletNameMap(vc)
+
+	filter, err := newSalesFilter(filters, oidToOutletName, registers, users, customers, customerGroupMap, products)
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	dtFrom := getTime(utcDateFrom).Add(-1 * time.Second)
+	dtTo := getTime(utcDateTo).Add(1 * time.Second)
+	var inRange []vend.Sale
+	for _, sale := range sales {
+		if sale.SaleDate == nil || !matchesID(filter.outlets, sale.OutletID) || !matchesID(filter.registers, sale.RegisterID) {
+			continue
+		}
+		saleDate := getTime((*sale.SaleDate)[:19] + "Z")
+		if saleDate.After(dtFrom) && saleDate.Before(dtTo) {
+			inRange = append(inRange, sale)
+		}
+	}
+
+	data := newSalesReportData(oidToOutletName, registers, users, customers, customerGroupMap, products, taxes)
+	data.LineTaxes = lineTaxes
+	return inRange, data
+}
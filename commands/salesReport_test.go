@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/govend/vend"
+	pbar "github.com/vend/vend-cli/pkg/progressbar"
+)
+
+func TestNewSalesLayout(t *testing.T) {
+	layout, err := newSalesLayout("default", nil, false)
+	assert.Nil(t, err)
+	assert.Equal(t, salesColumns[:35], layout.Columns)
+
+	layout, err = newSalesLayout("Default", []string{"-customer address1", "+brand"}, true)
+	assert.Nil(t, err)
+	assert.NotContains(t, layout.Columns, "Customer Address1")
+	assert.Equal(t, "Brand", layout.Columns[len(layout.Columns)-1])
+	assert.Equal(t, []string{"Sale UUID", "Sale Date", "Sale Time", "Invoice Number", "Outlet"}, layout.Columns[:5])
+
+	layout, err = newSalesLayout("default", []string{"sale date", "product-sku"}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Sale Date", "Product Sku"}, layout.Columns)
+
+	_, err = newSalesLayout("default", []string{"+Colour"}, false)
+	assert.Error(t, err)
+	_, err = newSalesLayout("wide", nil, false)
+	assert.Error(t, err)
+}
+
+func TestWriteSalesReportLayouts(t *testing.T) {
+	saleID, outletID, customerID, productID := "s1", "o1", "c1", "p1"
+	date, status := "2024-03-01T01:00:00Z", "CLOSED"
+	first, sku, name, brand := "Ana", "SKU-1", "Mug", "Acme"
+	quantity, price, tax, discount, totalPrice, totalTax := 2.0, 10.0, 1.5, 2.5, 20.0, 3.0
+	paymentName, amount := "Cash", 23.0
+
+	lines := []vend.LineItem{{ProductID: &productID, Quantity: &quantity, Price: &price, Tax: &tax, Discount: &discount}}
+	payments := []vend.Payment{{Name: &paymentName, Amount: &amount}}
+	sales := []vend.Sale{{ID: &saleID, OutletID: &outletID, CustomerID: &customerID, SaleDate: &date, Status: &status,
+		TotalPrice: &totalPrice, TotalTax: &totalTax, LineItems: &lines, Payments: &payments}}
+
+	product := vend.Product{ID: &productID, Name: &name, VariantName: &name, SKU: &sku}
+	product.Brand.Name = &brand
+	data := newSalesReportData(map[string]string{outletID: "Newmarket"}, nil, nil,
+		[]vend.Customer{{ID: &customerID, FirstName: &first}}, nil, []vend.Product{product}, nil)
+
+	write := func(layout salesLayout) [][]string {
+		file, err := os.Create(filepath.Join(t.TempDir(), "sales.csv"))
+		assert.Nil(t, err)
+		defer file.Close()
+
+		p := pbar.CreateSingleBar()
+		bar, err := p.AddProgressBar(len(sales), "test")
+		assert.Nil(t, err)
+		addSalesReportHeader(file, layout)
+		writeSalesReport(file, bar, layout, data, sales, "UTC")
+		p.Wait()
+
+		_, err = file.Seek(0, 0)
+		assert.Nil(t, err)
+		rows, err := csv.NewReader(file).ReadAll()
+		assert.Nil(t, err)
+		return rows
+	}
+
+	rows := write(salesProfiles["default"])
+	assert.Len(t, rows, 4)
+	assert.Equal(t, []string{"Sale", "Sale Line", "Payment"}, []string{rows[1][4], rows[2][4], rows[3][4]})
+	assert.Equal(t, "5", rows[1][21]) // the sale's discount is the line discount times the quantity
+	assert.Equal(t, "Ana", rows[1][6])
+	assert.Equal(t, "", rows[2][6])
+
+	layout, err := newSalesLayout("lines", []string{"Outlet", "Customer Name", "Product Sku", "Brand", "Quantity"}, false)
+	assert.Nil(t, err)
+	rows = write(layout)
+	assert.Equal(t, [][]string{
+		{"Outlet", "Customer Name", "Product Sku", "Brand", "Quantity"},
+		{"Newmarket", "Ana", "SKU-1", "Acme", "2"},
+	}, rows)
+}
@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vend/vend-cli/pkg/messenger"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vend/govend/vend"
+)
+
+// Command config
+var (
+	scanFilters    salesFilterFlags
+	scanStaleDays  int
+	scanCategories []string
+
+	scanSalesCmd = &cobra.Command{
+		Use:   "scan-sales",
+		Short: "Scan Sales for Anomalies",
+		Long: fmt.Sprintf(`
+Scans the sales between the dates for suspect records, in these categories:
+
+  unbalanced        closed sales whose payments do not add up to the total
+  negative          sales with a negative total but no return lines
+  missing-product   lines for products that are deleted or no longer exist
+  stale             sales still OPEN or SAVED after --stale-days
+  zero-price        lines sold for nothing
+  missing-register  sales on registers that are deleted or no longer exist
+
+Every finding is written to one CSV, and the sale IDs of each category to their own file with no header, ready for
+void-sales or the other commands that read a list of sale IDs.
+
+Example:
+%s`, color.GreenString("vendcli scan-sales -d DOMAINPREFIX -t TOKEN -z TIMEZONE -F DATEFROM -T DATETO --category stale,unbalanced")),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			scanSales()
+		},
+	}
+)
+
+// scanCategoryNames are the kinds of anomaly scan-sales looks for, in report order
+var scanCategoryNames = []string{"unbalanced", "negative", "missing-product", "stale", "zero-price", "missing-register"}
+
+func init() {
+	// Flags
+	scanSalesCmd.Flags().StringVarP(&timeZone, "Timezone", "z", "", "Timezone of the store in zoneinfo format.")
+	scanSalesCmd.Flags().StringVarP(&dateFrom, "DateFrom", "F", "", "Date from (YYYY-MM-DD)")
+	scanSalesCmd.Flags().StringVarP(&dateTo, "DateTo", "T", "", "Date to (YYYY-MM-DD)")
+	scanSalesCmd.Flags().StringSliceVarP(&scanFilters.Outlets, "Outlet", "o", []string{"all"}, "Outlets to scan the sales of, or all")
+	scanSalesCmd.Flags().StringSliceVar(&scanFilters.Registers, "Register", nil, "Only scan sales from these registers")
+	scanSalesCmd.Flags().IntVar(&scanStaleDays, "stale-days", 7, "Days after which an OPEN or SAVED sale is stale")
+	scanSalesCmd.Flags().StringSliceVar(&scanCategories, "category", nil, fmt.Sprintf("Only look for these categories: %s", strings.Join(scanCategoryNames, ", ")))
+	scanSalesCmd.MarkFlagRequired("Timezone")
+	scanSalesCmd.MarkFlagRequired("DateFrom")
+	scanSalesCmd.MarkFlagRequired("DateTo")
+
+	rootCmd.AddCommand(scanSalesCmd)
+}
+
+// saleFinding is a suspect sale
+type saleFinding struct {
+	Category      string
+	SaleID        string
+	InvoiceNumber string
+	Outlet        string
+	Register      string
+	SaleDate      string
+	Status        string
+	Total         string
+	Detail        string
+}
+
+func scanSales() {
+	categories, err := parseScanCategories(scanCategories)
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+	if scanStaleDays < 1 {
+		messenger.ExitWithError(fmt.Errorf("--stale-days must be at least 1"))
+	}
+
+	vc := vend.NewClient(Token, DomainPrefix, timeZone)
+	fmt.Println("Scanning Sales...")
+	sales, data := fetchSalesInRange(vc, scanFilters)
+
+	staleBefore := time.Now().UTC().AddDate(0, 0, -scanStaleDays)
+	findings := findSaleAnomalies(sales, data, categories, staleBefore, timeZone)
+
+	fileName := fmt.Sprintf("%s_scan_sales_f%s_t%s.csv", DomainPrefix, dateFrom, dateTo)
+	if err := saleFindingsTable(findings).writeCSV(fileName); err != nil {
+		err = fmt.Errorf("failed to write sale findings: %w", err)
+		messenger.ExitWithError(err)
+	}
+	files := []string{fileName}
+
+	// a file of ids per category, with no header like void-sales expects
+	counts := map[string]int{}
+	for _, category := range categories {
+		var ids []string
+		for _, finding := range findings {
+			if finding.Category == category {
+				ids = append(ids, finding.SaleID)
+			}
+		}
+		counts[category] = len(ids)
+		if len(ids) == 0 {
+			continue
+		}
+		idFile := fmt.Sprintf("%s_scan_sales_f%s_t%s_%s_ids.csv", DomainPrefix, dateFrom, dateTo, category)
+		if err := writeSaleIDs(idFile, ids); err != nil {
+			err = fmt.Errorf("failed to write %s sale ids: %w", category, err)
+			messenger.ExitWithError(err)
+		}
+		files = append(files, idFile)
+	}
+
+	fmt.Println()
+	for _, category := range categories {
+		fmt.Printf("  %-17s %d\n", category, counts[category])
+	}
+	fmt.Println(color.GreenString("\n\nFinished!🎉\nScanned %d sales, found %d suspect: %s", len(sales), len(findings), strings.Join(files, ", ")))
+}
+
+// writeSaleIDs writes one sale id per line, with no header
+func writeSaleIDs(fileName string, ids []string) error {
+	file, err := os.Create(fmt.Sprintf("./%s", fileName))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	csvWriter := csv.NewWriter(file)
+	for _, id := range ids {
+		csvWriter.Write([]string{id})
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// parseScanCategories checks the values of --category, every category when none are given
+func parseScanCategories(wanted []string) ([]string, error) {
+	if len(wanted) == 0 {
+		return scanCategoryNames, nil
+	}
+	var categories []string
+	for _, category := range scanCategoryNames {
+		for _, want := range wanted {
+			if normaliseName(want) == normaliseName(category) && !containsString(categories, category) {
+				categories = append(categories, category)
+			}
+		}
+	}
+	for _, want := range wanted {
+		known := false
+		for _, category := range scanCategoryNames {
+			known = known || normaliseName(want) == normaliseName(category)
+		}
+		if !known {
+			return nil, fmt.Errorf("'%s' is not a scan category, categories are: %s", want, strings.Join(scanCategoryNames, ", "))
+		}
+	}
+	return categories, nil
+}
+
+// findSaleAnomalies checks every sale that is not deleted for the categories, in category then date order. A sale is
+// reported once per category, with the details of every line that caused it.
+func findSaleAnomalies(sales []vend.Sale, data salesReportData, categories []string, staleBefore time.Time, timeZone string) []saleFinding {
+	var findings []saleFinding
+	for _, sale := range sales {
+		if sale.DeletedAt != nil || sale.SaleDate == nil {
+			continue
+		}
+		var status string
+		if sale.Status != nil {
+			status = *sale.Status
+		}
+		total := saleTotal(sale)
+
+		for _, category := range categories {
+			var details []string
+			switch category {
+			case "unbalanced":
+				paid := salePaid(sale)
+				if strings.HasSuffix(status, "CLOSED") && math.Abs(paid-total) > 0.005 {
+					details = append(details, fmt.Sprintf("paid %s of %s", formatCents(paid), formatCents(total)))
+				}
+			case "negative":
+				if total < -0.005 && !hasReturnLine(sale) {
+					details = append(details, "negative total without return lines")
+				}
+			case "missing-product":
+				for _, lineitem := range saleLines(sale) {
+					if lineitem.ProductID == nil {
+						details = append(details, "line has no product")
+						continue
+					}
+					product, ok := data.Products[*lineitem.ProductID]
+					if !ok {
+						details = append(details, fmt.Sprintf("product %s does not exist", *lineitem.ProductID))
+					} else if product.DeletedAt != nil {
+						details = append(details, fmt.Sprintf("product %s is deleted", productName(product)))
+					}
+				}
+			case "stale":
+				if (status == "OPEN" || status == "SAVED") && getTime((*sale.SaleDate)[:19]+"Z").Before(staleBefore) {
+					details = append(details, fmt.Sprintf("%s since %s", status, (*sale.SaleDate)[:10]))
+				}
+			case "zero-price":
+				for _, lineitem := range saleLines(sale) {
+					if lineitem.Quantity != nil && *lineitem.Quantity != 0 && (lineitem.Price == nil || *lineitem.Price == 0) {
+						var name string
+						if lineitem.ProductID != nil {
+							name = productName(data.Products[*lineitem.ProductID])
+						}
+						if name == "" {
+							name = "a product"
+						}
+						details = append(details, fmt.Sprintf("%s sold for 0", name))
+					}
+				}
+			case "missing-register":
+				if sale.RegisterID == nil {
+					details = append(details, "sale has no register")
+				} else if register, ok := data.Registers[*sale.RegisterID]; !ok {
+					details = append(details, fmt.Sprintf("register %s does not exist", *sale.RegisterID))
+				} else if register.DeletedAt != nil {
+					details = append(details, fmt.Sprintf("register %s is deleted", stringOf(register.Name)))
+				}
+			}
+
+			if len(details) > 0 {
+				findings = append(findings, newSaleFinding(category, sale, data, total, strings.Join(details, "; "), timeZone))
+			}
+		}
+	}
+
+	order := map[string]int{}
+	for i, category := range scanCategoryNames {
+		order[category] = i
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Category != findings[j].Category {
+			return order[findings[i].Category] < order[findings[j].Category]
+		}
+		return findings[i].SaleDate < findings[j].SaleDate
+	})
+	return findings
+}
+
+func saleLines(sale vend.Sale) []vend.LineItem {
+	if sale.LineItems == nil {
+		return nil
+	}
+	return *sale.LineItems
+}
+
+// saleTotal is the total of a sale including tax
+func saleTotal(sale vend.Sale) float64 {
+	var total float64
+	if sale.TotalPrice != nil {
+		total += *sale.TotalPrice
+	}
+	if sale.TotalTax != nil {
+		total += *sale.TotalTax
+	}
+	return total
+}
+
+func salePaid(sale vend.Sale) float64 {
+	var paid float64
+	if sale.Payments != nil {
+		for _, payment := range *sale.Payments {
+			if payment.Amount != nil {
+				paid += *payment.Amount
+			}
+		}
+	}
+	return paid
+}
+
+func hasReturnLine(sale vend.Sale) bool {
+	for _, lineitem := range saleLines(sale) {
+		if lineitem.IsReturn != nil && *lineitem.IsReturn {
+			return true
+		}
+	}
+	return false
+}
+
+func newSaleFinding(category string, sale vend.Sale, data salesReportData, total float64, detail, timeZone string) saleFinding {
+	finding := saleFinding{
+		Category:      category,
+		SaleID:        stringOf(sale.ID),
+		InvoiceNumber: stringOf(sale.InvoiceNumber),
+		Register:      data.registerName(sale.RegisterID),
+		Status:        stringOf(sale.Status),
+		Total:         formatCents(total),
+		Detail:        detail,
+	}
+	if sale.OutletID != nil {
+		finding.Outlet = data.Outlets[*sale.OutletID]
+	}
+	if saleDate, err := vend.ParseVendDT(*sale.SaleDate, timeZone); err == nil {
+		finding.SaleDate = saleDate.Format("2006-01-02 15:04:05")
+	}
+	return finding
+}
+
+func saleFindingsTable(findings []saleFinding) reportTable {
+	var rows [][]string
+	for _, finding := range findings {
+		rows = append(rows, []string{finding.Category, finding.SaleID, finding.InvoiceNumber, finding.Outlet,
+			finding.Register, finding.SaleDate, finding.Status, finding.Total, finding.Detail})
+	}
+	return reportTable{
+		Header: []string{"Category", "Sale ID", "Invoice Number", "Outlet", "Register", "Sale Date", "Status", "Total", "Detail"},
+		Rows:   rows,
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/govend/vend"
+)
+
+func TestFindSaleAnomalies(t *testing.T) {
+	registerID, oldRegisterID, deleted := "r1", "r0", "2024-01-01T00:00:00Z"
+	registerName, oldRegisterName := "Main", "Old"
+	registerDeleted := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	productID, goneProductID := "p1", "p2"
+	productName := "Mug"
+	sold := []lineFixture{{Product: productID, Quantity: 1, Price: 10}}
+
+	sales := []vend.Sale{
+		testSale(saleFixture{ID: "s1", Status: "CLOSED", Date: "2024-03-01T01:00:00Z", Register: registerID, Total: 10, Lines: sold, Payments: []vend.Payment{testPayment("", 10)}}),
+		testSale(saleFixture{ID: "s2", Status: "CLOSED", Date: "2024-03-01T02:00:00Z", Register: registerID, Total: 10, Lines: sold, Payments: []vend.Payment{testPayment("", 5)}}),
+		testSale(saleFixture{ID: "s3", Status: "CLOSED", Date: "2024-03-01T03:00:00Z", Register: registerID, Total: -10, Payments: []vend.Payment{testPayment("", -10)},
+			Lines: []lineFixture{{Product: productID, Quantity: -1, Price: 10}}}),
+		testSale(saleFixture{ID: "s4", Status: "OPEN", Date: "2024-03-01T04:00:00Z", Register: registerID, Total: 10,
+			Lines: []lineFixture{{Product: goneProductID, Quantity: 1, Price: 10}, {Product: productID, Quantity: 1}}}),
+		testSale(saleFixture{ID: "s5", Status: "OPEN", Date: "2024-03-09T04:00:00Z", Register: registerID, Total: 10, Lines: sold}),
+		testSale(saleFixture{ID: "s6", Status: "CLOSED", Date: "2024-03-01T06:00:00Z", Register: oldRegisterID, Total: 10, Lines: sold, Payments: []vend.Payment{testPayment("", 10)}}),
+		testSale(saleFixture{ID: "s7", Status: "CLOSED", Date: "2024-03-01T07:00:00Z", Register: registerID, Total: 10, Lines: sold, DeletedAt: deleted}),
+		testSale(saleFixture{ID: "s8", Status: "CLOSED", Date: "2024-03-01T08:00:00Z", Register: registerID, Total: -10, Payments: []vend.Payment{testPayment("", -10)},
+			Lines: []lineFixture{{Product: productID, Quantity: -1, Price: 10, Return: true}}}),
+	}
+	data := newSalesReportData(nil, []vend.Register{{ID: &registerID, Name: &registerName}, {ID: &oldRegisterID, Name: &oldRegisterName, DeletedAt: &registerDeleted}},
+		nil, nil, nil, []vend.Product{{ID: &productID, Name: &productName}}, nil)
+
+	staleBefore := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	findings := findSaleAnomalies(sales, data, scanCategoryNames, staleBefore, "UTC")
+
+	var found []string
+	for _, finding := range findings {
+		found = append(found, finding.Category+" "+finding.SaleID+": "+finding.Detail)
+	}
+	assert.Equal(t, []string{
+		"unbalanced s2: paid 5.00 of 10.00",
+		"negative s3: negative total without return lines",
+		"missing-product s4: product p2 does not exist",
+		"stale s4: OPEN since 2024-03-01",
+		"zero-price s4: Mug sold for 0",
+		"missing-register s6: register Old is deleted",
+	}, found)
+
+	categories, err := parseScanCategories([]string{"Stale", "unbalanced"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"unbalanced", "stale"}, categories)
+	_, err = parseScanCategories([]string{"voided"})
+	assert.Error(t, err)
+}
+
+func TestWriteSaleIDs(t *testing.T) {
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(t.TempDir())
+
+	// void-sales reads every line as an id, so there is no header or blank first line
+	assert.Nil(t, writeSaleIDs("ids.csv", []string{"s1", "s2"}))
+	written, err := os.ReadFile("ids.csv")
+	assert.Nil(t, err)
+	assert.Equal(t, "s1\ns2\n", string(written))
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vend/vend-cli/pkg/messenger"
+	"github.com/vend/vend-cli/pkg/snapshot"
+
+	"github.com/fatih/color"
+)
+
+// Snapshot file for the command being run, nil unless the command changes sales or customers
+var (
+	snapshotWriter  *snapshot.Writer
+	snapshotCommand string
+)
+
+// startSaleSnapshots creates the snapshot file that every sale is saved to before it is changed
+func startSaleSnapshots(command string) {
+	startSnapshots(command, "sales")
+}
+
+// startSnapshots creates the snapshot file for a command, entities is what it changes e.g. sales or customers
+func startSnapshots(command, entities string) {
+	fileName := fmt.Sprintf("%s_%s_snapshots_%v.jsonl", DomainPrefix, command, time.Now().Unix())
+	writer, err := snapshot.NewWriter(fileName)
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+	snapshotWriter = writer
+	snapshotCommand = command
+	if currentRun != nil {
+		currentRun.SnapshotFile = fileName
+	}
+
+	fmt.Printf("\nSaving original %s to:  %s\n", entities, color.YellowString(fileName))
+	fmt.Printf("-- Keep this file, in case an issue occurs the %s can be put back with %s --\n", entities,
+		color.GreenString("vendcli restore -f %s", fileName))
+}
+
+func stopSnapshots() {
+	if snapshotWriter == nil {
+		return
+	}
+	snapshotWriter.Close()
+	snapshotWriter = nil
+}
+
+// snapshotSale saves a sale before it is changed. Callers must not change the sale if this fails.
+func snapshotSale(id string, sale json.RawMessage) error {
+	if snapshotWriter == nil {
+		return nil
+	}
+	err := snapshotWriter.Write(snapshot.Snapshot{
+		Timestamp: time.Now().UTC(),
+		Domain:    DomainPrefix,
+		Command:   snapshotCommand,
+		SaleID:    id,
+		Sale:      sale,
+	})
+	if err != nil {
+		return fmt.Errorf("sale was not changed because it could not be snapshotted: %w", err)
+	}
+	return nil
+}
+
+// snapshotCustomer saves the fields of a customer that are about to change. Callers must not change the customer if
+// this fails.
+func snapshotCustomer(id string, fields map[string]string) error {
+	if snapshotWriter == nil {
+		return nil
+	}
+	customer, err := customerSnapshotJSON(id, fields)
+	if err == nil {
+		err = snapshotWriter.Write(snapshot.Snapshot{
+			Timestamp:  time.Now().UTC(),
+			Domain:     DomainPrefix,
+			Command:    snapshotCommand,
+			CustomerID: id,
+			Customer:   customer,
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("customer was not changed because it could not be snapshotted: %w", err)
+	}
+	return nil
+}
+
+// customerSnapshotJSON is the body that posts the fields back to the 0.9 customers API
+func customerSnapshotJSON(id string, fields map[string]string) (json.RawMessage, error) {
+	body := map[string]string{"id": id}
+	for field, value := range fields {
+		body[field] = value
+	}
+	return json.Marshal(body)
+}
+
+// snapshotExistingSale saves a sale that is about to be overwritten, if it exists
+func snapshotExistingSale(id string) error {
+	sale, err := fetchRegisterSale(id)
+	if errors.Is(err, errSaleNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch sale to snapshot: %w", err)
+	}
+	return snapshotSale(id, sale)
+}
+
+// snapshotExistingCustomer saves the current values of the fields a customer snapshot is about to put back
+func snapshotExistingCustomer(s snapshot.Snapshot) error {
+	fields, err := snapshotCustomerFields(s)
+	if err != nil {
+		return err
+	}
+	current, err := fetchCustomerFields(s.CustomerID, fields)
+	if err != nil {
+		return fmt.Errorf("failed to fetch customer to snapshot: %w", err)
+	}
+	var values map[string]string
+	if err = json.Unmarshal(current, &values); err != nil {
+		return err
+	}
+	delete(values, "id")
+	return snapshotCustomer(s.CustomerID, values)
+}
+
+// fetchRegisterSale gets a single sale from the 0.9 API exactly as Vend returns it
+func fetchRegisterSale(id string) (json.RawMessage, error) {
+	var saleResponse map[string][]json.RawMessage
+
+	// Create the Vend URL
+	url := fmt.Sprintf("https://%s.vendhq.com/api/register_sales/%s", DomainPrefix, id)
+	res, err := vendClient.MakeRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting sale info: %s", err)
+	}
+
+	// Unmarshal JSON Response
+	err = json.Unmarshal(res, &saleResponse)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling sale info: %s", err)
+	}
+
+	// check the data is valid
+	data, ok := saleResponse["register_sales"]
+	if !ok {
+		return nil, fmt.Errorf("unable to parse sale response")
+	} else if len(data) < 1 {
+		return nil, errSaleNotFound
+	}
+	return data[0], nil
+}
+
+// fetchCustomerFields gets the current values of some fields of a customer from the 2.0 API, in the same shape as a
+// customer snapshot
+func fetchCustomerFields(id string, fields []string) (json.RawMessage, error) {
+	url := fmt.Sprintf("https://%s.vendhq.com/api/2.0/customers/%s", DomainPrefix, id)
+	res, err := vendClient.MakeRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting customer info: %s", err)
+	}
+
+	var customerResponse struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err = json.Unmarshal(res, &customerResponse); err != nil {
+		return nil, fmt.Errorf("error unmarshalling customer info: %s", err)
+	}
+	if customerResponse.Data == nil {
+		return nil, errCustomerNotFound
+	}
+
+	current := map[string]string{}
+	for _, field := range fields {
+		if value := customerResponse.Data[field]; value != nil {
+			current[field] = fmt.Sprint(value)
+		} else {
+			current[field] = ""
+		}
+	}
+	return customerSnapshotJSON(id, current)
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vend/vend-cli/pkg/csvparser"
+	"github.com/vend/vend-cli/pkg/messenger"
+	"github.com/vend/vend-cli/templates"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// commandTemplate is the CSV template of an import or update command
+type commandTemplate struct {
+	// File is the embedded template, its header is the template header
+	File   string
+	Schema csvparser.Schema
+	// Extra documents the columns a KeepExtra schema reads by their header, ExtraExample fills the first of them
+	Extra        string
+	ExtraExample string
+}
+
+// Command config
+var (
+	templateMode      string
+	templateOutputDir string
+
+	templateCmd = &cobra.Command{
+		Use:   "template COMMAND",
+		Short: "Write the CSV template of an import or update command",
+		Long: fmt.Sprintf(`
+Writes the CSV template of an import or update command, with an example row, and a sheet describing
+every column: whether it is required, the type of value, the other headers accepted for it and an example.
+
+Templates are available for:
+  %s
+
+Example:
+%s`, strings.Join(templateCommands(), "\n  "), color.GreenString("vendcli template update-storecredits -m adjust")),
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{offlineAnnotation: "true"},
+		Run: func(cmd *cobra.Command, args []string) {
+			writeTemplate(args[0])
+		},
+	}
+)
+
+func init() {
+	// Flags
+	templateCmd.Flags().StringVarP(&templateMode, "mode", "m", "replace", "update-storecredits mode: replace, adjust")
+	templateCmd.Flags().StringVarP(&templateOutputDir, "output", "o", ".", "Folder to write the template to")
+
+	rootCmd.AddCommand(templateCmd)
+}
+
+// commandTemplates are the templates by command name. update-storecredits has one per mode.
+func commandTemplates() map[string]commandTemplate {
+	return map[string]commandTemplate{
+		"import-images": {File: "images-template.csv", Schema: imageSchema},
+		"import-product-codes": {File: "productcodes-template.csv", Schema: productCodeSchema,
+			Extra:        "Every other column is a code type, e.g. EAN, ISBN, UPC or CUSTOM. Leave a cell empty to add no code of that type",
+			ExtraExample: "9300000000012"},
+		"import-suppliers":            {File: "supplier-template.csv", Schema: supplierSchema},
+		"loyalty-adjustment":          {File: "loyalty-adjustment-template.csv", Schema: loyaltyAdjustmentSchema},
+		"update-average-cost":         {File: "average-cost-template.csv", Schema: averageCostSchema},
+		"update-sale-invoice-number":  {File: "sale-invoice-template.csv", Schema: saleInvoiceSchema},
+		"update-sale-user-id":         {File: "sale-user-template.csv", Schema: saleUserSchema},
+		"update-storecredits-adjust":  {File: "storecredit-adjust-template.csv", Schema: storeCreditSchema("adjust")},
+		"update-storecredits-replace": {File: "storecredit-replace-template.csv", Schema: storeCreditSchema("replace")},
+		"void-giftcards":              {File: "giftcards-template.csv", Schema: giftCardSchema},
+	}
+}
+
+// templateCommands lists the commands that have a template
+func templateCommands() []string {
+	var names []string
+	for name := range commandTemplates() {
+		if strings.HasPrefix(name, "update-storecredits-") {
+			continue
+		}
+		names = append(names, name)
+	}
+	names = append(names, "update-storecredits")
+	sort.Strings(names)
+	return names
+}
+
+func writeTemplate(command string) {
+
+	name := command
+	if command == "update-storecredits" {
+		templateMode = strings.ToLower(templateMode)
+		if templateMode != "adjust" && templateMode != "replace" {
+			err := fmt.Errorf("'%s' is not a valid option for -m mode. Mode should be 'adjust' or replace'", templateMode)
+			messenger.ExitWithError(err)
+		}
+		name = fmt.Sprintf("%s-%s", command, templateMode)
+	}
+
+	template, ok := commandTemplates()[name]
+	if !ok {
+		err := fmt.Errorf("there is no template for %q, templates are available for: %s", command, strings.Join(templateCommands(), ", "))
+		messenger.ExitWithError(err)
+	}
+
+	rows, err := templateRows(template)
+	if err != nil {
+		err = fmt.Errorf("failed to build template: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	templateFile := filepath.Join(templateOutputDir, fmt.Sprintf("%s-template.csv", name))
+	columnsFile := filepath.Join(templateOutputDir, fmt.Sprintf("%s-columns.csv", name))
+	for _, path := range []string{templateFile, columnsFile} {
+		if _, err = os.Stat(path); err == nil {
+			err = fmt.Errorf("%s already exists, move it or pass -o to write the template somewhere else", path)
+			messenger.ExitWithError(err)
+		}
+	}
+	for _, file := range []struct {
+		path string
+		rows [][]string
+	}{
+		{templateFile, rows},
+		{columnsFile, templateColumnRows(template, rows[0])},
+	} {
+		if err = writeNewCSV(file.path, file.rows); err != nil {
+			messenger.ExitWithError(err)
+		}
+	}
+
+	fmt.Println(color.GreenString("\nTemplate: %s", templateFile))
+	fmt.Println(color.GreenString("Columns:  %s", columnsFile))
+	fmt.Println("\nReplace the example row with your data before running the command")
+}
+
+// templateRows returns the header of the embedded template followed by an example row from the schema
+func templateRows(template commandTemplate) ([][]string, error) {
+	raw, err := templates.Read(template.File)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := csvparser.ParseCSV(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("template %s has no header", template.File)
+	}
+	header := rows[0]
+
+	example := make([]string, len(header))
+	extraFilled := false
+	for i, cell := range header {
+		if column, ok := template.Schema.Column(cell); ok {
+			example[i] = column.Example
+		} else if template.Schema.KeepExtra && !extraFilled {
+			example[i] = template.ExtraExample
+			extraFilled = true
+		}
+	}
+	return [][]string{header, example}, nil
+}
+
+// templateColumnRows describes every column of a template
+func templateColumnRows(template commandTemplate, header []string) [][]string {
+	rows := [][]string{{"Column", "Required", "Type", "Also accepted as", "Example", "Description"}}
+	for _, cell := range header {
+		column, ok := template.Schema.Column(cell)
+		if !ok {
+			continue
+		}
+		required := "no"
+		if column.Required {
+			required = "yes"
+		}
+		rows = append(rows, []string{cell, required, column.Type.String(), strings.Join(column.Aliases, ", "), column.Example, column.Description})
+	}
+	if template.Extra != "" {
+		rows = append(rows, []string{"(other columns)", "no", csvparser.Text.String(), "", template.ExtraExample, template.Extra})
+	}
+	return rows
+}
+
+// writeNewCSV writes rows to a file that must not exist yet
+func writeNewCSV(path string, rows [][]string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err = writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
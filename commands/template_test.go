@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/vend-cli/pkg/csvparser"
+)
+
+func TestTemplatesMatchSchemas(t *testing.T) {
+	for name, template := range commandTemplates() {
+		rows, err := templateRows(template)
+		assert.Nil(t, err, name)
+
+		table, err := template.Schema.Parse(rows)
+		assert.Nil(t, err, name)
+		assert.Empty(t, table.Errors, name)
+		assert.Empty(t, table.Ignored, name)
+		assert.Len(t, table.Records, 1, name)
+
+		columns := templateColumnRows(template, rows[0])
+		assert.Equal(t, "Column", columns[0][0], name)
+		for _, column := range columns[1:] {
+			assert.NotEmpty(t, column[5], "%s %s has no description", name, column[0])
+		}
+	}
+}
+
+func TestTemplatesPassImporterChecks(t *testing.T) {
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(t.TempDir())
+
+	rows, err := templateRows(commandTemplates()["update-average-cost"])
+	assert.Nil(t, err)
+	assert.Nil(t, checkAverageCostCSVHeader(rows))
+	assert.Nil(t, checkAverageCostCSVHeader([][]string{{"product_id", "outlet_id_Newmarket", "average_cost_Newmarket", "outlet_id", "cost"}}))
+	assert.Error(t, checkAverageCostCSVHeader([][]string{{"product_id", "product_name", "cost"}}))
+
+	// the header is skipped and only the example number is read
+	rows, err = templateRows(commandTemplates()["void-giftcards"])
+	assert.Nil(t, err)
+	assert.Nil(t, writeNewCSV("giftcards.csv", rows))
+	numbers, err := readSchemaIDFile("giftcards.csv", giftCardSchema)
+	assert.Nil(t, err)
+	assert.Equal(t, rows[1], numbers)
+}
+
+func TestTemplateCommands(t *testing.T) {
+	names := templateCommands()
+	assert.Contains(t, names, "update-storecredits")
+	assert.NotContains(t, names, "update-storecredits-adjust")
+	_, ok := csvparser.Schema{}.Column("anything")
+	assert.False(t, ok)
+}
@@ -26,9 +26,12 @@ type ProductCost struct {
 	ProductID string `json:"product_id"`
 	OutletID  string `json:"outlet_id"`
 	Cost      string `json:"cost"`
+	// Line is the row of the file the cost came from
+	Line int `json:"-"`
 }
 
 type FailedUpdateAvgCostRequest struct {
+	Line      string
 	ProductID string
 	OutletID  string
 	Cost      string
@@ -50,7 +53,7 @@ var (
 update-average-cost will update the average cost of products in Vend, based on the CSV file you provide.
 
 The CSV file should have the following format:
-Template: https://bit.ly/vendcli-csv-templates
+Template: vendcli template update-average-cost, or use -m print-template for one filled in with your products and outlets
 +------------+---------------+------------+---------------+------------+-----+---------------+------------+
 | product_id | outlet_1 name | avg_cost_1 | outlet_2 name | avg_cost_2 | ... | outlet_n name | avg_cost_n |
 +------------+---------------+------------+---------------+------------+-----+---------------+------------+
@@ -67,6 +70,7 @@ the above pattern and then use the update mode to update the average costs in Ve
 
 Example:
 %s`, color.GreenString("vendcli update-average-cost -d DOMAINPREFIX -t TOKEN -m MODE -f FILENAME.csv")),
+		Annotations: map[string]string{mutatingAnnotation: "true"},
 		Run: func(cmd *cobra.Command, args []string) {
 			vc := vend.NewClient(Token, DomainPrefix, "")
 			vendClient = &vc
@@ -86,6 +90,7 @@ func init() {
 	updateAverageCostCmd.Flags().StringVarP(&avgCostFilePath, "filename", "f", "", "The name of your file: filename.csv")
 
 	updateAverageCostCmd.Flags().StringVarP(&updateAverageCostCmdmMode, "mode", "m", "update", "modes: print-template, update")
+	addValidateFlag(updateAverageCostCmd)
 
 	rootCmd.AddCommand(updateAverageCostCmd)
 }
@@ -309,22 +314,72 @@ func updateAverageCost() {
 		messenger.ExitWithError(err)
 	}
 
+	if validateOnly {
+		validateAverageCosts(productCosts)
+		return
+	}
+
 	fmt.Printf("\nUpdating %v products\n", len(productCosts))
 	count := postAverageCosts(productCosts)
 
+	var failureFile string
 	if len(failedUpdateAvgCostRequests) > 0 {
 		fmt.Println(color.RedString("\nThere were some errors. Writing failures to csv.."))
-		fileName := fmt.Sprintf("%s_failed_update_average_cost_requests_%v.csv", DomainPrefix, time.Now().Unix())
-		err := csvparser.WriteErrorCSV(fileName, failedUpdateAvgCostRequests)
+		failureFile = fmt.Sprintf("%s_failed_update_average_cost_requests_%v.csv", DomainPrefix, time.Now().Unix())
+		err := csvparser.WriteErrorCSV(failureFile, failedUpdateAvgCostRequests)
 		if err != nil {
 			messenger.ExitWithError(err)
 			return
 		}
 	}
+	finishRun(count+len(failedUpdateAvgCostRequests), count, failureFile)
 
 	fmt.Println(color.GreenString("\nFinished! 🎉\nUpdated %d out of %d requests", count, len(productCosts)))
 }
 
+// validateAverageCosts checks every product and outlet exists and that the product carries an average cost,
+// without updating anything
+func validateAverageCosts(productCosts []ProductCost) {
+	v := newValidation("update-average-cost")
+
+	lines := map[int]bool{}
+	for _, failure := range failedUpdateAvgCostRequests {
+		line, _ := strconv.Atoi(failure.Line)
+		lines[line] = true
+		v.add(line, failure.ProductID, failure.Reason)
+	}
+
+	products, err := fetchStoreRecords("products", paginateVersion, "id")
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+	outlets, err := fetchStoreRecords("outlets", paginateVersion, "id")
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	seen := map[string]int{}
+	for _, productCost := range productCosts {
+		line := productCost.Line
+		lines[line] = true
+
+		if product, ok := v.exists(line, "product", productCost.ProductID, products[0]); ok {
+			switch {
+			case recordBool(product, "is_composite"):
+				v.add(line, productCost.ProductID, "composite products have no average cost of their own")
+			case recordBool(product, "has_variants"):
+				v.add(line, productCost.ProductID, "product is a variant parent, set the cost on its variants")
+			case product["has_inventory"] == false:
+				v.add(line, productCost.ProductID, "product does not track inventory")
+			}
+		}
+		v.exists(line, "outlet", productCost.OutletID, outlets[0])
+		v.unique(line, "product and outlet", productCost.ProductID+" "+productCost.OutletID, seen)
+	}
+	v.rows = len(lines)
+	v.finish()
+}
+
 func parseUpdateAverageCostMode(mode string) bool {
 	mode = strings.ToLower(strings.TrimSpace(mode))
 
@@ -357,7 +412,7 @@ func readAverageCostCSVFile(pathToFile string) ([]ProductCost, error) {
 	done := make(chan struct{})
 	go bar.AnimateIndeterminateBar(done)
 
-	records, err := openAverageCostCSVFile(pathToFile)
+	records, err := csvparser.ReadRows(pathToFile)
 	if err != nil {
 		bar.AbortBar()
 		p.Wait()
@@ -374,20 +429,27 @@ func readAverageCostCSVFile(pathToFile string) ([]ProductCost, error) {
 	products := []ProductCost{}
 
 	// Loop through the rows, skip the first row (header)
-	for _, record := range records[1:] {
+	for i, record := range records[1:] {
+		line := i + 2
+		// rows can be shorter than the header when trailing cells are empty
+		for len(record) < len(records[0]) {
+			record = append(record, "")
+		}
 		productID := record[0]
 
 		// Zero index is productID, then outletID and cost are in pairs so we increment by 2 from index 1
 		for j := 1; j < len(record); j += 2 {
 			product := ProductCost{
-				ProductID: productID,
-				OutletID:  record[j],
-				Cost:      record[j+1],
+				ProductID: strings.TrimSpace(productID),
+				OutletID:  strings.TrimSpace(record[j]),
+				Cost:      strings.TrimSpace(record[j+1]),
+				Line:      line,
 			}
 
 			// skip the empty requests
 			if product.ProductID == "" || product.OutletID == "" || product.Cost == "" {
 				failedUpdateAvgCostRequests = append(failedUpdateAvgCostRequests, FailedUpdateAvgCostRequest{
+					Line:      strconv.Itoa(product.Line),
 					ProductID: product.ProductID,
 					OutletID:  product.OutletID,
 					Cost:      product.Cost,
@@ -395,6 +457,16 @@ func readAverageCostCSVFile(pathToFile string) ([]ProductCost, error) {
 				})
 				continue
 			}
+			if _, err := strconv.ParseFloat(product.Cost, 64); err != nil {
+				failedUpdateAvgCostRequests = append(failedUpdateAvgCostRequests, FailedUpdateAvgCostRequest{
+					Line:      strconv.Itoa(product.Line),
+					ProductID: product.ProductID,
+					OutletID:  product.OutletID,
+					Cost:      product.Cost,
+					Reason:    fmt.Sprintf("cost is not a number: %q", product.Cost),
+				})
+				continue
+			}
 			products = append(products, product)
 		}
 	}
@@ -404,34 +476,52 @@ func readAverageCostCSVFile(pathToFile string) ([]ProductCost, error) {
 	return products, nil
 }
 
-func openAverageCostCSVFile(pathToFile string) ([][]string, error) {
-	// Open the file
-	csvFile, err := os.Open(pathToFile)
-	if err != nil {
-		err = fmt.Errorf(`%s - please check you've specified the right file path.%sTip: make sure you're in the same folder as your file. Use "cd ~/Downloads" to navigate to your Downloads folder`, err, "\n")
-		return nil, err
-	}
-	defer csvFile.Close()
-
-	reader := csv.NewReader(csvFile)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, err
-	}
-
-	return records, nil
+// averageCostSchema is the first outlet of the Average Cost CSV, more outlet_id and cost pairs can follow it
+var averageCostSchema = csvparser.Schema{
+	Columns: []csvparser.Column{
+		{Name: "product_id", Required: true, Example: "0adfd74a-153e-11e9-fa42-67b5781ba1fb",
+			Description: "ID of the product, variants are listed one per row"},
+		{Name: "outlet_id", Required: true, Example: "0adfd74a-153e-11e9-fa42-67b57818de7e",
+			Description: "ID of the outlet the cost applies to"},
+		{Name: "cost", Aliases: []string{"average_cost"}, Type: csvparser.Number, Required: true, Example: "12.50",
+			Description: "Average cost of the product at the outlet. Add more outlet_id and cost pairs for other outlets"},
+	},
 }
 
-// checkHeader checks if the header has the correct format
+// checkAverageCostCSVHeader checks the header is the product_id column of averageCostSchema followed by its
+// outlet_id and cost columns once per outlet
 func checkAverageCostCSVHeader(records [][]string) error {
-	// check if the first column has "product_id", and the number of columns is odd
-	if len(records) == 0 || len(records[0]) < 2 || records[0][0] != "product_id" || len(records[0])%2 == 0 {
-		err := fmt.Errorf("warning: Incorrect header format. Expected format: 'product_id, outlet_id, cost, ...'")
-		return err
+	if len(records) == 0 || len(records[0]) < 3 || len(records[0])%2 == 0 {
+		return fmt.Errorf("warning: Incorrect header format. Expected format: 'product_id, outlet_id, cost, ...'")
+	}
+	header := records[0]
+	if !isAverageCostColumn(header[0], "product_id") {
+		return fmt.Errorf("warning: Incorrect header format. Expected product_id as the first column, found %q", header[0])
+	}
+	for i := 1; i < len(header); i += 2 {
+		if !isAverageCostColumn(header[i], "outlet_id") || !isAverageCostColumn(header[i+1], "cost") {
+			return fmt.Errorf("warning: Incorrect header format. Expected an outlet_id and cost pair, found %q and %q", header[i], header[i+1])
+		}
 	}
 	return nil
 }
 
+// isAverageCostColumn reports whether a header names a column of averageCostSchema, on its own or followed by
+// an outlet name as in the print-template worksheet, e.g. outlet_id_Newmarket and average_cost_Newmarket
+func isAverageCostColumn(header, name string) bool {
+	if column, ok := averageCostSchema.Column(header); ok {
+		return column.Name == name
+	}
+	column, _ := averageCostSchema.Column(name)
+	header = strings.ToLower(strings.TrimSpace(header))
+	for _, prefix := range append([]string{column.Name}, column.Aliases...) {
+		if strings.HasPrefix(header, prefix+"_") {
+			return true
+		}
+	}
+	return false
+}
+
 func postAverageCosts(productCosts []ProductCost) int {
 
 	vc := *vendClient
@@ -482,6 +572,7 @@ func retryBatch(productCosts []ProductCost, url string, bar *pbar.CustomBar) int
 			err = fmt.Errorf("failure when making request: %v response: %s", err, string(resp))
 			failedUpdateAvgCostRequests = append(failedUpdateAvgCostRequests,
 				FailedUpdateAvgCostRequest{
+					Line:      strconv.Itoa(request.Line),
 					ProductID: request.ProductID,
 					OutletID:  request.OutletID,
 					Cost:      request.Cost,
@@ -1,13 +1,8 @@
 package cmd
 
 import (
-	"encoding/csv"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"log"
-	"os"
-	"strings"
 	"time"
 
 	"github.com/vend/vend-cli/pkg/csvparser"
@@ -17,7 +12,6 @@ import (
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/vend/govend/vend"
-	"github.com/wallclockbuilder/stringutil"
 )
 
 type FailedUpdateSaleIDRequests struct {
@@ -38,16 +32,16 @@ If you are unsure of its use, %s and seek help from a Product Specialist
 
 
 csv should be in the following format
-Template: https://bit.ly/vendcli-csv-templates
+Template: vendcli template update-sale-user-id
 +-------------+-------------+
 |   sale_id   |   user_id   |
 +-------------+-------------+
 | <sale UUID> | <user UUID> |
 +-------------+-------------+
 
-** Also prints a "post in case of emergency" log file 
-** filename: DOMAINNAME_update_saleid_original_sales_before_update_TIMESTAMP.txt
-** Keep this file, in case an issue occurs we can use this to recover data
+** Also saves every sale to a snapshot file before changing it
+** filename: DOMAINNAME_update-sale-user-id_snapshots_TIMESTAMP.jsonl
+** Keep this file, in case an issue occurs the sales can be put back with vendcli restore
 
 Example: %s
 `,
@@ -56,6 +50,7 @@ Example: %s
 		color.RedString("do not use this"),
 		color.GreenString("vendcli update-sale-user-id -t TOKEN -d DOMAINPREFIX -f PATH/TO/FILE")),
 
+	Annotations: map[string]string{mutatingAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		updateSaleID()
 	},
@@ -67,6 +62,7 @@ func init() {
 	// Flag
 	updateSaleIDcmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv")
 	updateSaleIDcmd.MarkFlagRequired("Filename")
+	addValidateFlag(updateSaleIDcmd)
 
 	rootCmd.AddCommand(updateSaleIDcmd)
 
@@ -74,17 +70,13 @@ func init() {
 
 func updateSaleID() {
 
-	// Create Log File
-	logFileName := fmt.Sprintf("%s_update_saleid_original_sales_before_update_%v.txt", DomainPrefix, time.Now().Unix())
-	logFile, err := os.OpenFile(fmt.Sprintf("./%s", logFileName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
-	if err != nil {
-		err = fmt.Errorf("error:%s", err)
-		messenger.ExitWithError(err)
+	if validateOnly {
+		validateSaleUserFile(FilePath)
+		return
 	}
-	fmt.Println("\nSaving original sales to: ", color.YellowString(logFileName))
-	fmt.Println("-- Keep this file, in case an issue occurs we can use this to recover data --")
-	log.SetOutput(logFile)
-	defer logFile.Close()
+
+	startSaleSnapshots("update-sale-user-id")
+	defer stopSnapshots()
 
 	fmt.Println("\n\nStarting Command Update Sale User ID..")
 	// Create new Vend Client.
@@ -103,16 +95,18 @@ func updateSaleID() {
 	fmt.Println("\nUpdating Sales...")
 	succesfulPosts := PostUpdateSaleID(saleList)
 
+	var failureFile string
 	if len(failedUpdateSaleIDRequests) > 0 {
 		fmt.Println(color.RedString("\nThere were some errors. Writing failures to csv.."))
-		fileName := fmt.Sprintf("%s_failed_update_saleid_requests_%v.csv", DomainPrefix, time.Now().Unix())
-		err := csvparser.WriteErrorCSV(fileName, failedUpdateSaleIDRequests)
+		failureFile = fmt.Sprintf("%s_failed_update_saleid_requests_%v.csv", DomainPrefix, time.Now().Unix())
+		err := csvparser.WriteErrorCSV(failureFile, failedUpdateSaleIDRequests)
 		if err != nil {
 			messenger.ExitWithError(err)
 			return
 		}
 
 	}
+	finishRun(succesfulPosts+len(failedUpdateSaleIDRequests), succesfulPosts, failureFile)
 	fmt.Println(color.GreenString("\n\nFinished! 🎉\nSuccesfully adjusted %d of %d sales", succesfulPosts, len(saleList)))
 }
 
@@ -165,32 +159,26 @@ func PostUpdateSaleID(saleList []vend.SaleUserUpload) int {
 func getSale9(id string) (vend.Sale9, error) {
 
 	sale := vend.Sale9{}
-	saleResponse := vend.RegisterSale9{}
 
-	// Create the Vend URL
-	url := fmt.Sprintf("https://%s.vendhq.com/api/register_sales/%s", DomainPrefix, id)
-	res, err := vendClient.MakeRequest("GET", url, nil)
+	data, err := fetchRegisterSale(id)
 	if err != nil {
-		err = fmt.Errorf("error getting sale info: %s", err)
 		return sale, err
 	}
-	// log the sale info for later in case we need to recover it
-	log.Println(id, string(res))
 
-	// Unmarshal JSON Response
-	err = json.Unmarshal(res, &saleResponse)
+	// save the sale for later in case we need to restore it
+	err = snapshotSale(id, data)
 	if err != nil {
-		err = fmt.Errorf("error unmarshalling sale info: %s", err)
 		return sale, err
 	}
 
-	// return sale info if results, otherwise return err
-	if len(saleResponse.RegisterSale9) > 0 {
-		return saleResponse.RegisterSale9[0], nil
-	} else {
-		err = errors.New("failed to GET sale info from vend")
+	// Unmarshal JSON Response
+	err = json.Unmarshal(data, &sale)
+	if err != nil {
+		err = fmt.Errorf("error unmarshalling sale info: %s", err)
 		return sale, err
 	}
+
+	return sale, nil
 }
 
 // Swap exisiting user for new desired user
@@ -210,105 +198,75 @@ func changeUser(sale vend.Sale9, userID string) vend.Sale9 {
 	return sale
 }
 
-// ReadSaleUserCSV reads the provided CSV file and stores the input as sale structs.
-func ReadSaleUserCSV(FilePath string) ([]vend.SaleUserUpload, error) {
+// saleUserSchema is the layout of the Update Sale User ID CSV template
+var saleUserSchema = csvparser.Schema{
+	Columns: []csvparser.Column{
+		{Name: "sale_id", Aliases: []string{"id"}, Required: true, Example: "0adfd74a-153e-11e9-fa42-67b5781ba1fb",
+			Description: "ID of the sale"},
+		{Name: "user_id", Aliases: []string{"user"}, Required: true, Example: "0adfd74a-153e-11e9-fa42-67b578d1ec2c",
+			Description: "ID of the user the sale is moved to"},
+	},
+}
 
-	p := pbar.CreateSingleBar()
-	bar, err := p.AddIndeterminateProgressBar("Reading CSV")
-	if err != nil {
-		err = fmt.Errorf("error creating progress bar:%s", err)
-		return nil, err
-	}
-	done := make(chan struct{})
-	go bar.AnimateIndeterminateBar(done)
+// validateSaleUserFile checks every sale and user exists and each sale appears once, without changing any sale
+func validateSaleUserFile(filePath string) {
+	v := newValidation("update-sale-user-id")
 
-	file, err := os.Open(FilePath)
+	fmt.Printf("\nReading CSV...\n")
+	table, err := readSchemaFile(filePath, saleUserSchema)
 	if err != nil {
-		bar.AbortBar()
-		p.Wait()
-		err = fmt.Errorf(`%s - please check you've specified the right file path.%sTip: make sure you're in the same folder as your file. Use "cd ~/Downloads" to navigate to your Downloads folder`, err, "\n")
-		return []vend.SaleUserUpload{}, err
+		err = fmt.Errorf("failed to get ids from the file: %s, error: %w", filePath, err)
+		messenger.ExitWithError(err)
 	}
-	defer file.Close()
-	reader := csv.NewReader(file)
+	v.addRowErrors(table.Errors)
 
-	header := []string{"sale_id", "user_id"}
-	headerRow, err := reader.Read()
+	users, err := fetchStoreRecords("users", paginateVersion, "id")
 	if err != nil {
-		bar.AbortBar()
-		p.Wait()
-		err = fmt.Errorf("failed to read headerow. %w", err)
-		return []vend.SaleUserUpload{}, err
+		messenger.ExitWithError(err)
 	}
 
-	if len(headerRow) > 2 {
-		bar.AbortBar()
-		p.Wait()
-		err = errors.New("header row longer than expected")
-		return []vend.SaleUserUpload{}, err
+	seen := map[string]int{}
+	saleIDs := map[int]string{}
+	for _, record := range table.Records {
+		v.rows++
+		v.exists(record.Line, "user", record.String("user_id"), users[0])
+		v.unique(record.Line, "sale", record.String("sale_id"), seen)
+		saleIDs[record.Line] = record.String("sale_id")
 	}
+	v.checkSales(saleIDs)
+	v.finish()
+}
 
-	// Check each string in the header row is same as our template.
-	for i, row := range headerRow {
-		if stringutil.Strip(strings.ToLower(row)) != header[i] {
-			bar.AbortBar()
-			p.Wait()
-			err = fmt.Errorf("mismatched CSV headers, expecting {sale_id, user_id}")
-			return []vend.SaleUserUpload{}, fmt.Errorf("mistmatched headers %v", err)
-		}
-	}
+// ReadSaleUserCSV reads the provided CSV file and stores the input as sale structs.
+func ReadSaleUserCSV(FilePath string) ([]vend.SaleUserUpload, error) {
 
-	// Read the rest of the data from the CSV.
-	rawData, err := reader.ReadAll()
+	table, err := readSchemaFile(FilePath, saleUserSchema)
 	if err != nil {
-		bar.AbortBar()
-		p.Wait()
 		return []vend.SaleUserUpload{}, err
 	}
 
-	// Loop through rows and assign them to saleUserUpload struct.
-	var sale vend.SaleUserUpload
+	for _, rowErr := range table.Errors {
+		failedUpdateSaleIDRequests = append(failedUpdateSaleIDRequests,
+			FailedUpdateSaleIDRequests{
+				SaleID: rowErr.Values["sale_id"],
+				UserID: rowErr.Values["user_id"],
+				Reason: rowErr.Error(),
+			})
+	}
+
 	var saleList []vend.SaleUserUpload
-	for idx, row := range rawData {
-		sale, err = readSaleUserRow(row)
-		if err != nil {
-			err = fmt.Errorf("error reading csv row %d: %s", idx+1, err) // +1 to make it 1-indexed
-			failedUpdateSaleIDRequests = append(failedUpdateSaleIDRequests,
-				FailedUpdateSaleIDRequests{
-					SaleID: row[0],
-					UserID: row[1],
-					Reason: err.Error(),
-				})
-			continue
-		}
-		saleList = append(saleList, sale)
+	for _, record := range table.Records {
+		saleList = append(saleList, vend.SaleUserUpload{
+			SaleID: record.String("sale_id"),
+			UserID: record.String("user_id"),
+		})
 	}
+
 	// Check how many rows we successfully read and stored.
-	if len(saleList) > 0 {
-	} else {
-		bar.AbortBar()
-		p.Wait()
+	if len(saleList) == 0 {
 		err = fmt.Errorf("no valid sales found")
 		return saleList, err
 	}
-	bar.SetIndeterminateBarComplete()
-	p.Wait()
-
-	return saleList, err
-}
 
-// Read a single row of a CSV file and check for errors.
-func readSaleUserRow(row []string) (vend.SaleUserUpload, error) {
-	var sale vend.SaleUserUpload
-
-	sale.SaleID = row[0]
-	sale.UserID = row[1]
-
-	for i := range row {
-		if len(row[i]) < 1 {
-			err := errors.New("missing field")
-			return sale, err
-		}
-	}
-	return sale, nil
+	return saleList, nil
 }
@@ -1,13 +1,8 @@
 package cmd
 
 import (
-	"encoding/csv"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"log"
-	"os"
-	"strings"
 	"time"
 
 	"github.com/vend/vend-cli/pkg/csvparser"
@@ -17,7 +12,6 @@ import (
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/vend/govend/vend"
-	"github.com/wallclockbuilder/stringutil"
 )
 
 type UpdateSaleInvoiceRequest struct {
@@ -43,15 +37,16 @@ If you are unsure of its use, %s and seek help from a Product Specialist
 
 
 csv should be in the following format
-template: https://bit.ly/vendcli-csv-templates
+template: vendcli template update-sale-invoice-number
 +-------------+--------------------------+
 |   sale_id   |   invoice_number         |
 +-------------+--------------------------+
 | <sale UUID> | <desired invoice number> |
 +-------------+--------------------------+
 
-** Also prints a "post in case of emergency" log file 
-** Keep this file, in case an issue occurs we can use this to recover data
+** Also saves every sale to a snapshot file before changing it
+** filename: DOMAINNAME_update-sale-invoice-number_snapshots_TIMESTAMP.jsonl
+** Keep this file, in case an issue occurs the sales can be put back with vendcli restore
 
 Example: %s
 `,
@@ -60,6 +55,7 @@ Example: %s
 		color.RedString("do not use this"),
 		color.GreenString("vendcli update-sale-invoice-number -t TOKEN -d DOMAINPREFIX -f PATH/TO/FILE")),
 
+	Annotations: map[string]string{mutatingAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		updateSaleInvoice()
 	},
@@ -71,6 +67,7 @@ func init() {
 	// Flag
 	updateSaleInvoiceCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv")
 	updateSaleInvoiceCmd.MarkFlagRequired("Filename")
+	addValidateFlag(updateSaleInvoiceCmd)
 
 	rootCmd.AddCommand(updateSaleInvoiceCmd)
 
@@ -78,16 +75,13 @@ func init() {
 
 func updateSaleInvoice() {
 
-	// Create Log File
-	logFileName := fmt.Sprintf("%s_update_sale_invoice_number_original_sales_before_update_%v.txt", DomainPrefix, time.Now().Unix())
-	logFile, err := os.OpenFile(fmt.Sprintf("./%s", logFileName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
-	if err != nil {
-		messenger.ExitWithError(err)
+	if validateOnly {
+		validateSaleInvoiceFile(FilePath)
+		return
 	}
-	fmt.Println("\nSaving original sales to: ", color.YellowString(logFileName))
-	fmt.Println("-- Keep this file, in case an issue occurs we can use this to recover data --")
-	log.SetOutput(logFile)
-	defer logFile.Close()
+
+	startSaleSnapshots("update-sale-invoice-number")
+	defer stopSnapshots()
 
 	fmt.Println("\n\nStarting Command Update Invoice Number..")
 	// Create new Vend Client.
@@ -105,14 +99,16 @@ func updateSaleInvoice() {
 	fmt.Println("\nUpdating Invoice Numbers...")
 	succesfulPosts := fetchSaleAndUpdateInvoiceNumber(saleList)
 
+	var failureFile string
 	if len(failedUpdateSaleInvoiceRequests) > 0 {
 		fmt.Println(color.RedString("\nThere were some errors. Writing failures to csv.."))
-		fileName := fmt.Sprintf("%s_failed_update_invoice_number_requests_%v.csv", DomainPrefix, time.Now().Unix())
-		err := csvparser.WriteErrorCSV(fileName, failedUpdateSaleInvoiceRequests)
+		failureFile = fmt.Sprintf("%s_failed_update_invoice_number_requests_%v.csv", DomainPrefix, time.Now().Unix())
+		err := csvparser.WriteErrorCSV(failureFile, failedUpdateSaleInvoiceRequests)
 		if err != nil {
 			messenger.ExitWithError(err)
 		}
 	}
+	finishRun(succesfulPosts+len(failedUpdateSaleInvoiceRequests), succesfulPosts, failureFile)
 	fmt.Println(color.GreenString("\n\nFinished! 🎉\nSuccesfully adjusted %d of %d sales", succesfulPosts, len(saleList)))
 }
 
@@ -161,36 +157,23 @@ func fetchSaleAndUpdateInvoiceNumber(saleRequests []UpdateSaleInvoiceRequest) in
 	return count
 }
 
+// getSaleRaw pulls a sale from the 0.9 Vend API as a generic map, snapshotting it first
 func getSaleRaw(id string) (map[string]interface{}, error) {
 
-	var saleResponse map[string][]json.RawMessage
 	var sale map[string]interface{}
 
-	// Create the Vend URL
-	url := fmt.Sprintf("https://%s.vendhq.com/api/register_sales/%s", DomainPrefix, id)
-	res, err := vendClient.MakeRequest("GET", url, nil)
+	data, err := fetchRegisterSale(id)
 	if err != nil {
-		err = fmt.Errorf("error getting sale info: %s", err)
 		return sale, err
 	}
-	// log the sale info for later in case we need to recover it
-	log.Println(id, string(res))
 
-	// Unmarshal JSON Response
-	err = json.Unmarshal(res, &saleResponse)
+	// save the sale for later in case we need to restore it
+	err = snapshotSale(id, data)
 	if err != nil {
-		err = fmt.Errorf("error unmarshalling sale info: %s", err)
 		return sale, err
 	}
 
-	// check the data is valid
-	data, ok := saleResponse["register_sales"]
-	if !ok {
-		return sale, fmt.Errorf("unable to parse sale response")
-	} else if len(data) < 1 {
-		return sale, fmt.Errorf("sale not found. check that your sale_id is valid")
-	}
-	err = json.Unmarshal(saleResponse["register_sales"][0], &sale)
+	err = json.Unmarshal(data, &sale)
 	if err != nil {
 		return sale, err
 	}
@@ -198,106 +181,72 @@ func getSaleRaw(id string) (map[string]interface{}, error) {
 	return sale, nil
 }
 
-// ReadSaleUserCSV reads the provided CSV file and stores the input as UpdateSaleInvoiceRequest structs.
-func ReadSaleInvoiceCSV(FilePath string) ([]UpdateSaleInvoiceRequest, error) {
+// saleInvoiceSchema is the layout of the Update Sale Invoice Number CSV template
+var saleInvoiceSchema = csvparser.Schema{
+	Columns: []csvparser.Column{
+		{Name: "sale_id", Aliases: []string{"id"}, Required: true, Example: "0adfd74a-153e-11e9-fa42-67b5781ba1fb",
+			Description: "ID of the sale"},
+		{Name: "invoice_number", Aliases: []string{"new_invoice_number"}, Required: true, Example: "MAIN-1042",
+			Description: "New invoice number of the sale"},
+	},
+}
 
-	p := pbar.CreateSingleBar()
-	bar, err := p.AddIndeterminateProgressBar("Reading CSV")
-	if err != nil {
-		err = fmt.Errorf("error creating progress bar:%s", err)
-		return nil, err
-	}
-	done := make(chan struct{})
-	go bar.AnimateIndeterminateBar(done)
+// validateSaleInvoiceFile checks every sale exists and that sales and invoice numbers appear once, without changing any sale
+func validateSaleInvoiceFile(filePath string) {
+	v := newValidation("update-sale-invoice-number")
 
-	// Open our provided CSV file.
-	file, err := os.Open(FilePath)
+	fmt.Printf("\nReading CSV...\n")
+	table, err := readSchemaFile(filePath, saleInvoiceSchema)
 	if err != nil {
-		bar.AbortBar()
-		p.Wait()
-		err = fmt.Errorf(`%s - please check you've specified the right file path.%sTip: make sure you're in the same folder as your file. Use "cd ~/Downloads" to navigate to your Downloads folder`, err, "\n")
-		return []UpdateSaleInvoiceRequest{}, err
+		err = fmt.Errorf("failed to get ids from the file: %s, error: %w", filePath, err)
+		messenger.ExitWithError(err)
 	}
-	defer file.Close()
-	reader := csv.NewReader(file)
+	v.addRowErrors(table.Errors)
 
-	// Read and store our header line.
-	header := []string{"sale_id", "invoice_number"}
-	headerRow, err := reader.Read()
-	if err != nil {
-		bar.AbortBar()
-		p.Wait()
-		err = fmt.Errorf("failed to read header row: %v", err)
-		return []UpdateSaleInvoiceRequest{}, err
+	seenSales := map[string]int{}
+	seenInvoices := map[string]int{}
+	saleIDs := map[int]string{}
+	for _, record := range table.Records {
+		v.rows++
+		v.unique(record.Line, "sale", record.String("sale_id"), seenSales)
+		v.unique(record.Line, "invoice number", record.String("invoice_number"), seenInvoices)
+		saleIDs[record.Line] = record.String("sale_id")
 	}
 
-	if len(headerRow) > 2 {
-		bar.AbortBar()
-		p.Wait()
-		err = errors.New("header row longer than expected")
-		return []UpdateSaleInvoiceRequest{}, err
-	}
+	v.checkSales(saleIDs)
+	v.finish()
+}
 
-	// Check each string in the header row is same as our template.
-	for i, row := range headerRow {
-		if stringutil.Strip(strings.ToLower(row)) != header[i] {
-			bar.AbortBar()
-			p.Wait()
-			err = fmt.Errorf("mismatched CSV headers, expecting {sale_id, invoice_number}")
-			return []UpdateSaleInvoiceRequest{}, err
-		}
-	}
+// ReadSaleInvoiceCSV reads the provided CSV file and stores the input as UpdateSaleInvoiceRequest structs.
+func ReadSaleInvoiceCSV(FilePath string) ([]UpdateSaleInvoiceRequest, error) {
 
-	// Read the rest of the data from the CSV.
-	rawData, err := reader.ReadAll()
+	table, err := readSchemaFile(FilePath, saleInvoiceSchema)
 	if err != nil {
-		bar.AbortBar()
-		p.Wait()
 		return []UpdateSaleInvoiceRequest{}, err
 	}
 
-	var request UpdateSaleInvoiceRequest
+	for _, rowErr := range table.Errors {
+		failedUpdateSaleInvoiceRequests = append(failedUpdateSaleInvoiceRequests,
+			FailedUpdateSaleInvoiceRequests{
+				SaleID:           rowErr.Values["sale_id"],
+				NewInvoiceNumber: rowErr.Values["invoice_number"],
+				Reason:           rowErr.Error(),
+			})
+	}
+
 	var requests []UpdateSaleInvoiceRequest
-	for idx, row := range rawData {
-		request, err = readSaleInvoiceRow(row)
-		if err != nil {
-			err = fmt.Errorf("error reading csv row %d: %s", idx+1, err) // +1 to make it 1-indexed
-			failedUpdateSaleInvoiceRequests = append(failedUpdateSaleInvoiceRequests,
-				FailedUpdateSaleInvoiceRequests{
-					SaleID:           row[0],
-					NewInvoiceNumber: row[1],
-					Reason:           err.Error(),
-				})
-			continue
-		}
-		requests = append(requests, request)
+	for _, record := range table.Records {
+		requests = append(requests, UpdateSaleInvoiceRequest{
+			SaleID:           record.String("sale_id"),
+			NewInvoiceNumber: record.String("invoice_number"),
+		})
 	}
+
 	// Check how many rows we successfully read and stored.
-	if len(requests) > 0 {
-	} else {
-		bar.AbortBar()
-		p.Wait()
+	if len(requests) == 0 {
 		err = fmt.Errorf("no valid rows in csv")
 		return []UpdateSaleInvoiceRequest{}, err
 	}
-	bar.SetIndeterminateBarComplete()
-	p.Wait()
-
-	return requests, err
-}
 
-// Read a single row of a CSV file and check for errors.
-func readSaleInvoiceRow(row []string) (UpdateSaleInvoiceRequest, error) {
-	var request UpdateSaleInvoiceRequest
-
-	request.SaleID = row[0]
-	request.NewInvoiceNumber = row[1]
-
-	for i := range row {
-		if len(row[i]) < 1 {
-			err := errors.New("missing field")
-			return request, err
-		}
-	}
-	return request, nil
+	return requests, nil
 }
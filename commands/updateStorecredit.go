@@ -1,10 +1,7 @@
 package cmd
 
 import (
-	"encoding/csv"
-	"errors"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -60,12 +57,15 @@ It offers two modes:
 	| <id>        | <code>        |    0.0 |
 	+-------------+---------------+--------+
 
+Templates: vendcli template update-storecredits -m replace (or -m adjust)
+
 *Note: both modes must have the requisite headers. 
 However, values are only required in customer_id OR customer_code not both
 
 Example:
 %s`, color.GreenString("vendcli update-storecredits -d DOMAINPREFIX -t TOKEN -f FILENAME.csv -m replace")),
 
+		Annotations: map[string]string{mutatingAnnotation: "true"},
 		Run: func(cmd *cobra.Command, args []string) {
 			updateStoreCredit()
 		},
@@ -78,6 +78,7 @@ func init() {
 	updateStorecreditCmd.MarkFlagRequired("Filename")
 
 	updateStorecreditCmd.Flags().StringVarP(&submitMode, "mode", "m", "replace", "the method used for updating: replace, adjust")
+	addValidateFlag(updateStorecreditCmd)
 
 	rootCmd.AddCommand(updateStorecreditCmd)
 }
@@ -96,6 +97,11 @@ func updateStoreCredit() {
 	vc := vend.NewClient(Token, DomainPrefix, "")
 	vendClient = &vc
 
+	if validateOnly {
+		validateStoreCreditFile(FilePath, submitMode)
+		return
+	}
+
 	fmt.Println("\nReading Store Credits CSV...")
 	csvRows, usesCustomerCodes, err := readStoreCreditCSV(FilePath, submitMode)
 	if err != nil {
@@ -114,113 +120,98 @@ func updateStoreCredit() {
 	fmt.Printf("\n Posting %v Store Credits..\n", numTransactions)
 	numPosted := postStoreCredit(transactions)
 
+	var failureFile string
 	if len(failedUpdateStoreCreditRequests) > 0 {
 		fmt.Println(color.RedString("\n\nThere were some errors. Writing failures to csv.."))
-		fileName := fmt.Sprintf("%s_failed_update_storecredit_requests_%v.csv", DomainPrefix, time.Now().Unix())
-		err := csvparser.WriteErrorCSV(fileName, failedUpdateStoreCreditRequests)
+		failureFile = fmt.Sprintf("%s_failed_update_storecredit_requests_%v.csv", DomainPrefix, time.Now().Unix())
+		err := csvparser.WriteErrorCSV(failureFile, failedUpdateStoreCreditRequests)
 		if err != nil {
 			err = fmt.Errorf("failed to write error csv: %w", err)
 			messenger.ExitWithError(err)
 		}
 	}
+	finishRun(numPosted+len(failedUpdateStoreCreditRequests), numPosted, failureFile)
 
 	fmt.Println(color.GreenString("\nFinished! 🎉\nSuccesfully Posted %s of %s Store Credits \n",
 		strconv.Itoa(numPosted), strconv.Itoa(numTransactions)))
 }
 
+// storeCreditSchema is the layout of the Store Credit CSV, the amount column depends on the mode
+func storeCreditSchema(submitMode string) csvparser.Schema {
+	amount := csvparser.Column{Name: "amount", Aliases: []string{"adjustment", "adjust_amount"}, Type: csvparser.Number, Required: true,
+		Example: "-10.00", Description: "Amount to add to the balance, negative to take credit away"}
+	if submitMode == "replace" {
+		amount = csvparser.Column{Name: "new_balance", Aliases: []string{"balance", "store_credit"}, Type: csvparser.Number, Required: true,
+			Example: "25.00", Description: "Balance the customer ends up with"}
+	}
+	return csvparser.Schema{
+		Columns: []csvparser.Column{
+			{Name: "customer_id", Aliases: []string{"id"}, Example: "0adfd74a-153e-11e9-fa42-67b5781ba1fb",
+				Description: "ID of the customer, this or customer_code is needed on every row"},
+			{Name: "customer_code", Aliases: []string{"code"}, Example: "CUST-0042",
+				Description: "Customer code, used when there is no customer_id"},
+			amount,
+		},
+	}
+}
+
 // Read passed CSV, returns a slice of Store Credits
 func readStoreCreditCSV(filePath string, submitMode string) ([]vend.StoreCreditCsv, bool, error) {
 
-	p := pbar.CreateSingleBar()
-	bar, err := p.AddIndeterminateProgressBar("Reading CSV")
-	if err != nil {
-		err = fmt.Errorf("error creating progress bar:%s", err)
-		return nil, false, err
-	}
-	done := make(chan struct{})
-	go bar.AnimateIndeterminateBar(done)
+	schema := storeCreditSchema(submitMode)
+	amountColumn := schema.Columns[2].Name
 
-	// Open our provided CSV file
-	file, err := os.Open(filePath)
+	table, err := readSchemaFile(filePath, schema)
 	if err != nil {
-		err = fmt.Errorf(`%s - please check you've specified the right file path.%sTip: make sure you're in the same folder as your file. Use "cd ~/Downloads" to navigate to your Downloads folder`, err, "\n")
-		bar.AbortBar()
-		p.Wait()
 		return nil, false, err
 	}
-	defer file.Close()
-	reader := csv.NewReader(file)
-
-	// Read and store our header line.
-	headerRow, err := reader.Read()
-	if err != nil {
-		bar.AbortBar()
-		p.Wait()
-		err = fmt.Errorf("error reading header row")
+	if !table.HasColumn("customer_id") && !table.HasColumn("customer_code") {
+		err = fmt.Errorf("this mode (%s) needs a customer_id or customer_code column, and %s", submitMode, amountColumn)
 		return nil, false, err
 	}
 
-	// Check each header in the row is same as our template. Fail if not
-	if err = checkHeaders(submitMode, headerRow); err != nil {
-		bar.AbortBar()
-		p.Wait()
-		return nil, false, err
-	}
-
-	// Read the rest of the data from the CSV
-	rawData, err := reader.ReadAll()
-	if err != nil {
-		bar.AbortBar()
-		p.Wait()
-		err = fmt.Errorf("error reading data from csv: %w", err)
-		messenger.ExitWithError(err)
+	for _, rowErr := range table.Errors {
+		failedUpdateStoreCreditRequests = append(failedUpdateStoreCreditRequests,
+			FailedUpdateStoreCreditRequests{
+				CustomerID:   rowErr.Values["customer_id"],
+				CustomerCode: rowErr.Values["customer_code"],
+				Amount:       rowErr.Values[amountColumn],
+				Reason:       rowErr.Error(),
+			})
 	}
 
 	var csvStructs []vend.StoreCreditCsv
 	usesCustomerCodes := false
 
 	// Loop through rows and assign them to a StoreCreditCsv struct.
-	for _, row := range rawData {
-		amount, err := strconv.ParseFloat(row[2], 64)
-		if err != nil {
-			err = errors.New("invalid amount")
-			failedUpdateStoreCreditRequests = append(failedUpdateStoreCreditRequests,
-				FailedUpdateStoreCreditRequests{
-					CustomerID:   row[0],
-					CustomerCode: row[1],
-					Amount:       row[2],
-					Reason:       err.Error(),
-				})
-			continue
-		}
-
-		rowStruct := vend.StoreCreditCsv{
-			CustomerID:   &row[0],
-			CustomerCode: &row[1],
-			Amount:       &amount,
-		}
+	for _, record := range table.Records {
+		customerID := record.String("customer_id")
+		customerCode := record.String("customer_code")
+		amount := record.Float(amountColumn)
 
 		// make sure there is at least a customer id or customer code present
-		if *rowStruct.CustomerCode != "" {
+		if customerCode != "" {
 			usesCustomerCodes = true
-		} else if *rowStruct.CustomerID == "" {
-			err = errors.New("you must have at least customer_id or customer_code")
+		} else if customerID == "" {
+			err = fmt.Errorf("line %d: you must have at least customer_id or customer_code", record.Line)
 			failedUpdateStoreCreditRequests = append(failedUpdateStoreCreditRequests,
 				FailedUpdateStoreCreditRequests{
-					CustomerID:   row[0],
-					CustomerCode: row[1],
-					Amount:       row[2],
+					CustomerID:   customerID,
+					CustomerCode: customerCode,
+					Amount:       record.String(amountColumn),
 					Reason:       err.Error(),
 				})
 			continue
 		}
-		csvStructs = append(csvStructs, rowStruct)
-	}
 
-	bar.SetIndeterminateBarComplete()
-	p.Wait()
+		csvStructs = append(csvStructs, vend.StoreCreditCsv{
+			CustomerID:   &customerID,
+			CustomerCode: &customerCode,
+			Amount:       &amount,
+		})
+	}
 
-	return csvStructs, usesCustomerCodes, err
+	return csvStructs, usesCustomerCodes, nil
 }
 
 // Post each Store Credits to Vend
@@ -254,23 +245,49 @@ func postStoreCredit(transactions []vend.StoreCreditTransaction) int {
 	return count
 }
 
-// checkHeaders makes sure the submitted CSV headers matches our desired format
-func checkHeaders(submitMode string, headerRow []string) error {
-	headers := [3]string{"customer_id", "customer_code", ""}
-	if submitMode == "replace" {
-		headers[2] = "new_balance"
-	} else if submitMode == "adjust" {
-		headers[2] = "amount"
+// validateStoreCreditFile checks every customer in the file exists and appears once, without posting anything
+func validateStoreCreditFile(filePath, submitMode string) {
+	v := newValidation("update-storecredits")
+
+	fmt.Println("\nReading Store Credits CSV...")
+	table, err := readSchemaFile(filePath, storeCreditSchema(submitMode))
+	if err != nil {
+		err = fmt.Errorf("couldnt read Store Credits CSV file,  %s", err)
+		messenger.ExitWithError(err)
 	}
+	v.addRowErrors(table.Errors)
 
-	for i := range headerRow {
-		if headerRow[i] != headers[i] {
-			err := fmt.Errorf("mismatch in headers, this mode (%s) needs three headers: customer_id, customer_code, %s. No header match for: %s instead got: %s",
-				submitMode, string(headers[2]), string(headers[i]), string(headerRow[i]))
-			return err
+	customers, err := fetchStoreRecords("customers", paginateVersion, "id", "customer_code")
+	if err != nil {
+		messenger.ExitWithError(err)
+	}
+	byID, byCode := customers[0], customers[1]
+
+	seen := map[string]int{}
+	for _, record := range table.Records {
+		v.rows++
+		customerID := record.String("customer_id")
+		customerCode := record.String("customer_code")
+
+		var customer map[string]interface{}
+		var ok bool
+		switch {
+		case customerID != "":
+			customer, ok = v.exists(record.Line, "customer", customerID, byID)
+			if ok && customerCode != "" && recordString(customer, "customer_code") != customerCode {
+				v.add(record.Line, customerCode, "customer %s has code %q, the code in the file is ignored",
+					customerID, recordString(customer, "customer_code"))
+			}
+		case customerCode != "":
+			customer, ok = v.exists(record.Line, "customer code", customerCode, byCode)
+		default:
+			v.add(record.Line, "", "you must have at least customer_id or customer_code")
+		}
+		if ok {
+			v.unique(record.Line, "customer", recordString(customer, "id"), seen)
 		}
 	}
-	return nil
+	v.finish()
 }
 
 // makeTransactions takes the info from csvStructs and makes bodys for our POST requests
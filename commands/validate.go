@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vend/vend-cli/pkg/csvparser"
+	"github.com/vend/vend-cli/pkg/messenger"
+	pbar "github.com/vend/vend-cli/pkg/progressbar"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/vend/govend/vend"
+)
+
+// ValidationIssue is a row of the validation report
+type ValidationIssue struct {
+	Line    string
+	Value   string
+	Problem string
+}
+
+// validateOnly is set by --validate on import and update commands
+var validateOnly bool
+
+// addValidateFlag adds --validate to an import or update command
+func addValidateFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&validateOnly, "validate", false, "Check every row against the store and write a validation report, without changing anything")
+}
+
+// storeRecords are the records of a resource keyed by one of their fields
+type storeRecords map[string]map[string]interface{}
+
+// validation collects the problems found while checking a file against the store
+type validation struct {
+	command string
+	rows    int
+	issues  []ValidationIssue
+}
+
+func newValidation(command string) *validation {
+	fmt.Println(color.YellowString("\nValidating only, nothing will be changed"))
+
+	// validating never changes the store, so it is not recorded in the run log
+	skipRun()
+
+	if vendClient == nil {
+		vc := vend.NewClient(Token, DomainPrefix, "")
+		vendClient = &vc
+	}
+	return &validation{command: command}
+}
+
+// add records a problem with a row
+func (v *validation) add(line int, value, problem string, args ...interface{}) {
+	v.issues = append(v.issues, ValidationIssue{
+		Line:    strconv.Itoa(line),
+		Value:   value,
+		Problem: fmt.Sprintf(problem, args...),
+	})
+}
+
+// addRowErrors records the rows that could not be read from the file
+func (v *validation) addRowErrors(rowErrors []csvparser.RowError) {
+	v.rows += len(rowErrors)
+	for _, rowErr := range rowErrors {
+		v.add(rowErr.Line, "", strings.Join(rowErr.Problems, "; "))
+	}
+}
+
+// exists reports a problem and returns false when a value is not in the store or is deleted
+func (v *validation) exists(line int, kind, value string, records storeRecords) (map[string]interface{}, bool) {
+	record, ok := records[value]
+	if !ok {
+		v.add(line, value, "%s not found", kind)
+		return nil, false
+	}
+	if isDeletedRecord(record) {
+		v.add(line, value, "%s is deleted", kind)
+		return nil, false
+	}
+	return record, true
+}
+
+// unique reports a problem when a value was already seen on an earlier line
+func (v *validation) unique(line int, kind, value string, seen map[string]int) {
+	if first, ok := seen[value]; ok {
+		v.add(line, value, "duplicate %s, also on line %d", kind, first)
+		return
+	}
+	seen[value] = line
+}
+
+// checkSales fetches each sale, keyed by line, and reports the ones that can not be found. Sales are fetched
+// one at a time, so this is the slow part of validating a file of sales.
+func (v *validation) checkSales(saleIDs map[int]string) {
+	p := pbar.CreateSingleBar()
+	bar, err := p.AddProgressBar(len(saleIDs), "Checking sales")
+	if err != nil {
+		fmt.Printf("error creating progress bar:%s\n", err)
+	}
+
+	lines := make([]int, 0, len(saleIDs))
+	for line := range saleIDs {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+
+	for _, line := range lines {
+		bar.Increment()
+		saleID := saleIDs[line]
+		_, err := fetchRegisterSale(saleID)
+		if errors.Is(err, errSaleNotFound) {
+			v.add(line, saleID, "sale not found")
+		} else if err != nil {
+			v.add(line, saleID, "could not fetch sale: %s", err)
+		}
+	}
+	p.Wait()
+}
+
+// finish prints the outcome and writes the report. Problems exit with an error so scripts and runbooks stop.
+func (v *validation) finish() {
+	if len(v.issues) == 0 {
+		fmt.Println(color.GreenString("\nAll %d rows passed validation, nothing was changed", v.rows))
+		return
+	}
+
+	lines := map[string]bool{}
+	for _, issue := range v.issues {
+		lines[issue.Line] = true
+	}
+
+	fmt.Println(color.RedString("\nFound %d problems on %d of %d rows. Writing the validation report..", len(v.issues), len(lines), v.rows))
+	reportFile := fmt.Sprintf("%s_%s_validation_%v.csv", DomainPrefix, strings.ReplaceAll(v.command, "-", "_"), time.Now().Unix())
+	err := csvparser.WriteErrorCSV(reportFile, v.issues)
+	if err != nil {
+		err = fmt.Errorf("failed to write validation report: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	err = fmt.Errorf("validation failed, see %s. Nothing was changed", reportFile)
+	messenger.ExitWithError(err)
+}
+
+// fetchStoreRecords fetches every record of a 2.0 resource, deleted ones included, and indexes them by the given fields
+func fetchStoreRecords(resource, style string, fields ...string) ([]storeRecords, error) {
+	fmt.Printf("\nFetching %s...\n", strings.TrimPrefix(resource, "balances/"))
+
+	requestURL := fmt.Sprintf("https://%s.vendhq.com/api/2.0/%s?deleted=true", DomainPrefix, resource)
+	pages, err := fetchAPIPages(requestURL, style)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", resource, err)
+	}
+
+	indexes := make([]storeRecords, len(fields))
+	for i := range indexes {
+		indexes[i] = storeRecords{}
+	}
+	for _, page := range pages {
+		response, err := decodeAPIResponse(page)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range apiRecords(response) {
+			record, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for i, field := range fields {
+				if key := recordString(record, field); key != "" {
+					indexes[i][key] = record
+				}
+			}
+		}
+	}
+	return indexes, nil
+}
+
+// recordString returns a field of a fetched record as text, empty when it is missing or null
+func recordString(record map[string]interface{}, field string) string {
+	value, ok := record[field]
+	if !ok || value == nil {
+		return ""
+	}
+	if number, ok := value.(json.Number); ok {
+		return number.String()
+	}
+	return fmt.Sprint(value)
+}
+
+func recordBool(record map[string]interface{}, field string) bool {
+	value, _ := record[field].(bool)
+	return value
+}
+
+func isDeletedRecord(record map[string]interface{}) bool {
+	return recordString(record, "deleted_at") != ""
+}
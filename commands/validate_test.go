@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vend/vend-cli/pkg/csvparser"
+)
+
+func TestValidationChecks(t *testing.T) {
+	records := storeRecords{
+		"live":    {"id": "live", "deleted_at": nil},
+		"deleted": {"id": "deleted", "deleted_at": "2024-01-01T00:00:00Z"},
+	}
+	v := &validation{command: "update-storecredits"}
+
+	_, ok := v.exists(2, "customer", "live", records)
+	assert.True(t, ok)
+	_, ok = v.exists(3, "customer", "deleted", records)
+	assert.False(t, ok)
+	_, ok = v.exists(4, "customer", "missing", records)
+	assert.False(t, ok)
+
+	seen := map[string]int{}
+	v.unique(2, "customer", "live", seen)
+	v.unique(5, "customer", "live", seen)
+
+	v.addRowErrors([]csvparser.RowError{{Line: 6, Problems: []string{"amount is missing", "customer_id is missing"}}})
+
+	assert.Equal(t, []ValidationIssue{
+		{Line: "3", Value: "deleted", Problem: "customer is deleted"},
+		{Line: "4", Value: "missing", Problem: "customer not found"},
+		{Line: "5", Value: "live", Problem: "duplicate customer, also on line 2"},
+		{Line: "6", Value: "", Problem: "amount is missing; customer_id is missing"},
+	}, v.issues)
+	assert.Equal(t, 1, v.rows)
+}
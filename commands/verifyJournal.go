@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/vend/vend-cli/pkg/messenger"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// Command config
+var (
+	verifyManifestFile  string
+	verifyPublicKeyFile string
+
+	verifyJournalCmd = &cobra.Command{
+		Use:   "verify-journal",
+		Short: "Verify a Chained Sales Journal",
+		Long: fmt.Sprintf(`
+Checks a journal written by export-sales-journal --chained has not been tampered with. Every record must match its
+hash and follow the record before it on its register, and the manifest's signature, journal hash and the last
+record of every register must match the journal. Altered, inserted and removed records are reported by line.
+
+The manifest is read from next to the journal unless --manifest is given. The store's public key is required, as
+the key written in the manifest can be replaced along with the journal.
+
+Example:
+%s`, color.GreenString("vendcli verify-journal -f FILENAME.ndjson --public-key store_key_public.pem")),
+		Annotations: map[string]string{offlineAnnotation: "true"},
+		Run: func(cmd *cobra.Command, args []string) {
+			verifyJournal()
+		},
+	}
+)
+
+func init() {
+	// Flags
+	verifyJournalCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The chained journal: filename.ndjson")
+	verifyJournalCmd.Flags().StringVar(&verifyManifestFile, "manifest", "", "The journal's manifest, by default filename_manifest.json")
+	verifyJournalCmd.Flags().StringVar(&verifyPublicKeyFile, "public-key", "", "PEM public key the manifest must be signed with, required")
+	verifyJournalCmd.MarkFlagRequired("Filename")
+	verifyJournalCmd.MarkFlagRequired("public-key")
+
+	rootCmd.AddCommand(verifyJournalCmd)
+}
+
+func verifyJournal() {
+	if verifyManifestFile == "" {
+		verifyManifestFile = manifestPath(FilePath)
+	}
+	data, err := os.ReadFile(verifyManifestFile)
+	if err != nil {
+		err = fmt.Errorf("failed to read the manifest: %w", err)
+		messenger.ExitWithError(err)
+	}
+	var manifest journalManifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		err = fmt.Errorf("failed to read the manifest %s: %w", verifyManifestFile, err)
+		messenger.ExitWithError(err)
+	}
+
+	trusted, err := readPublicKey(verifyPublicKeyFile)
+	if err != nil {
+		err = fmt.Errorf("failed to read the public key %s: %w", verifyPublicKeyFile, err)
+		messenger.ExitWithError(err)
+	}
+	if err = manifest.verify(trusted); err != nil {
+		messenger.ExitWithError(err)
+	}
+
+	file, err := os.Open(FilePath)
+	if err != nil {
+		err = fmt.Errorf("failed to read the journal: %w", err)
+		messenger.ExitWithError(err)
+	}
+	defer file.Close()
+	records, problems, err := verifyChainedJournal(file, manifest)
+	if err != nil {
+		err = fmt.Errorf("failed to read the journal: %w", err)
+		messenger.ExitWithError(err)
+	}
+
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Println(" -", problem)
+		}
+		err = fmt.Errorf("the journal %s has been tampered with, found %d problems", FilePath, len(problems))
+		messenger.ExitWithError(err)
+	}
+	fmt.Println(color.GreenString("\n\nFinished!🎉\nThe %d records on %d registers of %s are intact", records, len(manifest.Registers), FilePath))
+}
+
+// verifyChainedJournal checks every record of a journal against its hash, the record before it on its register and
+// the manifest. It returns the number of records and the problems found.
+func verifyChainedJournal(journal io.Reader, manifest journalManifest) (int, []string, error) {
+	var problems []string
+	hash := sha256.New()
+	scanner := bufio.NewScanner(io.TeeReader(journal, hash))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	heads := map[string]*manifestRegister{}
+	var registerOrder []string
+	records := 0
+	for number := 1; scanner.Scan(); number++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record chainedRecord
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&record); err != nil {
+			problems = append(problems, fmt.Sprintf("line %d is not a journal record: %s", number, err))
+			continue
+		}
+		records++
+
+		expected, err := record.hash()
+		if err != nil {
+			return records, problems, err
+		}
+		if expected != record.Hash {
+			problems = append(problems, fmt.Sprintf("line %d: sale %s has been altered", number, record.SaleID))
+		}
+
+		head, ok := heads[record.RegisterID]
+		if !ok {
+			head = &manifestRegister{RegisterID: record.RegisterID, HeadHash: chainGenesisHash}
+			heads[record.RegisterID] = head
+			registerOrder = append(registerOrder, record.RegisterID)
+		}
+		if record.PreviousHash != head.HeadHash {
+			problems = append(problems, fmt.Sprintf("line %d: sale %s does not follow the previous record of register %s, a record was inserted or removed before it",
+				number, record.SaleID, record.Register))
+		}
+		head.Records++
+		head.HeadHash = record.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return records, problems, err
+	}
+
+	for _, register := range manifest.Registers {
+		head, ok := heads[register.RegisterID]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("register %s has no records, %d were signed", register.Register, register.Records))
+		case head.Records != register.Records || head.HeadHash != register.HeadHash:
+			problems = append(problems, fmt.Sprintf("register %s has %d records ending in %s, %d ending in %s were signed",
+				register.Register, head.Records, head.HeadHash, register.Records, register.HeadHash))
+		}
+		delete(heads, register.RegisterID)
+	}
+	for _, registerID := range registerOrder {
+		if _, ok := heads[registerID]; ok {
+			problems = append(problems, fmt.Sprintf("register %s is not in the manifest", registerID))
+		}
+	}
+
+	if journalHash := hex.EncodeToString(hash.Sum(nil)); journalHash != manifest.JournalSHA256 {
+		problems = append(problems, "the journal file does not match the SHA-256 in the manifest")
+	}
+	return records, problems, nil
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/vend/vend-cli/pkg/csvparser"
@@ -29,7 +30,7 @@ var (
 		Use:   "void-giftcards",
 		Short: "Void Gift Cards",
 		Long: fmt.Sprintf(`
-This tool requires a CSV of Gift Card Numbers, no headers.
+This tool requires a CSV of Gift Card Numbers, with no header or the number header of the template.
 "Numbers" is column two of the export-giftcard command
 
 Note: Due to an API limitation Redeemed Gift Cards are not able to be voided. To overcome this, vendcli presents the following workaround:
@@ -43,18 +44,29 @@ A: Good question! We are gathering gift card numbers from Vend in order to verif
 Example Usage:
 %s`, color.GreenString("vendcli void-giftcards -d DOMAINPREFIX -t TOKEN -r TRUE/FALSE -f FILENAME.csv")),
 
+		Annotations: map[string]string{mutatingAnnotation: "true"},
 		Run: func(cmd *cobra.Command, args []string) {
 			voidGiftCards()
 		},
 	}
 )
 
+// giftCardSchema is the Gift Card CSV, a list of numbers whose header is optional
+var giftCardSchema = csvparser.Schema{
+	Columns: []csvparser.Column{
+		{Name: "number", Required: true, Example: "4000123412341234",
+			Description: "Gift card number, as in the number column of export-giftcards"},
+	},
+}
+
 func init() {
 	// Flags
-	voidGiftcardsCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv")
+	voidGiftcardsCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv, or - to read standard input")
 	voidGiftcardsCmd.MarkFlagRequired("Filename")
+	addIDColumnFlag(voidGiftcardsCmd)
 	voidGiftcardsCmd.Flags().StringVarP(&includeRedeemedStr, "include-redeemed", "r", "", "include redeemed Gift Cards: true or false")
 	voidGiftcardsCmd.MarkFlagRequired("include-redeemed")
+	addValidateFlag(voidGiftcardsCmd)
 	rootCmd.AddCommand(voidGiftcardsCmd)
 }
 
@@ -78,12 +90,17 @@ func voidGiftCards() {
 
 	// Get Gift Card Numbers from CSV
 	fmt.Println("\nReading Gift Card CSV")
-	ids, err := csvparser.ReadIdCSV(FilePath)
+	ids, err := readSchemaIDFile(FilePath, giftCardSchema)
 	if err != nil {
 		err = fmt.Errorf("failed to get gift card numbers from the file: %s, error: %w", FilePath, err)
 		messenger.ExitWithError(err)
 	}
 
+	if validateOnly {
+		validateGiftCardNumbers(ids, includeRedeemed)
+		return
+	}
+
 	fmt.Println("\nRetrieving Info from Vend...")
 	userID, giftCardBalances, err := fetchDataForGiftCardVoid()
 	if err != nil {
@@ -91,19 +108,33 @@ func voidGiftCards() {
 		messenger.ExitWithError(err)
 	}
 
+	confirmDestructiveAction(destructiveAction{
+		Command: "void-giftcards",
+		Entity:  "gift cards",
+		IDs:     ids,
+		Describe: func(id string) string {
+			if balance, ok := giftCardBalances[id]; ok {
+				return fmt.Sprintf("balance %.2f", balance)
+			}
+			return "(not found)"
+		},
+	})
+
 	// Voiding Gift Cards
 	fmt.Printf("\nVoiding %d Gift Cards...\n", len(ids))
 	succesfulPosts := postGiftCardDeleteRequets(ids, userID, includeRedeemed, giftCardBalances)
 
+	var failureFile string
 	if len(failedGiftCardVoidRequests) > 0 {
 		fmt.Println(color.RedString("\n\nThere were some errors. Writing failures to csv.."))
-		fileName := fmt.Sprintf("%s_failed_void_gift_card_requests_%v.csv", DomainPrefix, time.Now().Unix())
-		err := csvparser.WriteErrorCSV(fileName, failedGiftCardVoidRequests)
+		failureFile = fmt.Sprintf("%s_failed_void_gift_card_requests_%v.csv", DomainPrefix, time.Now().Unix())
+		err := csvparser.WriteErrorCSV(failureFile, failedGiftCardVoidRequests)
 		if err != nil {
 			messenger.ExitWithError(err)
 			return
 		}
 	}
+	finishRun(len(ids), succesfulPosts, failureFile)
 
 	fmt.Println(color.GreenString("\nFinished! 🎉\nVoided %d out of %d gift-cards", succesfulPosts, len(ids)))
 
@@ -146,6 +177,42 @@ func fetchDataForGiftCardVoid() (string, map[string]float64, error) {
 	return userID, giftCardBalances, nil
 }
 
+// validateGiftCardNumbers checks every gift card exists, is not already voided and can be voided, without voiding anything
+func validateGiftCardNumbers(numbers []string, includeRedeemed bool) {
+	v := newValidation("void-giftcards")
+
+	fmt.Println("\nFetching gift cards...")
+	giftCards, err := vendClient.GiftCards()
+	if err != nil {
+		err = fmt.Errorf("failed to retrieve gift cards: %w", err)
+		messenger.ExitWithError(err)
+	}
+	byNumber := map[string]vend.GiftCard{}
+	for _, card := range giftCards {
+		if card.Number != nil {
+			byNumber[*card.Number] = card
+		}
+	}
+
+	// the file has no header, so rows are counted from the first gift card
+	seen := map[string]int{}
+	for i, number := range numbers {
+		line := i + 1
+		v.rows++
+		card, ok := byNumber[number]
+		switch {
+		case !ok:
+			v.add(line, number, "gift card not found")
+		case card.Status != nil && strings.EqualFold(*card.Status, "VOIDED"):
+			v.add(line, number, "gift card is already voided")
+		case card.Balance != nil && *card.Balance == 0 && !includeRedeemed:
+			v.add(line, number, "gift card has a balance of zero, it is only voided with --include-redeemed true")
+		}
+		v.unique(line, "gift card", number, seen)
+	}
+	v.finish()
+}
+
 // make a gift card hash
 func makeGCHash(giftCards []vend.GiftCard) map[string]float64 {
 	gcHash := make(map[string]float64)
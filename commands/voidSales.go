@@ -24,9 +24,11 @@ var voidSaleCmd = &cobra.Command{
 	Short: "void-sales",
 	Long: fmt.Sprintf(`
 This tool requires a CSV of Sale IDs, no headers.
+Every sale is saved to a snapshot file before it is voided, see vendcli restore.
 
 Example:
 %s`, color.GreenString("vendcli void-sales -d DOMAINPREFIX -t TOKEN -f FILENAME.csv")),
+	Annotations: map[string]string{mutatingAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		voidSales()
 	},
@@ -34,8 +36,9 @@ Example:
 
 func init() {
 	// Flag
-	voidSaleCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv")
+	voidSaleCmd.Flags().StringVarP(&FilePath, "Filename", "f", "", "The name of your file: filename.csv, or - to read standard input")
 	voidSaleCmd.MarkFlagRequired("Filename")
+	addIDColumnFlag(voidSaleCmd)
 
 	rootCmd.AddCommand(voidSaleCmd)
 }
@@ -48,12 +51,22 @@ func voidSales() {
 
 	// Get passed entities from CSV
 	fmt.Println("\nReading CSV...")
-	ids, err := csvparser.ReadIdCSV(FilePath)
+	ids, err := readIDFile(FilePath)
 	if err != nil {
 		err = fmt.Errorf("failed to get IDs from the file: %s Error:%s", FilePath, err)
 		messenger.ExitWithError(err)
 	}
 
+	confirmDestructiveAction(destructiveAction{
+		Command:  "void-sales",
+		Entity:   "sales",
+		IDs:      ids,
+		Describe: describeSale,
+	})
+
+	startSaleSnapshots("void-sales")
+	defer stopSnapshots()
+
 	failedRequests := []FailedVoidRequest{}
 
 	// Make the requests
@@ -88,21 +101,23 @@ func voidSales() {
 	}
 	p.Wait()
 
+	var failureFile string
 	if len(failedRequests) > 0 {
 		fmt.Println(color.RedString("\n\nThere were some errors. Writing failures to csv.."))
-		saveFailedVoidRequestsToCSV(failedRequests)
+		failureFile = saveFailedVoidRequestsToCSV(failedRequests)
 	}
+	finishRun(len(ids), len(ids)-len(failedRequests), failureFile)
 
 	fmt.Println(color.GreenString("\n\nFinished! 🎉\n"))
 
 }
 
-func saveFailedVoidRequestsToCSV(failedRequests []FailedVoidRequest) {
+func saveFailedVoidRequestsToCSV(failedRequests []FailedVoidRequest) string {
 
 	fileName := fmt.Sprintf("%s_failed_void_requests__%v.csv", DomainPrefix, time.Now().Unix())
 	err := csvparser.WriteErrorCSV(fileName, failedRequests)
 	if err != nil {
 		messenger.ExitWithError(err)
-		return
 	}
+	return fileName
 }
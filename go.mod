@@ -7,12 +7,15 @@ require (
 	github.com/google/uuid v1.3.0
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/spf13/cobra v1.1.1
+	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.7.1
 	github.com/stretchr/testify v1.3.0
 	github.com/vbauerster/mpb/v8 v8.7.2
 	github.com/vend/govend v0.8.0
 	github.com/wallclockbuilder/stringutil v0.0.0-20151229105100-650d35b119a3
 	golang.org/x/crypto v0.19.0
+	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v2 v2.2.8
 )
 
 require (
@@ -33,12 +36,9 @@ require (
 	github.com/spf13/afero v1.1.2 // indirect
 	github.com/spf13/cast v1.3.0 // indirect
 	github.com/spf13/jwalterweatherman v1.0.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/subosito/gotenv v1.2.0 // indirect
 	github.com/wallclockbuilder/testify v0.0.0-20150512124233-dab07ac62d49 // indirect
 	golang.org/x/sys v0.17.0 // indirect
 	golang.org/x/term v0.17.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
 	gopkg.in/ini.v1 v1.51.0 // indirect
-	gopkg.in/yaml.v2 v2.2.8 // indirect
 )
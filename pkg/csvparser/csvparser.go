@@ -70,8 +70,32 @@ func WriteErrorCSV(filename string, data interface{}) error {
 	return nil
 }
 
-// readIdCSV reads a CSV that is just ids with no header
+// looksLikeIDHeader reports whether the first cell of a file of ids is a column name e.g. "id", "sale_id" or "Gift Card Number"
+func looksLikeIDHeader(value string) bool {
+	header := normaliseHeader(value)
+	return header == "id" || header == "number" || strings.HasSuffix(header, "_id") || strings.HasSuffix(header, "_number")
+}
+
+// ReadIdCSV reads a CSV or XLSX file that is just ids with no header, "-" reads standard input
 func ReadIdCSV(FilePath string) ([]string, error) {
+	return readIDs(FilePath, "", looksLikeIDHeader)
+}
+
+// ReadIdList reads a file of ids like ReadIdCSV, but the only header it skips is one naming a column of the schema
+func ReadIdList(FilePath string, schema Schema) ([]string, error) {
+	return readIDs(FilePath, "", func(value string) bool {
+		_, ok := schema.Column(value)
+		return ok
+	})
+}
+
+// ReadIdColumn reads the ids in a named column of a CSV or XLSX file with a header, such as an export.
+// The column is matched like schema columns, so "Customer ID" finds customer_id. "-" reads standard input.
+func ReadIdColumn(FilePath, column string) ([]string, error) {
+	return readIDs(FilePath, column, nil)
+}
+
+func readIDs(FilePath, column string, isHeader func(string) bool) ([]string, error) {
 
 	p := pbar.CreateSingleBar()
 	bar, err := p.AddIndeterminateProgressBar("Reading CSV")
@@ -83,42 +107,55 @@ func ReadIdCSV(FilePath string) ([]string, error) {
 	done := make(chan struct{})
 	go bar.AnimateIndeterminateBar(done)
 
-	// Open our provided CSV file
-	file, err := os.Open(FilePath)
+	rows, err := ReadRows(FilePath)
+	if err == nil && column != "" {
+		rows, err = selectColumn(rows, column)
+	}
 	if err != nil {
-		err = fmt.Errorf(`%s - please check you've specified the right file path.%sTip: make sure you're in the same folder as your file. Use "cd ~/Downloads" to navigate to your Downloads folder`, err, "\n")
 		bar.AbortBar()
 		p.Wait()
 		return nil, err
 	}
 
-	// Make sure to close the file
-	defer file.Close()
-
-	// Create CSV read on our file
-	reader := csv.NewReader(file)
-
-	// Read the rest of the data from the CSV
-	rows, err := reader.ReadAll()
-	if err != nil {
-		bar.AbortBar()
-		p.Wait()
-		return nil, err
+	// the file should not have a header, but skip one if it is obviously there
+	if column == "" && len(rows) > 0 && len(rows[0]) > 0 && isHeader(rows[0][0]) {
+		rows = rows[1:]
 	}
 
-	var rowNumber int
 	entities := []string{}
 
 	// Loop through rows and assign them
 	for _, row := range rows {
-		rowNumber++
-		entityIDs := row[0]
-		entityIDs = strings.Trim(entityIDs, "\u00a0 ") // removes nonbreaking space, if present. Support has been seeing these in some xlsx exports
+		if len(row) == 0 {
+			continue
+		}
+		entityIDs := strings.Trim(row[0], trimChars) // removes nonbreaking space, if present. Support has been seeing these in some xlsx exports
+		if entityIDs == "" {
+			continue
+		}
 		entities = append(entities, entityIDs)
 	}
 
 	bar.SetIndeterminateBarComplete()
 	p.Wait()
 
-	return entities, err
+	return entities, nil
+}
+
+// selectColumn reduces rows with a header to the values of one column
+func selectColumn(rows [][]string, column string) ([][]string, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("file is empty, expecting a header row with a %s column", column)
+	}
+	schema := Schema{Columns: []Column{{Name: column, Required: true}}}
+	positions, _, err := schema.mapHeader(rows[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var values [][]string
+	for _, row := range rows[1:] {
+		values = append(values, []string{cell(row, positions[column])})
+	}
+	return values, nil
 }
@@ -0,0 +1,34 @@
+package csvparser
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadIDs(t *testing.T) {
+	dir := t.TempDir()
+
+	plain := filepath.Join(dir, "ids.csv")
+	assert.Nil(t, ioutil.WriteFile(plain, []byte("sale_id\n1\n\n2\n"), 0644))
+	ids, err := ReadIdCSV(plain)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1", "2"}, ids)
+
+	// only the header the schema names is skipped
+	schema := Schema{Columns: []Column{{Name: "number", Required: true}}}
+	ids, err = ReadIdList(plain, schema)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"sale_id", "1", "2"}, ids)
+
+	export := filepath.Join(dir, "export.csv")
+	assert.Nil(t, ioutil.WriteFile(export, []byte("name;Customer ID\nAnn;a1\nBob;\nCat;c3\n"), 0644))
+	ids, err = ReadIdColumn(export, "customer_id")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a1", "c3"}, ids)
+
+	_, err = ReadIdColumn(export, "product_id")
+	assert.Error(t, err)
+}
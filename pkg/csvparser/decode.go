@@ -0,0 +1,141 @@
+package csvparser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Stdin is the file name that reads standard input instead of a file
+const Stdin = "-"
+
+// standard input can only be read once, so it is kept for everything that needs it
+var stdin struct {
+	once sync.Once
+	raw  []byte
+	err  error
+}
+
+// delimiters we look for in the first line, Excel uses ; in locales with decimal commas
+var delimiters = []rune{',', ';', '\t'}
+
+// ReadRows reads every row of a CSV or XLSX file. CSV files may be UTF-8 (with or without a BOM),
+// UTF-16 with a BOM or Windows-1252, and separated by commas, semicolons or tabs.
+// Only the first sheet of an XLSX file is read. A path of "-" reads standard input.
+func ReadRows(path string) ([][]string, error) {
+	var raw []byte
+	var err error
+	if path == Stdin {
+		raw, err = ReadStdin()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		raw, err = ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fileError(err)
+		}
+	}
+	// xlsx files are zip archives, whatever their extension
+	if isZip(raw) {
+		return ParseXLSX(raw)
+	}
+	return ParseCSV(raw)
+}
+
+// ReadStdin reads all of standard input the first time it is called, and returns the same bytes after that
+func ReadStdin() ([]byte, error) {
+	stdin.once.Do(func() {
+		stdin.raw, stdin.err = ioutil.ReadAll(os.Stdin)
+		if stdin.err != nil {
+			stdin.err = fmt.Errorf("failed to read standard input: %w", stdin.err)
+		}
+	})
+	return stdin.raw, stdin.err
+}
+
+// ParseCSV decodes CSV data to UTF-8, detects its delimiter and splits it into rows
+func ParseCSV(raw []byte) ([][]string, error) {
+	text, err := Decode(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = SniffDelimiter(text)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv: %w", err)
+	}
+	return rows, nil
+}
+
+// Decode returns the text of a file as UTF-8 without a byte order mark.
+// Files with a UTF-16 BOM are decoded as UTF-16, other files that are not valid UTF-8 as Windows-1252.
+func Decode(raw []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(raw, []byte{0xEF, 0xBB, 0xBF}):
+		return string(raw[3:]), nil
+	case bytes.HasPrefix(raw, []byte{0xFF, 0xFE}), bytes.HasPrefix(raw, []byte{0xFE, 0xFF}):
+		decoder := unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder()
+		decoded, _, err := transform.Bytes(decoder, raw)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode UTF-16 file: %w", err)
+		}
+		return string(decoded), nil
+	case utf8.Valid(raw):
+		return string(raw), nil
+	default:
+		decoded, _, err := transform.Bytes(charmap.Windows1252.NewDecoder(), raw)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode Windows-1252 file: %w", err)
+		}
+		return string(decoded), nil
+	}
+}
+
+// SniffDelimiter picks the delimiter that appears most often in the first line, outside quotes.
+// Commas win ties, so a single column file is read as CSV.
+func SniffDelimiter(text string) rune {
+	firstLine, _ := bufio.NewReader(strings.NewReader(text)).ReadString('\n')
+
+	counts := map[rune]int{}
+	quoted := false
+	for _, char := range firstLine {
+		if char == '"' {
+			quoted = !quoted
+			continue
+		}
+		if !quoted {
+			counts[char]++
+		}
+	}
+
+	best := delimiters[0]
+	for _, delimiter := range delimiters[1:] {
+		if counts[delimiter] > counts[best] {
+			best = delimiter
+		}
+	}
+	return best
+}
+
+// isZip reports whether data starts with the zip file signature, like xlsx files do
+func isZip(raw []byte) bool {
+	return bytes.HasPrefix(raw, []byte("PK\x03\x04"))
+}
+
+func fileError(err error) error {
+	return fmt.Errorf(`%s - please check you've specified the right file path.%sTip: make sure you're in the same folder as your file. Use "cd ~/Downloads" to navigate to your Downloads folder`, err, "\n")
+}
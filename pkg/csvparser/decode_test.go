@@ -0,0 +1,91 @@
+package csvparser
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCSVEncodingsAndDelimiters(t *testing.T) {
+	expected := [][]string{{"name", "note"}, {"Café", "1,5"}}
+
+	cases := map[string][]byte{
+		"utf-8 with bom":  []byte("\xEF\xBB\xBFname;note\nCafé;1,5\n"),
+		"windows-1252":    []byte("name\tnote\nCaf\xE9\t1,5\n"),
+		"utf-16 le bom":   {0xFF, 0xFE, 'n', 0, 'a', 0, 'm', 0, 'e', 0, ';', 0, 'n', 0, 'o', 0, 't', 0, 'e', 0, '\n', 0, 'C', 0, 'a', 0, 'f', 0, 0xE9, 0, ';', 0, '1', 0, ',', 0, '5', 0, '\n', 0},
+		"quoted by comma": []byte("name,note\nCafé,\"1,5\"\n"),
+	}
+	for name, raw := range cases {
+		rows, err := ParseCSV(raw)
+		assert.Nil(t, err, name)
+		assert.Equal(t, expected, rows, name)
+	}
+}
+
+func TestSniffDelimiter(t *testing.T) {
+	assert.Equal(t, ',', SniffDelimiter("id\n1\n"))
+	assert.Equal(t, ';', SniffDelimiter("\"a;b\",c;d;e\n"))
+	assert.Equal(t, ',', SniffDelimiter("\"a;b;c\",d\n"))
+}
+
+func TestParseXLSX(t *testing.T) {
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+			<sheets><sheet name="Codes" sheetId="1" r:id="rId2"/><sheet name="Other" sheetId="2" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+			<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+			<si><t>product_id</t></si><si><r><t>E</t></r><r><t>AN</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>wrong sheet</t></is></c></row></sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+			<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>
+			<row r="3"><c r="A3" t="inlineStr"><is><t>abc</t></is></c><c r="B3" t="b"><v>1</v></c><c r="C3"><v>9.300000000012E+12</v></c></row>
+			<row r="4"><c r="A4" t="str"><v>def</v></c><c r="C4"><v>0.30000000000000004</v></c></row>
+		</sheetData></worksheet>`,
+	}
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for name, content := range parts {
+		writer, err := archive.Create(name)
+		assert.Nil(t, err)
+		_, err = writer.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, archive.Close())
+
+	rows, err := ParseXLSX(buffer.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"product_id", "", "EAN"},
+		{},
+		{"abc", "TRUE", "9300000000012"},
+		{"def", "", "0.3"},
+	}, rows)
+
+	_, err = ParseXLSX([]byte("not a zip"))
+	assert.Error(t, err)
+}
+
+func TestReadStdinTwice(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	assert.Nil(t, err)
+	original := os.Stdin
+	os.Stdin = reader
+	defer func() { os.Stdin = original }()
+
+	writer.WriteString("id\n1\n")
+	writer.Close()
+
+	raw, err := ReadStdin()
+	assert.Nil(t, err)
+	assert.Equal(t, "id\n1\n", string(raw))
+
+	// the command reads the rows after the run log has hashed them
+	rows, err := ReadRows(Stdin)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"id"}, {"1"}}, rows)
+}
@@ -0,0 +1,327 @@
+package csvparser
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// characters trimmed from headers and values: byte order marks, nonbreaking spaces (seen in xlsx exports) and whitespace
+const trimChars = "\ufeff\u00a0 \t"
+
+// ColumnType is the kind of value a column holds
+type ColumnType int
+
+// Column types
+const (
+	Text ColumnType = iota
+	Number
+	Integer
+	Boolean
+)
+
+func (t ColumnType) String() string {
+	switch t {
+	case Number:
+		return "number"
+	case Integer:
+		return "whole number"
+	case Boolean:
+		return "true/false"
+	default:
+		return "text"
+	}
+}
+
+// Column is a column a command reads. Headers are matched to the name or any alias, ignoring case,
+// surrounding spaces and whether words are separated by spaces, dashes or underscores.
+type Column struct {
+	Name    string
+	Aliases []string
+	Type    ColumnType
+	// Required columns must be in the header and have a value on every row
+	Required bool
+	// Description and Example document the column in templates
+	Description string
+	Example     string
+}
+
+// Schema declares the columns a command reads from a file
+type Schema struct {
+	Columns []Column
+	// KeepExtra keeps the columns that are not in the schema, in file order, for commands
+	// where the header itself is data (e.g. product code types). Otherwise they are ignored.
+	KeepExtra bool
+}
+
+// Table is a file read with a schema. Rows with problems are left out of Records and reported in Errors.
+type Table struct {
+	Records []Record
+	Errors  []RowError
+	// Columns are the schema columns found in the header
+	Columns []string
+	// Extra are the headers of columns not in the schema, kept when the schema asks for them
+	Extra []string
+	// Ignored are the headers of columns not in the schema that were skipped
+	Ignored []string
+}
+
+// Record is a single valid row
+type Record struct {
+	// Line is the line in the file, the header is line 1
+	Line   int
+	values map[string]string
+	typed  map[string]interface{}
+	extra  map[string]string
+}
+
+// RowError is every problem found on a single row
+type RowError struct {
+	Line     int
+	Problems []string
+	// Values are every schema column of the row, so the row can be written to a failures file
+	Values map[string]string
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, strings.Join(e.Problems, "; "))
+}
+
+// ReadFile reads a CSV or XLSX file with the schema, see ReadRows for the formats understood
+func (s Schema) ReadFile(path string) (*Table, error) {
+	rows, err := ReadRows(path)
+	if err != nil {
+		return nil, err
+	}
+	return s.Parse(rows)
+}
+
+// Read reads CSV data with the schema. An error is only returned for problems with the whole file,
+// such as a missing column; problems with single rows are collected in the table.
+func (s Schema) Read(r io.Reader) (*Table, error) {
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv: %w", err)
+	}
+	rows, err := ParseCSV(raw)
+	if err != nil {
+		return nil, err
+	}
+	return s.Parse(rows)
+}
+
+// Parse maps already split rows to the schema, the first row is the header
+func (s Schema) Parse(rows [][]string) (*Table, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("file is empty, expecting a header row")
+	}
+
+	positions, table, err := s.mapHeader(rows[0])
+	if err != nil {
+		return nil, err
+	}
+
+	for i, row := range rows[1:] {
+		line := i + 2
+		if isBlankRow(row) {
+			continue
+		}
+
+		record := Record{
+			Line:   line,
+			values: map[string]string{},
+			typed:  map[string]interface{}{},
+			extra:  map[string]string{},
+		}
+		for _, column := range s.Columns {
+			if position, found := positions[column.Name]; found {
+				record.values[column.Name] = cell(row, position)
+			} else {
+				record.values[column.Name] = ""
+			}
+		}
+
+		var problems []string
+		for _, column := range s.Columns {
+			typed, problem := parseValue(column, record.values[column.Name])
+			if problem != "" {
+				problems = append(problems, fmt.Sprintf("%s %s", column.Name, problem))
+				continue
+			}
+			record.typed[column.Name] = typed
+		}
+		for _, header := range table.Extra {
+			record.extra[header] = cell(row, positions[extraKey(header)])
+		}
+
+		if len(problems) > 0 {
+			table.Errors = append(table.Errors, RowError{Line: line, Problems: problems, Values: record.values})
+			continue
+		}
+		table.Records = append(table.Records, record)
+	}
+	return table, nil
+}
+
+// mapHeader finds each schema column in the header
+func (s Schema) mapHeader(header []string) (map[string]int, *Table, error) {
+	lookup := map[string]string{}
+	for _, column := range s.Columns {
+		for _, name := range append([]string{column.Name}, column.Aliases...) {
+			lookup[normaliseHeader(name)] = column.Name
+		}
+	}
+
+	table := &Table{}
+	positions := map[string]int{}
+	for i, raw := range header {
+		name := strings.Trim(raw, trimChars)
+		if name == "" {
+			continue
+		}
+		column, ok := lookup[normaliseHeader(name)]
+		if !ok {
+			if s.KeepExtra {
+				if _, seen := positions[extraKey(name)]; seen {
+					return nil, nil, fmt.Errorf("column %q appears more than once in the header", name)
+				}
+				positions[extraKey(name)] = i
+				table.Extra = append(table.Extra, name)
+			} else {
+				table.Ignored = append(table.Ignored, name)
+			}
+			continue
+		}
+		if _, seen := positions[column]; seen {
+			return nil, nil, fmt.Errorf("column %s appears more than once in the header (%q)", column, name)
+		}
+		positions[column] = i
+		table.Columns = append(table.Columns, column)
+	}
+
+	var missing []string
+	for _, column := range s.Columns {
+		if _, found := positions[column.Name]; column.Required && !found {
+			missing = append(missing, column.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("missing required column(s): %s. Found: %s",
+			strings.Join(missing, ", "), strings.Join(header, ", "))
+	}
+	return positions, table, nil
+}
+
+// Column finds the schema column a header refers to
+func (s Schema) Column(header string) (Column, bool) {
+	for _, column := range s.Columns {
+		for _, name := range append([]string{column.Name}, column.Aliases...) {
+			if normaliseHeader(name) == normaliseHeader(header) {
+				return column, true
+			}
+		}
+	}
+	return Column{}, false
+}
+
+// HasColumn reports whether a schema column was found in the header
+func (t *Table) HasColumn(name string) bool {
+	for _, column := range t.Columns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the text of a column, empty when the column is not in the file
+func (r Record) String(name string) string {
+	return r.values[name]
+}
+
+// Float returns the value of a Number column
+func (r Record) Float(name string) float64 {
+	value, _ := r.typed[name].(float64)
+	return value
+}
+
+// Int returns the value of an Integer column
+func (r Record) Int(name string) int64 {
+	value, _ := r.typed[name].(int64)
+	return value
+}
+
+// Bool returns the value of a Boolean column
+func (r Record) Bool(name string) bool {
+	value, _ := r.typed[name].(bool)
+	return value
+}
+
+// Extra returns the value of a column that is not in the schema, by its header
+func (r Record) Extra(header string) string {
+	return r.extra[header]
+}
+
+func parseValue(column Column, value string) (interface{}, string) {
+	if value == "" {
+		if column.Required {
+			return nil, "is missing"
+		}
+		return nil, ""
+	}
+
+	switch column.Type {
+	case Number:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Sprintf("is not a number: %q", value)
+		}
+		return number, ""
+	case Integer:
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Sprintf("is not a whole number: %q", value)
+		}
+		return number, ""
+	case Boolean:
+		switch strings.ToLower(value) {
+		case "true", "yes", "y", "1":
+			return true, ""
+		case "false", "no", "n", "0":
+			return false, ""
+		}
+		return nil, fmt.Sprintf("is not true or false: %q", value)
+	default:
+		return value, ""
+	}
+}
+
+// normaliseHeader makes "Customer ID", "customer-id" and "customer_id" the same
+func normaliseHeader(header string) string {
+	header = strings.ToLower(strings.Trim(header, trimChars))
+	header = strings.NewReplacer(" ", "_", "-", "_").Replace(header)
+	return header
+}
+
+func extraKey(header string) string {
+	return "\x00" + header
+}
+
+// cell returns a trimmed value, or empty when the row is shorter than the header
+func cell(row []string, position int) string {
+	if position >= len(row) {
+		return ""
+	}
+	return strings.Trim(row[position], trimChars)
+}
+
+func isBlankRow(row []string) bool {
+	for _, value := range row {
+		if strings.Trim(value, trimChars) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package csvparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testSchema = Schema{
+	Columns: []Column{
+		{Name: "customer_id", Aliases: []string{"id"}, Required: true},
+		{Name: "amount", Type: Number, Required: true},
+		{Name: "note"},
+	},
+}
+
+func TestSchemaRead(t *testing.T) {
+	data := "\ufeffAmount, Customer ID ,Register\n" +
+		"10.5,abc,main\n" +
+		",,\n" +
+		"ten,def,main\n" +
+		"3,,main\n"
+
+	table, err := testSchema.Read(strings.NewReader(data))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"amount", "customer_id"}, table.Columns)
+	assert.Equal(t, []string{"Register"}, table.Ignored)
+
+	assert.Len(t, table.Records, 1)
+	assert.Equal(t, 2, table.Records[0].Line)
+	assert.Equal(t, "abc", table.Records[0].String("customer_id"))
+	assert.Equal(t, 10.5, table.Records[0].Float("amount"))
+	assert.Equal(t, "", table.Records[0].String("note"))
+
+	assert.Len(t, table.Errors, 2)
+	assert.Equal(t, `line 4: amount is not a number: "ten"`, table.Errors[0].Error())
+	assert.Equal(t, "line 5: customer_id is missing", table.Errors[1].Error())
+	assert.Equal(t, "3", table.Errors[1].Values["amount"])
+}
+
+func TestSchemaHeaderProblems(t *testing.T) {
+	_, err := testSchema.Read(strings.NewReader("id,note\nabc,hi\n"))
+	assert.EqualError(t, err, "missing required column(s): amount. Found: id, note")
+
+	_, err = testSchema.Read(strings.NewReader("id,customer_id,amount\n"))
+	assert.Error(t, err)
+}
+
+func TestSchemaKeepExtra(t *testing.T) {
+	schema := Schema{Columns: []Column{{Name: "product_id", Required: true}}, KeepExtra: true}
+
+	table, err := schema.Read(strings.NewReader("product_id,EAN,UPC\n1,111,\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"EAN", "UPC"}, table.Extra)
+	assert.Equal(t, "111", table.Records[0].Extra("EAN"))
+	assert.Equal(t, "", table.Records[0].Extra("UPC"))
+}
@@ -0,0 +1,232 @@
+package csvparser
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// The parts of the SpreadsheetML format needed to read cell values. Styles are ignored,
+// so dates are read as the day numbers Excel stores them as.
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name  string     `xml:"name,attr"`
+		Attrs []xml.Attr `xml:",any,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	text := t.T
+	for _, run := range t.Runs {
+		text += run.T
+	}
+	return text
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string   `xml:"r,attr"`
+			T      string   `xml:"t,attr"`
+			V      string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ParseXLSX reads every row of the first sheet of an Excel workbook. Rows are numbered as in Excel,
+// so empty rows are kept as empty rows and line numbers in errors match the spreadsheet.
+func ParseXLSX(raw []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spreadsheet: %w", err)
+	}
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[strings.TrimPrefix(file.Name, "/")] = file
+	}
+	if _, ok := files["xl/workbook.xml"]; !ok {
+		return nil, fmt.Errorf("file is not an Excel workbook, only .xlsx files are supported")
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var sharedStrings xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err = readXMLPart(files, "xl/sharedStrings.xml", &sharedStrings); err != nil {
+			return nil, err
+		}
+	}
+
+	var sheet xlsxSheet
+	if err = readXMLPart(files, sheetPath, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		number := row.R
+		if number == 0 {
+			number = len(rows) + 1
+		}
+		// pad rows Excel left out because they are empty
+		for len(rows) < number-1 {
+			rows = append(rows, []string{})
+		}
+
+		var values []string
+		for _, cell := range row.Cells {
+			column := len(values)
+			if cell.R != "" {
+				if column, err = columnIndex(cell.R); err != nil {
+					return nil, err
+				}
+			}
+			for len(values) < column {
+				values = append(values, "")
+			}
+
+			value, err := cellValue(cell.T, cell.V, cell.Inline, sharedStrings)
+			if err != nil {
+				return nil, fmt.Errorf("cell %s: %w", cell.R, err)
+			}
+			values = append(values, value)
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// firstSheetPath finds the file of the first sheet in the workbook
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook xlsxWorkbook
+	if err := readXMLPart(files, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", fmt.Errorf("workbook has no sheets")
+	}
+
+	var relationID string
+	for _, attr := range workbook.Sheets[0].Attrs {
+		if attr.Name.Local == "id" {
+			relationID = attr.Value
+		}
+	}
+
+	var relationships xlsxRelationships
+	if _, ok := files["xl/_rels/workbook.xml.rels"]; ok && relationID != "" {
+		if err := readXMLPart(files, "xl/_rels/workbook.xml.rels", &relationships); err != nil {
+			return "", err
+		}
+		for _, relationship := range relationships.Relationships {
+			if relationship.ID != relationID {
+				continue
+			}
+			// targets are relative to the xl folder, or absolute within the archive
+			if strings.HasPrefix(relationship.Target, "/") {
+				return strings.TrimPrefix(relationship.Target, "/"), nil
+			}
+			return path.Join("xl", relationship.Target), nil
+		}
+	}
+
+	if _, ok := files["xl/worksheets/sheet1.xml"]; ok {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+	return "", fmt.Errorf("could not find sheet %q in the workbook", workbook.Sheets[0].Name)
+}
+
+func readXMLPart(files map[string]*zip.File, name string, target interface{}) error {
+	file, ok := files[name]
+	if !ok {
+		return fmt.Errorf("workbook is missing %s", name)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer reader.Close()
+
+	if err = xml.NewDecoder(reader).Decode(target); err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return nil
+}
+
+// cellValue returns a cell as the text Excel would write to a CSV
+func cellValue(cellType, value string, inline xlsxText, sharedStrings xlsxSharedStrings) (string, error) {
+	switch cellType {
+	case "s":
+		index, err := strconv.Atoi(value)
+		if err != nil || index < 0 || index >= len(sharedStrings.Items) {
+			return "", fmt.Errorf("invalid shared string %q", value)
+		}
+		return sharedStrings.Items[index].String(), nil
+	case "inlineStr":
+		return inline.String(), nil
+	case "b":
+		if value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case "str", "e", "d":
+		return value, nil
+	default:
+		return formatNumber(value), nil
+	}
+}
+
+// formatNumber writes numbers the way Excel shows them: without exponents and rounded to 15 significant
+// digits, so barcodes stored as numbers keep every digit and 0.1+0.2 is 0.3
+func formatNumber(value string) string {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(number, 'g', 15, 64), 64)
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
+
+// columnIndex converts a cell reference such as "AB12" to a zero based column index
+func columnIndex(reference string) (int, error) {
+	index := 0
+	letters := 0
+	for _, char := range strings.ToUpper(reference) {
+		if char < 'A' || char > 'Z' {
+			break
+		}
+		index = index*26 + int(char-'A'+1)
+		letters++
+	}
+	if letters == 0 {
+		return 0, fmt.Errorf("invalid cell reference %q", reference)
+	}
+	return index - 1, nil
+}
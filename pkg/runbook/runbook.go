@@ -0,0 +1,301 @@
+package runbook
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Failure policies
+const (
+	OnFailureStop     = "stop"
+	OnFailureContinue = "continue"
+	OnFailureRetry    = "retry"
+)
+
+// Runbook is a sequence of steps run against one store
+type Runbook struct {
+	Name      string `yaml:"name"`
+	Domain    string `yaml:"domain"`
+	Token     string `yaml:"token"`
+	OutputDir string `yaml:"output_dir"`
+	Steps     []Step `yaml:"steps"`
+
+	// Dir is the folder the runbook file is in, relative paths in flags are resolved against it
+	Dir string `yaml:"-"`
+}
+
+// Step is either a vendcli command with flags, or an external program to run with Exec
+type Step struct {
+	ID      string            `yaml:"id"`
+	Command string            `yaml:"command"`
+	Flags   map[string]string `yaml:"flags"`
+	Exec    []string          `yaml:"exec"`
+
+	// OnFailure is stop (default), continue or retry
+	OnFailure string `yaml:"on_failure"`
+	Retries   int    `yaml:"retries"`
+	// AllowRowFailures stops a failures CSV written by the step from failing it
+	AllowRowFailures bool `yaml:"allow_row_failures"`
+	// Yes skips the confirmation prompt of destructive commands
+	Yes bool `yaml:"yes"`
+}
+
+// Outputs are the files each finished step created, keyed by step ID
+type Outputs map[string]StepOutput
+
+// StepOutput is the files a step created, and which of them is the failures CSV its run recorded
+type StepOutput struct {
+	Files       []string
+	FailureFile string
+}
+
+var (
+	placeholder = regexp.MustCompile(`\{\{\s*([^}]*?)\s*\}\}`)
+	envVar      = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
+// Load reads and validates a runbook file. ${NAME} in the domain and token is replaced with the environment variable.
+func Load(path string) (*Runbook, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	book := &Runbook{}
+	if err = yaml.UnmarshalStrict(raw, book); err != nil {
+		return nil, fmt.Errorf("failed to parse runbook: %w", err)
+	}
+
+	book.Dir, err = filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	book.Domain = expandEnv(book.Domain)
+	book.Token = expandEnv(book.Token)
+	if book.Name == "" {
+		book.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	for i := range book.Steps {
+		if book.Steps[i].OnFailure == "" {
+			book.Steps[i].OnFailure = OnFailureStop
+		}
+		if book.Steps[i].OnFailure == OnFailureRetry && book.Steps[i].Retries == 0 {
+			book.Steps[i].Retries = 1
+		}
+	}
+
+	if err = book.Validate(); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// Validate checks the steps can be run in order before anything is run
+func (b *Runbook) Validate() error {
+	if len(b.Steps) == 0 {
+		return fmt.Errorf("runbook has no steps")
+	}
+
+	seen := map[string]bool{}
+	for i, step := range b.Steps {
+		name := fmt.Sprintf("step %d", i+1)
+		if step.ID == "" {
+			return fmt.Errorf("%s has no id", name)
+		}
+		name = fmt.Sprintf("step %q", step.ID)
+		if seen[step.ID] {
+			return fmt.Errorf("%s is declared twice", name)
+		}
+
+		if (step.Command == "") == (len(step.Exec) == 0) {
+			return fmt.Errorf("%s needs either a command or exec", name)
+		}
+		if len(step.Exec) > 0 && len(step.Flags) > 0 {
+			return fmt.Errorf("%s: flags can only be used with a command, add them to exec instead", name)
+		}
+		for flag := range step.Flags {
+			switch strings.TrimLeft(flag, "-") {
+			case "Domain", "d", "Token", "t":
+				return fmt.Errorf("%s: set the domain and token once at the top of the runbook", name)
+			}
+		}
+
+		switch step.OnFailure {
+		case OnFailureStop, OnFailureContinue, OnFailureRetry:
+		default:
+			return fmt.Errorf("%s: unknown on_failure %q, expecting stop, continue or retry", name, step.OnFailure)
+		}
+		if step.Retries < 0 {
+			return fmt.Errorf("%s: retries can not be negative", name)
+		}
+
+		for _, value := range step.values() {
+			for _, match := range placeholder.FindAllStringSubmatch(value, -1) {
+				if err := checkReference(match[1], seen); err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+			}
+		}
+		seen[step.ID] = true
+	}
+	return nil
+}
+
+// Args builds the vendcli arguments for a command step, resolving references to earlier outputs
+func (b *Runbook) Args(step Step, outputDir string, outputs Outputs) ([]string, error) {
+	args := []string{step.Command}
+
+	names := make([]string, 0, len(step.Flags))
+	for flag := range step.Flags {
+		names = append(names, flag)
+	}
+	sort.Strings(names)
+
+	for _, flag := range names {
+		value, err := b.resolve(step.Flags[flag], outputDir, outputs)
+		if err != nil {
+			return nil, fmt.Errorf("flag %s: %w", flag, err)
+		}
+		args = append(args, "--"+strings.TrimLeft(flag, "-"), value)
+	}
+	if step.Yes {
+		args = append(args, "--yes")
+	}
+	return args, nil
+}
+
+// ExecArgs builds the program and arguments of an exec step, resolving references to earlier outputs
+func (b *Runbook) ExecArgs(step Step, outputDir string, outputs Outputs) ([]string, error) {
+	var args []string
+	for _, arg := range step.Exec {
+		value, err := b.resolve(arg, outputDir, outputs)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+	return args, nil
+}
+
+// resolve replaces placeholders and turns paths relative to the runbook into absolute paths,
+// since steps are run inside the output folder
+func (b *Runbook) resolve(value, outputDir string, outputs Outputs) (string, error) {
+	var resolveErr error
+	resolved := placeholder.ReplaceAllStringFunc(value, func(match string) string {
+		reference := placeholder.FindStringSubmatch(match)[1]
+		replacement, err := lookup(reference, b.Domain, outputDir, outputs)
+		if err != nil && resolveErr == nil {
+			resolveErr = err
+		}
+		return replacement
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+
+	if resolved == value && resolved != "" && !filepath.IsAbs(resolved) {
+		candidate := filepath.Join(b.Dir, resolved)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return resolved, nil
+}
+
+// lookup resolves a single reference:
+// domain, output_dir, steps.ID.output (the one file a step created) or steps.ID.output:GLOB
+func lookup(reference, domain, outputDir string, outputs Outputs) (string, error) {
+	switch reference {
+	case "domain":
+		return domain, nil
+	case "output_dir":
+		return outputDir, nil
+	}
+
+	id, pattern, err := parseStepReference(reference)
+	if err != nil {
+		return "", err
+	}
+	output, ok := outputs[id]
+	if !ok {
+		return "", fmt.Errorf("step %q did not finish, so it has no output", id)
+	}
+
+	var matched []string
+	for _, file := range output.Files {
+		if pattern == "" {
+			if file != output.FailureFile {
+				matched = append(matched, file)
+			}
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(file)); ok {
+			matched = append(matched, file)
+		}
+	}
+
+	switch len(matched) {
+	case 1:
+		return matched[0], nil
+	case 0:
+		return "", fmt.Errorf("step %q did not create a file matching {{%s}}", id, reference)
+	default:
+		var bases []string
+		for _, file := range matched {
+			bases = append(bases, filepath.Base(file))
+		}
+		return "", fmt.Errorf("step %q created %d files (%s), pick one with {{steps.%s.output:PATTERN}}",
+			id, len(matched), strings.Join(bases, ", "), id)
+	}
+}
+
+func checkReference(reference string, earlierSteps map[string]bool) error {
+	if reference == "domain" || reference == "output_dir" {
+		return nil
+	}
+	id, pattern, err := parseStepReference(reference)
+	if err != nil {
+		return err
+	}
+	if !earlierSteps[id] {
+		return fmt.Errorf("{{%s}} refers to step %q which does not run before it", reference, id)
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("{{%s}} has an invalid pattern: %w", reference, err)
+	}
+	return nil
+}
+
+func parseStepReference(reference string) (string, string, error) {
+	var pattern string
+	if i := strings.Index(reference, ":"); i >= 0 {
+		reference, pattern = reference[:i], reference[i+1:]
+	}
+	parts := strings.Split(reference, ".")
+	if len(parts) != 3 || parts[0] != "steps" || parts[2] != "output" || parts[1] == "" {
+		return "", "", fmt.Errorf("unknown reference {{%s}}, expecting steps.ID.output, domain or output_dir", reference)
+	}
+	return parts[1], pattern, nil
+}
+
+func (s Step) values() []string {
+	values := append([]string{}, s.Exec...)
+	for _, value := range s.Flags {
+		values = append(values, value)
+	}
+	return values
+}
+
+func expandEnv(value string) string {
+	return envVar.ReplaceAllStringFunc(value, func(match string) string {
+		return os.Getenv(envVar.FindStringSubmatch(match)[1])
+	})
+}
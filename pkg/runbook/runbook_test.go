@@ -0,0 +1,75 @@
+package runbook
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadAndResolve(t *testing.T) {
+	dir := t.TempDir()
+	os.Setenv("RUNBOOK_TEST_TOKEN", "secret")
+	defer os.Unsetenv("RUNBOOK_TEST_TOKEN")
+
+	path := filepath.Join(dir, "migration.yaml")
+	err := ioutil.WriteFile(path, []byte(`
+domain: acme
+token: ${RUNBOOK_TEST_TOKEN}
+steps:
+  - id: export
+    command: export-products
+  - id: import
+    command: import-product-codes
+    on_failure: retry
+    yes: true
+    flags:
+      Filename: "{{steps.export.output}}"
+      validate: true
+`), 0600)
+	assert.Nil(t, err)
+
+	book, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "migration", book.Name)
+	assert.Equal(t, "secret", book.Token)
+	assert.Equal(t, 1, book.Steps[1].Retries)
+
+	outputs := Outputs{"export": {Files: []string{"/out/acme_products_1.csv", "/out/product_code_add_1.csv"}, FailureFile: "/out/product_code_add_1.csv"}}
+	args, err := book.Args(book.Steps[1], "/out", outputs)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"import-product-codes", "--Filename", "/out/acme_products_1.csv", "--validate", "true", "--yes"}, args)
+
+	_, err = book.Args(book.Steps[1], "/out", Outputs{})
+	assert.NotNil(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	book := &Runbook{Steps: []Step{
+		{ID: "import", Command: "import-suppliers", OnFailure: OnFailureStop, Flags: map[string]string{"Filename": "{{steps.export.output}}"}},
+		{ID: "export", Command: "export-suppliers", OnFailure: OnFailureStop},
+	}}
+	assert.NotNil(t, book.Validate())
+
+	book = &Runbook{Steps: []Step{{ID: "a", Command: "export-users", Exec: []string{"ls"}, OnFailure: OnFailureStop}}}
+	assert.NotNil(t, book.Validate())
+
+	book = &Runbook{Steps: []Step{{ID: "a", Command: "export-users", OnFailure: "ignore"}}}
+	assert.NotNil(t, book.Validate())
+
+	book = &Runbook{Steps: []Step{{ID: "a", Command: "export-users", OnFailure: OnFailureStop, Flags: map[string]string{"Token": "x"}}}}
+	assert.NotNil(t, book.Validate())
+}
+
+func TestLookupPattern(t *testing.T) {
+	outputs := Outputs{"export": {Files: []string{"/out/a_products.csv", "/out/a_inventory.csv"}}}
+
+	_, err := lookup("steps.export.output", "a", "/out", outputs)
+	assert.NotNil(t, err)
+
+	file, err := lookup("steps.export.output:*_inventory.csv", "a", "/out", outputs)
+	assert.Nil(t, err)
+	assert.Equal(t, "/out/a_inventory.csv", file)
+}
@@ -0,0 +1,133 @@
+package runlog
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// name of the append-only run log, stored in the user's home directory
+const fileName = ".vendcli_history.jsonl"
+
+// Run statuses
+const (
+	StatusCompleted = "completed"
+	StatusAborted   = "aborted"
+)
+
+// Run is a single invocation of a mutating vendcli command
+type Run struct {
+	Timestamp    time.Time         `json:"timestamp"`
+	OSUser       string            `json:"os_user"`
+	Domain       string            `json:"domain"`
+	Command      string            `json:"command"`
+	Flags        map[string]string `json:"flags"`
+	InputFile    string            `json:"input_file,omitempty"`
+	InputSHA256  string            `json:"input_sha256,omitempty"`
+	Attempted    int               `json:"attempted"`
+	Succeeded    int               `json:"succeeded"`
+	Failed       int               `json:"failed"`
+	FailureFile  string            `json:"failure_file,omitempty"`
+	SnapshotFile string            `json:"snapshot_file,omitempty"`
+	Status       string            `json:"status"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// DefaultPath returns the location of the run log
+func DefaultPath() (string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, fileName), nil
+}
+
+// Append writes a run to the end of the log. The file is only ever opened for appending.
+func Append(path string, run Run) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open run log: %w", err)
+	}
+	defer file.Close()
+
+	line, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to encode run: %w", err)
+	}
+	line = append(line, '\n')
+
+	if _, err = file.Write(line); err != nil {
+		return fmt.Errorf("failed to write run log: %w", err)
+	}
+	return nil
+}
+
+// Read returns every run in the log, oldest first. A missing log is not an error.
+func Read(path string) ([]Run, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return []Run{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open run log: %w", err)
+	}
+	defer file.Close()
+
+	runs := []Run{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return runs, fmt.Errorf("run log line %d is malformed: %w", lineNumber, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, scanner.Err()
+}
+
+// FileSHA256 returns the hex encoded SHA-256 of a file
+func FileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// SHA256 returns the hex encoded SHA-256 of data that is not in a file, such as standard input
+func SHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// CurrentUser returns the OS user running the CLI
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
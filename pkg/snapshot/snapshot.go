@@ -0,0 +1,101 @@
+package snapshot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Snapshot is the state of a sale or customer immediately before vendcli changed it.
+// Sale holds the register_sales object exactly as it was returned by the 0.9 API, so it can be posted back as is.
+// Customer holds the customer's id and the fields that were changed, as the 0.9 customers API takes them.
+type Snapshot struct {
+	Timestamp  time.Time       `json:"timestamp"`
+	Domain     string          `json:"domain"`
+	Command    string          `json:"command"`
+	SaleID     string          `json:"sale_id,omitempty"`
+	Sale       json.RawMessage `json:"sale,omitempty"`
+	CustomerID string          `json:"customer_id,omitempty"`
+	Customer   json.RawMessage `json:"customer,omitempty"`
+}
+
+// ID returns the id of the sale or customer in the snapshot
+func (s Snapshot) ID() string {
+	if s.CustomerID != "" {
+		return s.CustomerID
+	}
+	return s.SaleID
+}
+
+// Entity returns what the snapshot is of, sale or customer
+func (s Snapshot) Entity() string {
+	if s.CustomerID != "" {
+		return "customer"
+	}
+	return "sale"
+}
+
+// Writer appends snapshots to a JSONL file, one snapshot per line
+type Writer struct {
+	Path string
+	file *os.File
+}
+
+// NewWriter creates the snapshot file, failing if it already exists so snapshots are never overwritten
+func NewWriter(path string) (*Writer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	return &Writer{Path: path, file: file}, nil
+}
+
+// Write appends a snapshot and syncs it to disk before returning, so it survives a crash mid run
+func (w *Writer) Write(snapshot Snapshot) error {
+	line, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot for %s %s: %w", snapshot.Entity(), snapshot.ID(), err)
+	}
+	line = append(line, '\n')
+
+	if _, err = w.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write snapshot for %s %s: %w", snapshot.Entity(), snapshot.ID(), err)
+	}
+	return w.file.Sync()
+}
+
+// Close closes the snapshot file
+func (w *Writer) Close() error {
+	return w.file.Close()
+}
+
+// Read returns the snapshots in a file in the order they were written
+func Read(path string) ([]Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	snapshots := []Snapshot{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var snapshot Snapshot
+		if err := json.Unmarshal(scanner.Bytes(), &snapshot); err != nil {
+			return snapshots, fmt.Errorf("snapshot line %d is malformed: %w", lineNumber, err)
+		}
+		if snapshot.ID() == "" || (len(snapshot.Sale) == 0 && len(snapshot.Customer) == 0) {
+			return snapshots, fmt.Errorf("snapshot line %d is missing the sale or customer", lineNumber)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, scanner.Err()
+}
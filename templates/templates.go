@@ -0,0 +1,14 @@
+// Package templates holds the CSV templates of the import and update commands, embedded in the binary
+package templates
+
+import (
+	"embed"
+)
+
+//go:embed *.csv
+var files embed.FS
+
+// Read returns the template with the given file name
+func Read(name string) ([]byte, error) {
+	return files.ReadFile(name)
+}